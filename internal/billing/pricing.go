@@ -0,0 +1,97 @@
+package billing
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/BatmanBruc/bat-bot-convetor/internal/formats"
+	"github.com/BatmanBruc/bat-bot-convetor/types"
+)
+
+const (
+	sizeStep     = 20 << 20
+	maxSizeExtra = 5
+	ocrExtra     = 2
+)
+
+var (
+	ErrFileTooLarge       = errors.New("file exceeds plan size limit")
+	ErrCategoryNotAllowed = errors.New("category is not included in plan")
+)
+
+var categoryCredits = map[string]int{
+	"images":       1,
+	"font":         1,
+	"subtitles":    1,
+	"audio":        2,
+	"document":     2,
+	"presentation": 3,
+	"ebook":        3,
+	"video":        4,
+}
+
+func Cost(task *types.Task) int {
+	if task == nil {
+		return 0
+	}
+	cost := 1
+	if c, ok := categoryCredits[Category(task.OriginalExt)]; ok {
+		cost = c
+	}
+	if op, _ := task.Options["pdf_op"].(string); op == "ocr" {
+		cost += ocrExtra
+	}
+	if extra := int(fileSize(task.Options) / sizeStep); extra > 0 {
+		if extra > maxSizeExtra {
+			extra = maxSizeExtra
+		}
+		cost += extra
+	}
+	return cost
+}
+
+func Category(ext string) string {
+	ext = strings.ToLower(strings.TrimPrefix(ext, "."))
+	if c := formats.GetCategoryByExtension(ext); c != "" {
+		return c
+	}
+	for name, groups := range formats.SupportedFormats {
+		for _, g := range groups {
+			for _, f := range g.Formats {
+				if strings.EqualFold(f, ext) {
+					return name
+				}
+			}
+		}
+	}
+	return ""
+}
+
+func CheckPlan(plan *types.Plan, task *types.Task) error {
+	if plan == nil || task == nil {
+		return nil
+	}
+	if plan.MaxFileSize > 0 && fileSize(task.Options) > plan.MaxFileSize {
+		return ErrFileTooLarge
+	}
+	if c := Category(task.OriginalExt); c != "" && !plan.AllowsCategory(c) {
+		return ErrCategoryNotAllowed
+	}
+	return nil
+}
+
+func fileSize(options map[string]interface{}) int64 {
+	switch v := options["file_size"].(type) {
+	case int:
+		return int64(v)
+	case int64:
+		return v
+	case float64:
+		return int64(v)
+	case string:
+		n, _ := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+		return n
+	}
+	return 0
+}
@@ -0,0 +1,53 @@
+package billing
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/BatmanBruc/bat-bot-convetor/types"
+)
+
+const refundPoll = 5 * time.Second
+
+func RunRefunds(ctx context.Context, queue types.RefundQueue, store types.BillingStore) {
+	ticker := time.NewTicker(refundPoll)
+	defer ticker.Stop()
+
+	for {
+		drainRefunds(queue, store)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func drainRefunds(queue types.RefundQueue, store types.BillingStore) {
+	for {
+		refund, err := queue.PopRefund()
+		if err != nil {
+			log.Printf("Billing: failed to read refund queue: %v", err)
+			return
+		}
+		if refund == nil {
+			return
+		}
+		err = store.Refund(refund.UserID, refund.Credits, refund.Purchased, refund.TaskID)
+		if errors.Is(err, types.ErrNoCreditAccount) {
+			log.Printf("Billing: dropping refund for task %s: %v", refund.TaskID, err)
+			continue
+		}
+		if err != nil {
+			log.Printf("Billing: failed to refund %d credits to user %d: %v", refund.Credits, refund.UserID, err)
+			if err := queue.PushRefund(*refund); err != nil {
+				log.Printf("Billing: lost refund for task %s: %v", refund.TaskID, err)
+			}
+			return
+		}
+		log.Printf("Billing: refunded %d credits to user %d for task %s", refund.Credits, refund.UserID, refund.TaskID)
+	}
+}
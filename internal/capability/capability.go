@@ -0,0 +1,268 @@
+package capability
+
+import (
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+const MaxHops = 3
+
+type Edge struct {
+	From    string
+	To      string
+	Backend string
+	Cost    int
+	Gated   bool
+}
+
+type Matrix struct {
+	edges    map[string]map[string]Edge
+	gated    map[string]map[string]Edge
+	features map[string]struct{}
+	pivots   map[string]struct{}
+}
+
+func NewMatrix() *Matrix {
+	return &Matrix{
+		edges:    make(map[string]map[string]Edge),
+		gated:    make(map[string]map[string]Edge),
+		features: make(map[string]struct{}),
+		pivots:   make(map[string]struct{}),
+	}
+}
+
+func normalizeExt(ext string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(ext), "."))
+}
+
+func (m *Matrix) Add(e Edge) {
+	e.From = normalizeExt(e.From)
+	e.To = normalizeExt(e.To)
+	if e.From == "" || e.To == "" {
+		return
+	}
+	if e.Cost <= 0 {
+		e.Cost = 1
+	}
+	edges := m.edges
+	if e.Gated {
+		edges = m.gated
+	}
+	targets, ok := edges[e.From]
+	if !ok {
+		targets = make(map[string]Edge)
+		edges[e.From] = targets
+	}
+	if existing, exists := targets[e.To]; exists && existing.Cost <= e.Cost {
+		return
+	}
+	targets[e.To] = e
+}
+
+func (m *Matrix) AddPivot(ext string) {
+	ext = normalizeExt(ext)
+	if ext == "" {
+		return
+	}
+	m.pivots[ext] = struct{}{}
+}
+
+func (m *Matrix) isPivot(ext string) bool {
+	_, ok := m.pivots[ext]
+	return ok
+}
+
+func (m *Matrix) AddFeature(name string) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return
+	}
+	m.features[name] = struct{}{}
+}
+
+func (m *Matrix) Has(feature string) bool {
+	if m == nil {
+		return false
+	}
+	_, ok := m.features[strings.TrimSpace(feature)]
+	return ok
+}
+
+func (m *Matrix) Edge(from, to string) (Edge, bool) {
+	if m == nil {
+		return Edge{}, false
+	}
+	from = normalizeExt(from)
+	to = normalizeExt(to)
+	if e, ok := m.edges[from][to]; ok {
+		return e, true
+	}
+	e, ok := m.gated[from][to]
+	return e, ok
+}
+
+func (m *Matrix) Supports(from, to string) bool {
+	_, ok := m.Edge(from, to)
+	return ok
+}
+
+func (m *Matrix) Targets(from string) []string {
+	if m == nil {
+		return nil
+	}
+	targets := m.edges[normalizeExt(from)]
+	out := make([]string, 0, len(targets))
+	for to := range targets {
+		out = append(out, to)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func (m *Matrix) Path(from, to string) ([]Edge, bool) {
+	if m == nil {
+		return nil, false
+	}
+	from = normalizeExt(from)
+	to = normalizeExt(to)
+	if from == "" || to == "" {
+		return nil, false
+	}
+	var best []Edge
+	bestCost := 0
+	m.search(from, func(node string, path []Edge, cost int) {
+		if node != to {
+			return
+		}
+		if best == nil || cost < bestCost || (cost == bestCost && len(path) < len(best)) {
+			best = path
+			bestCost = cost
+		}
+	})
+	return best, best != nil
+}
+
+func (m *Matrix) Reachable(from string) []string {
+	if m == nil {
+		return nil
+	}
+	from = normalizeExt(from)
+	seen := map[string]struct{}{}
+	m.search(from, func(node string, path []Edge, cost int) {
+		if node != from {
+			seen[node] = struct{}{}
+		}
+	})
+	out := make([]string, 0, len(seen))
+	for node := range seen {
+		out = append(out, node)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func (m *Matrix) search(from string, visit func(node string, path []Edge, cost int)) {
+	type state struct {
+		node string
+		path []Edge
+		cost int
+	}
+	best := map[string]int{from: 0}
+	frontier := []state{{node: from}}
+	for hop := 0; hop < MaxHops && len(frontier) > 0; hop++ {
+		next := make([]state, 0)
+		for _, st := range frontier {
+			if hop > 0 && !m.isPivot(st.node) {
+				continue
+			}
+			for _, e := range m.edges[st.node] {
+				if e.To == from || pathVisits(st.path, e.To) {
+					continue
+				}
+				path := append(append([]Edge{}, st.path...), e)
+				cost := st.cost + e.Cost
+				visit(e.To, path, cost)
+				if c, ok := best[e.To]; ok && c <= cost {
+					continue
+				}
+				best[e.To] = cost
+				next = append(next, state{node: e.To, path: path, cost: cost})
+			}
+		}
+		frontier = next
+	}
+}
+
+func pathVisits(path []Edge, node string) bool {
+	for _, e := range path {
+		if e.From == node || e.To == node {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Matrix) PairCount() int {
+	if m == nil {
+		return 0
+	}
+	n := 0
+	for _, targets := range m.edges {
+		n += len(targets)
+	}
+	for from, targets := range m.gated {
+		for to := range targets {
+			if _, ok := m.edges[from][to]; !ok {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func (m *Matrix) Features() []string {
+	if m == nil {
+		return nil
+	}
+	out := make([]string, 0, len(m.features))
+	for f := range m.features {
+		out = append(out, f)
+	}
+	sort.Strings(out)
+	return out
+}
+
+var current atomic.Pointer[Matrix]
+
+func SetCurrent(m *Matrix) {
+	current.Store(m)
+}
+
+func Current() *Matrix {
+	return current.Load()
+}
+
+func Supports(from, to string) bool {
+	m := Current()
+	if m == nil {
+		return true
+	}
+	return m.Supports(from, to)
+}
+
+func Reachable(from string) ([]string, bool) {
+	m := Current()
+	if m == nil {
+		return nil, false
+	}
+	return m.Reachable(from), true
+}
+
+func Has(feature string) bool {
+	m := Current()
+	if m == nil {
+		return true
+	}
+	return m.Has(feature)
+}
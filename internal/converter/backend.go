@@ -0,0 +1,190 @@
+package converter
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+type Pair struct {
+	From string
+	To   string
+}
+
+type Requirement struct {
+	Name     string
+	Commands []string
+}
+
+type Backend interface {
+	Name() string
+	Pairs() []Pair
+	Requirements() []Requirement
+	Convert(ctx context.Context, inputPath, outputPath string, options map[string]interface{}) error
+}
+
+type OptionMatcher interface {
+	MatchOptions(options map[string]interface{}) bool
+}
+
+type MultiInputBackend interface {
+	ConvertInputs(ctx context.Context, inputPaths []string, outputPath string, options map[string]interface{}) error
+}
+
+type Registry struct {
+	mu       sync.RWMutex
+	backends []Backend
+	byName   map[string]Backend
+	byPair   map[Pair][]Backend
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		byName: make(map[string]Backend),
+		byPair: make(map[Pair][]Backend),
+	}
+}
+
+func (r *Registry) Register(b Backend) {
+	if b == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	name := strings.TrimSpace(b.Name())
+	if _, exists := r.byName[name]; exists {
+		panic(fmt.Sprintf("converter: backend %q already registered", name))
+	}
+	r.byName[name] = b
+	r.backends = append(r.backends, b)
+	for _, p := range b.Pairs() {
+		p = normalizePair(p)
+		if p.From == "" || p.To == "" {
+			continue
+		}
+		r.byPair[p] = append(r.byPair[p], b)
+	}
+}
+
+func (r *Registry) Get(name string) (Backend, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	b, ok := r.byName[strings.TrimSpace(name)]
+	return b, ok
+}
+
+func (r *Registry) Lookup(from, to string) (Backend, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	list := r.byPair[normalizePair(Pair{From: from, To: to})]
+	if len(list) == 0 {
+		return nil, false
+	}
+	return list[0], true
+}
+
+func (r *Registry) LookupFor(from, to string, options map[string]interface{}) (Backend, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var fallback Backend
+	for _, b := range r.byPair[normalizePair(Pair{From: from, To: to})] {
+		m, ok := b.(OptionMatcher)
+		if !ok {
+			if fallback == nil {
+				fallback = b
+			}
+			continue
+		}
+		if m.MatchOptions(options) {
+			return b, true
+		}
+	}
+	return fallback, fallback != nil
+}
+
+func (r *Registry) Match(from, to string, options map[string]interface{}) (Backend, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, b := range r.byPair[normalizePair(Pair{From: from, To: to})] {
+		if m, ok := b.(OptionMatcher); ok && m.MatchOptions(options) {
+			return b, true
+		}
+	}
+	return nil, false
+}
+
+func (r *Registry) Backends() []Backend {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]Backend{}, r.backends...)
+}
+
+var defaultRegistry = NewRegistry()
+
+func DefaultRegistry() *Registry {
+	return defaultRegistry
+}
+
+func Register(b Backend) {
+	defaultRegistry.Register(b)
+}
+
+func normalizePair(p Pair) Pair {
+	return Pair{From: normalizeExt(p.From), To: normalizeExt(p.To)}
+}
+
+func normalizeExt(ext string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(ext), "."))
+}
+
+func pairsFrom(sources []string, targets []string) []Pair {
+	out := make([]Pair, 0, len(sources)*len(targets))
+	for _, from := range sources {
+		for _, to := range targets {
+			out = append(out, Pair{From: from, To: to})
+		}
+	}
+	return out
+}
+
+func requirementMet(req Requirement) (string, bool) {
+	for _, cmd := range req.Commands {
+		if hasCommand(cmd) {
+			return cmd, true
+		}
+	}
+	return "", false
+}
+
+func checkRequirements(b Backend) error {
+	for _, req := range b.Requirements() {
+		if _, ok := requirementMet(req); !ok {
+			return fmt.Errorf("%s не установлен", req.Name)
+		}
+	}
+	return nil
+}
+
+func extOf(path string) string {
+	return normalizeExt(filepath.Ext(path))
+}
+
+func init() {
+	Register(imageMagickBackend{})
+	Register(ffmpegBackend{})
+	Register(libreOfficeBackend{})
+	Register(calibreBackend{})
+	Register(pdfToTextBackend{})
+	Register(pdfToImageBackend{})
+	Register(pdfToHTMLBackend{})
+	Register(pdfToolsBackend{})
+	Register(ghostscriptBackend{})
+	Register(tesseractBackend{})
+	Register(subtitlesBackend{})
+	Register(sfntBackend{})
+	Register(woff2Backend{})
+	Register(fontForgeBackend{})
+}
@@ -0,0 +1,49 @@
+package converter
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+var calibreProgressRe = regexp.MustCompile(`^\s*(\d{1,3})%\s`)
+
+type calibreBackend struct{}
+
+func (calibreBackend) Name() string { return "calibre" }
+
+func (calibreBackend) Cost() int { return 3 }
+
+func (calibreBackend) Pairs() []Pair {
+	pairs := pairsFrom(ebookFormats(), calibreOutputFormats())
+	return append(pairs, pairsFrom([]string{"docx", "odt", "rtf", "txt"}, calibreEbookOutputFormats())...)
+}
+
+func calibreEbookOutputFormats() []string {
+	return []string{"epub", "mobi", "azw3", "fb2", "lrf", "pdb"}
+}
+
+func calibreOutputFormats() []string {
+	return append(calibreEbookOutputFormats(), "pdf", "docx", "rtf", "txt")
+}
+
+func (calibreBackend) Requirements() []Requirement {
+	return []Requirement{{Name: "Calibre", Commands: []string{"ebook-convert"}}}
+}
+
+func (calibreBackend) Convert(ctx context.Context, inputPath, outputPath string, options map[string]interface{}) error {
+	_ = options
+	cmd := commandContext(ctx, "ebook-convert", inputPath, outputPath)
+	output, err := runWithProgress(cmd, func(line string) bool {
+		if m := calibreProgressRe.FindStringSubmatch(line); m != nil {
+			percent, _ := strconv.Atoi(m[1])
+			reportProgress(ctx, float64(percent)/100)
+		}
+		return false
+	})
+	if err != nil {
+		return fmt.Errorf("ошибка Calibre: %v, вывод: %s", err, string(output))
+	}
+	return nil
+}
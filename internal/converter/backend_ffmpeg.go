@@ -0,0 +1,403 @@
+package converter
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BatmanBruc/bat-bot-convetor/internal/subtitles"
+)
+
+type ffmpegBackend struct{}
+
+const (
+	videoNoteSize       = 640
+	videoNoteMaxSeconds = 60
+)
+
+func (ffmpegBackend) Name() string { return "ffmpeg" }
+
+func (ffmpegBackend) Cost() int { return 2 }
+
+func (ffmpegBackend) Pairs() []Pair {
+	pairs := pairsFrom(audioFormats(), audioFormats())
+	pairs = append(pairs, pairsFrom(videoFormats(), audioFormats())...)
+	pairs = append(pairs, pairsFrom(videoFormats(), []string{"gif"})...)
+	pairs = append(pairs, pairsFrom(videoFormats(), videoFormats())...)
+	pairs = append(pairs, pairsFrom(videoFormats(), []string{"srt", "vtt", "ass"})...)
+	return pairs
+}
+
+func (ffmpegBackend) Requirements() []Requirement {
+	return []Requirement{{Name: "ffmpeg", Commands: []string{"ffmpeg"}}}
+}
+
+func (b ffmpegBackend) Convert(ctx context.Context, inputPath, outputPath string, options map[string]interface{}) error {
+	originalExt := extOf(inputPath)
+	targetExt := extOf(outputPath)
+
+	switch {
+	case isAudioFormat(originalExt) && isAudioFormat(targetExt):
+		return b.convertAudio(ctx, inputPath, outputPath, options)
+	case isVideoFormat(originalExt) && isAudioFormat(targetExt):
+		return b.convertVideoToAudio(ctx, inputPath, outputPath)
+	case isVideoFormat(originalExt) && targetExt == "gif":
+		return b.convertVideoToGif(ctx, inputPath, outputPath, options)
+	case isVideoFormat(originalExt) && isVideoFormat(targetExt):
+		return b.convertVideo(ctx, inputPath, outputPath, options)
+	case isVideoFormat(originalExt) && isSubtitleFormat(targetExt):
+		return b.extractSubtitles(ctx, inputPath, outputPath)
+	}
+	return fmt.Errorf("ffmpeg: конвертация из %s в %s не поддерживается", originalExt, targetExt)
+}
+
+func (ffmpegBackend) convertAudio(ctx context.Context, inputPath, outputPath string, options map[string]interface{}) error {
+	args := []string{"-i", inputPath}
+	if op, _ := optString(options, "aud_op"); op == "voice" {
+		args = append(args, "-vn", "-ac", "1", "-ar", "48000", "-c:a", "libopus", "-b:a", "48k", "-application", "voip", "-f", "ogg")
+	}
+	args = append(args, "-y", outputPath)
+
+	output, err := runFFmpeg(ctx, inputPath, args...)
+	if err != nil {
+		return fmt.Errorf("ошибка ffmpeg: %v, вывод: %s", err, string(output))
+	}
+
+	return nil
+}
+
+func (b ffmpegBackend) convertVideo(ctx context.Context, inputPath, outputPath string, options map[string]interface{}) error {
+	if op, _ := optString(options, "vid_op"); op == "note" {
+		return b.convertVideoNote(ctx, inputPath, outputPath)
+	}
+
+	args := []string{"-i", inputPath}
+	vf := ""
+	if w, ok := optInt(options, "vid_w"); ok && w > 0 {
+		if h, ok := optInt(options, "vid_h"); ok && h > 0 {
+			vf = fmt.Sprintf("scale=%d:%d:force_original_aspect_ratio=decrease,pad=%d:%d:(ow-iw)/2:(oh-ih)/2,setsar=1", w, h, w, h)
+		}
+	}
+	if h, ok := optInt(options, "vid_height"); ok && h > 0 {
+		if vf == "" {
+			vf = fmt.Sprintf("scale=-2:%d", h)
+		}
+	}
+	if crf, ok := optInt(options, "vid_crf"); ok && crf > 0 {
+		if crf < 18 {
+			crf = 18
+		}
+		if crf > 40 {
+			crf = 40
+		}
+		if vf != "" {
+			args = append(args, "-vf", vf)
+		}
+		args = append(args, "-c:v", "libx264", "-preset", "veryfast", "-crf", fmt.Sprintf("%d", crf), "-c:a", "aac", "-b:a", "128k")
+	} else if vf != "" {
+		args = append(args, "-vf", vf, "-c:v", "libx264", "-preset", "veryfast", "-crf", "23", "-c:a", "aac", "-b:a", "128k")
+	}
+	args = append(args, "-y", outputPath)
+
+	output, err := runFFmpeg(ctx, inputPath, args...)
+	if err != nil {
+		return fmt.Errorf("ошибка ffmpeg: %v, вывод: %s", err, string(output))
+	}
+
+	return nil
+}
+
+func (ffmpegBackend) convertVideoNote(ctx context.Context, inputPath, outputPath string) error {
+	filter := fmt.Sprintf("crop='trunc(min(iw,ih)/2)*2':'trunc(min(iw,ih)/2)*2',scale='min(%d,iw)':'min(%d,ih)',setsar=1", videoNoteSize, videoNoteSize)
+	output, err := runFFmpeg(ctx, inputPath, "-i", inputPath, "-t", strconv.Itoa(videoNoteMaxSeconds), "-vf", filter,
+		"-c:v", "libx264", "-preset", "veryfast", "-crf", "26", "-pix_fmt", "yuv420p",
+		"-c:a", "aac", "-b:a", "96k", "-movflags", "+faststart", "-y", outputPath)
+	if err != nil {
+		return fmt.Errorf("ошибка ffmpeg (видеосообщение): %v, вывод: %s", err, string(output))
+	}
+	return nil
+}
+
+func (ffmpegBackend) convertVideoToAudio(ctx context.Context, inputPath, outputPath string) error {
+	output, err := runFFmpeg(ctx, inputPath, "-i", inputPath, "-vn", "-y", outputPath)
+	if err != nil {
+		return fmt.Errorf("ошибка ffmpeg (video->audio): %v, вывод: %s", err, string(output))
+	}
+	return nil
+}
+
+func (ffmpegBackend) convertVideoToGif(ctx context.Context, inputPath, outputPath string, options map[string]interface{}) error {
+	height := 480
+	if hasVideoOptions(options) {
+		op, _ := optString(options, "vid_op")
+		if op == "gif" {
+			if h, ok := optInt(options, "vid_gif_height"); ok && h > 0 {
+				height = h
+			}
+		}
+	}
+	if height < 120 {
+		height = 120
+	}
+	if height > 1080 {
+		height = 1080
+	}
+	filter := fmt.Sprintf("fps=12,scale=-2:%d:flags=lanczos,split[s0][s1];[s0]palettegen[p];[s1][p]paletteuse", height)
+	output, err := runFFmpeg(ctx, inputPath, "-i", inputPath, "-vf", filter, "-loop", "0", "-y", outputPath)
+	if err != nil {
+		return fmt.Errorf("ошибка ffmpeg (video->gif): %v, вывод: %s", err, string(output))
+	}
+	return nil
+}
+
+type subtitleTrack struct {
+	Index    string
+	Codec    string
+	Language string
+}
+
+func isBitmapSubtitleCodec(codec string) bool {
+	switch codec {
+	case "hdmv_pgs_subtitle", "dvd_subtitle", "dvb_subtitle", "xsub":
+		return true
+	}
+	return false
+}
+
+func subtitleTracks(ctx context.Context, inputPath string) ([]subtitleTrack, error) {
+	lines, err := commandOutputLines(ctx, "ffprobe", "-v", "error", "-select_streams", "s",
+		"-show_entries", "stream=index,codec_name:stream_tags=language", "-of", "csv=p=0", inputPath)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка ffprobe: %v", err)
+	}
+	tracks := make([]subtitleTrack, 0)
+	for _, line := range lines {
+		fields := strings.Split(strings.TrimSpace(line), ",")
+		if len(fields) < 2 || fields[0] == "" {
+			continue
+		}
+		t := subtitleTrack{Index: fields[0], Codec: fields[1]}
+		if len(fields) > 2 {
+			t.Language = fields[2]
+		}
+		tracks = append(tracks, t)
+	}
+	return tracks, nil
+}
+
+func (ffmpegBackend) extractSubtitles(ctx context.Context, inputPath, outputPath string) error {
+	if !hasCommand("ffprobe") {
+		cmd := commandContext(ctx, "ffmpeg", "-i", inputPath, "-map", "0:s:0", "-y", outputPath)
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("ошибка ffmpeg (извлечение субтитров): %v, вывод: %s", err, string(output))
+		}
+		return nil
+	}
+
+	all, err := subtitleTracks(ctx, inputPath)
+	if err != nil {
+		return err
+	}
+	tracks := make([]subtitleTrack, 0, len(all))
+	for _, t := range all {
+		if !isBitmapSubtitleCodec(t.Codec) {
+			tracks = append(tracks, t)
+		}
+	}
+	if len(tracks) == 0 {
+		if len(all) > 0 {
+			return fmt.Errorf("субтитры в видео хранятся картинками, извлечь из них текст нельзя")
+		}
+		return fmt.Errorf("в видео нет дорожек субтитров")
+	}
+
+	workDir, err := os.MkdirTemp(filepath.Dir(outputPath), "subs_*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.RemoveAll(workDir) }()
+
+	target := extOf(outputPath)
+	files := make([]string, 0, len(tracks))
+	names := make([]string, 0, len(tracks))
+	for i, t := range tracks {
+		name := fmt.Sprintf("track-%02d", i+1)
+		if t.Language != "" && t.Language != "und" {
+			name += "." + t.Language
+		}
+		name += "." + target
+		out := filepath.Join(workDir, name)
+
+		cmd := commandContext(ctx, "ffmpeg", "-i", inputPath, "-map", "0:"+t.Index, "-y", out)
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("ошибка ffmpeg (извлечение субтитров): %v, вывод: %s", err, string(output))
+		}
+		files = append(files, out)
+		names = append(names, name)
+	}
+
+	if err := copyFile(files[0], outputPath); err != nil {
+		return err
+	}
+	if len(files) == 1 {
+		return nil
+	}
+	return writeZip(ArchivePath(outputPath), files, names)
+}
+
+func (b ffmpegBackend) ConvertInputs(ctx context.Context, inputPaths []string, outputPath string, options map[string]interface{}) error {
+	if len(inputPaths) != 2 || !isVideoFormat(extOf(inputPaths[0])) || !isSubtitleFormat(extOf(inputPaths[1])) {
+		return fmt.Errorf("нужны видео и файл субтитров")
+	}
+	if !isVideoFormat(extOf(outputPath)) {
+		return fmt.Errorf("ffmpeg: результат должен быть видео")
+	}
+
+	subsPath, err := prepareSubtitles(inputPaths[1], filepath.Dir(outputPath))
+	if err != nil {
+		return err
+	}
+	if subsPath != inputPaths[1] {
+		defer func() { _ = os.Remove(subsPath) }()
+	}
+
+	op, _ := optString(options, "vid_op")
+	switch op {
+	case "burn_subs":
+		return b.burnSubtitles(ctx, inputPaths[0], subsPath, outputPath)
+	case "mux_subs":
+		return b.muxSubtitles(ctx, inputPaths[0], subsPath, outputPath)
+	}
+	return fmt.Errorf("неизвестная операция с субтитрами: %q", op)
+}
+
+func prepareSubtitles(path string, dir string) (string, error) {
+	ext := extOf(path)
+	if ext == "ass" || ext == "ssa" {
+		return path, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	out, err := subtitles.Convert(data, ext, "srt")
+	if err != nil {
+		return "", fmt.Errorf("ошибка чтения субтитров: %v", err)
+	}
+	f, err := os.CreateTemp(dir, "subs_*.srt")
+	if err != nil {
+		return "", err
+	}
+	_, err = f.Write(out)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+func escapeFilterPath(path string) string {
+	return strings.NewReplacer(`\`, `\\`, `:`, `\:`, `'`, `\'`, `,`, `\,`, `[`, `\[`, `]`, `\]`).Replace(path)
+}
+
+func (ffmpegBackend) burnSubtitles(ctx context.Context, videoPath, subsPath, outputPath string) error {
+	filter := "subtitles=filename=" + escapeFilterPath(subsPath)
+	output, err := runFFmpeg(ctx, videoPath, "-i", videoPath, "-vf", filter,
+		"-c:v", "libx264", "-preset", "veryfast", "-crf", "23", "-c:a", "aac", "-b:a", "128k", "-y", outputPath)
+	if err != nil {
+		return fmt.Errorf("ошибка ffmpeg (вшивание субтитров): %v, вывод: %s", err, string(output))
+	}
+	return nil
+}
+
+func (ffmpegBackend) muxSubtitles(ctx context.Context, videoPath, subsPath, outputPath string) error {
+	args := []string{"-i", videoPath, "-i", subsPath}
+	if extOf(outputPath) == "mkv" {
+		args = append(args, "-map", "0", "-map", "1:0", "-c", "copy")
+	} else {
+		args = append(args, "-map", "0:v", "-map", "0:a?", "-map", "1:0", "-c:v", "copy", "-c:a", "copy", "-c:s", "mov_text", "-disposition:s:0", "default")
+	}
+	args = append(args, "-y", outputPath)
+
+	output, err := runFFmpeg(ctx, videoPath, args...)
+	if err != nil {
+		return fmt.Errorf("ошибка ffmpeg (добавление субтитров): %v, вывод: %s", err, string(output))
+	}
+	return nil
+}
+
+var ffmpegProgressKeys = map[string]bool{
+	"frame":       true,
+	"fps":         true,
+	"bitrate":     true,
+	"total_size":  true,
+	"out_time":    true,
+	"dup_frames":  true,
+	"drop_frames": true,
+	"speed":       true,
+}
+
+func mediaDuration(ctx context.Context, path string) time.Duration {
+	if !hasCommand("ffprobe") {
+		return 0
+	}
+	lines, err := commandOutputLines(ctx, "ffprobe", "-v", "error", "-show_entries", "format=duration", "-of", "default=nw=1:nk=1", path)
+	if err != nil || len(lines) == 0 {
+		return 0
+	}
+	seconds, err := strconv.ParseFloat(strings.TrimSpace(lines[0]), 64)
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
+
+func runFFmpeg(ctx context.Context, inputPath string, args ...string) ([]byte, error) {
+	if !hasProgress(ctx) {
+		return commandContext(ctx, "ffmpeg", args...).CombinedOutput()
+	}
+	total := mediaDuration(ctx, inputPath)
+	if total <= 0 {
+		return commandContext(ctx, "ffmpeg", args...).CombinedOutput()
+	}
+	for i := 0; i+1 < len(args); i++ {
+		if args[i] != "-t" {
+			continue
+		}
+		if sec, err := strconv.ParseFloat(args[i+1], 64); err == nil && sec > 0 {
+			if limit := time.Duration(sec * float64(time.Second)); limit < total {
+				total = limit
+			}
+		}
+	}
+
+	args = append([]string{"-progress", "pipe:1", "-nostats"}, args...)
+	return runWithProgress(commandContext(ctx, "ffmpeg", args...), func(line string) bool {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok {
+			return false
+		}
+		switch key {
+		case "out_time_us", "out_time_ms":
+			if us, err := strconv.ParseInt(value, 10, 64); err == nil && us > 0 {
+				reportProgress(ctx, float64(us)/float64(total.Microseconds()))
+			}
+			return true
+		case "progress":
+			if value == "end" {
+				reportProgress(ctx, 1)
+			}
+			return true
+		}
+		return ffmpegProgressKeys[key] || strings.HasPrefix(key, "stream_")
+	})
+}
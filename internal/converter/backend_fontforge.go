@@ -0,0 +1,30 @@
+package converter
+
+import (
+	"context"
+	"fmt"
+)
+
+type fontForgeBackend struct{}
+
+func (fontForgeBackend) Name() string { return "fontforge" }
+
+func (fontForgeBackend) Cost() int { return 2 }
+
+func (fontForgeBackend) Pairs() []Pair {
+	return pairsFrom([]string{"ttf", "otf", "woff", "woff2", "pfb"}, []string{"ttf", "otf", "woff", "woff2", "svg", "pfb"})
+}
+
+func (fontForgeBackend) Requirements() []Requirement {
+	return []Requirement{{Name: "FontForge", Commands: []string{"fontforge"}}}
+}
+
+func (fontForgeBackend) Convert(ctx context.Context, inputPath, outputPath string, options map[string]interface{}) error {
+	_ = options
+	cmd := commandContext(ctx, "fontforge", "-lang=ff", "-c", "Open($1); Generate($2)", inputPath, outputPath)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("ошибка FontForge: %v, вывод: %s", err, string(output))
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package converter
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+type ghostscriptBackend struct{}
+
+func (ghostscriptBackend) Name() string { return "ghostscript" }
+
+func (ghostscriptBackend) Cost() int { return 2 }
+
+func (ghostscriptBackend) Pairs() []Pair {
+	return []Pair{{From: "pdf", To: "pdf"}}
+}
+
+func (ghostscriptBackend) Requirements() []Requirement {
+	return []Requirement{{Name: "Ghostscript", Commands: []string{"gs"}}}
+}
+
+func (ghostscriptBackend) MatchOptions(options map[string]interface{}) bool {
+	op, _ := optString(options, "pdf_op")
+	return op == "compress"
+}
+
+func (ghostscriptBackend) Convert(ctx context.Context, inputPath, outputPath string, options map[string]interface{}) error {
+	preset, _ := optString(options, "pdf_preset")
+	switch preset {
+	case "screen", "ebook", "printer":
+	case "":
+		preset = "ebook"
+	default:
+		return fmt.Errorf("неизвестный профиль сжатия PDF: %q", preset)
+	}
+
+	cmd := commandContext(ctx, "gs",
+		"-sDEVICE=pdfwrite",
+		"-dCompatibilityLevel=1.5",
+		"-dPDFSETTINGS=/"+preset,
+		"-dDetectDuplicateImages=true",
+		"-dNOPAUSE", "-dQUIET", "-dBATCH", "-dSAFER",
+		"-sOutputFile="+outputPath,
+		inputPath,
+	)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("ошибка Ghostscript: %v, вывод: %s", err, string(output))
+	}
+
+	in, err := os.Stat(inputPath)
+	if err != nil {
+		return err
+	}
+	out, err := os.Stat(outputPath)
+	if err != nil {
+		return fmt.Errorf("Ghostscript не создал файл: %s", outputPath)
+	}
+	if out.Size() >= in.Size() {
+		return copyFile(inputPath, outputPath)
+	}
+	return nil
+}
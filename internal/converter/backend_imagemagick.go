@@ -0,0 +1,69 @@
+package converter
+
+import (
+	"context"
+	"fmt"
+)
+
+type imageMagickBackend struct{}
+
+func (imageMagickBackend) Name() string { return "imagemagick" }
+
+func (imageMagickBackend) Cost() int { return 1 }
+
+func (imageMagickBackend) Pairs() []Pair {
+	return pairsFrom(imageFormats(), imageFormats())
+}
+
+func (imageMagickBackend) Requirements() []Requirement {
+	return []Requirement{{Name: "ImageMagick", Commands: []string{"magick", "convert"}}}
+}
+
+func (b imageMagickBackend) Convert(ctx context.Context, inputPath, outputPath string, options map[string]interface{}) error {
+	targetExt := extOf(outputPath)
+	if !isImageFormat(targetExt) {
+		return fmt.Errorf("целевой формат %s не является форматом изображения", targetExt)
+	}
+
+	cmdName, ok := requirementMet(b.Requirements()[0])
+	if !ok {
+		return fmt.Errorf("ImageMagick не установлен")
+	}
+
+	args := []string{inputPath}
+	if max, ok := optInt(options, "img_max"); ok && max > 0 {
+		args = append(args, "-resize", fmt.Sprintf("%dx%d>", max, max))
+	}
+	if w, ok := optInt(options, "img_w"); ok && w > 0 {
+		if h, ok := optInt(options, "img_h"); ok && h > 0 {
+			bg := "white"
+			if bgs, ok := optString(options, "img_bg"); ok {
+				bg = bgs
+			}
+			args = append(args,
+				"-resize", fmt.Sprintf("%dx%d", w, h),
+				"-background", bg,
+				"-gravity", "center",
+				"-extent", fmt.Sprintf("%dx%d", w, h),
+			)
+		}
+	}
+	if q, ok := optInt(options, "img_quality"); ok && q > 0 {
+		if q > 95 {
+			q = 95
+		}
+		if q < 10 {
+			q = 10
+		}
+		args = append(args, "-quality", fmt.Sprintf("%d", q))
+	}
+	args = append(args, outputPath)
+
+	cmd := commandContext(ctx, cmdName, args...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("ошибка ImageMagick: %v, вывод: %s", err, string(output))
+	}
+
+	return nil
+}
@@ -0,0 +1,126 @@
+package converter
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type libreOfficeBackend struct{}
+
+func (libreOfficeBackend) Name() string { return "libreoffice" }
+
+func (libreOfficeBackend) Cost() int { return 3 }
+
+func (libreOfficeBackend) Pairs() []Pair {
+	pairs := pairsFrom(writerFormats(), append(writerFormats(), "pdf"))
+	pairs = append(pairs, pairsFrom(sheetFormats(), append(sheetFormats(), "pdf"))...)
+	pairs = append(pairs, pairsFrom(slideFormats(), append(slideFormats(), "pdf"))...)
+	pairs = append(pairs, pairsFrom([]string{"pdf"}, []string{"docx", "odt"})...)
+	return pairs
+}
+
+func (libreOfficeBackend) Requirements() []Requirement {
+	return []Requirement{{Name: "LibreOffice", Commands: []string{"libreoffice", "soffice"}}}
+}
+
+func (b libreOfficeBackend) Convert(ctx context.Context, inputPath, outputPath string, options map[string]interface{}) error {
+	_ = options
+	cmdName, ok := requirementMet(b.Requirements()[0])
+	if !ok {
+		return fmt.Errorf("LibreOffice не установлен")
+	}
+
+	outputDir := filepath.Dir(outputPath)
+	convertTo, expectedExt, err := libreOfficeConvertToArg(extOf(outputPath))
+	if err != nil {
+		return err
+	}
+
+	args := []string{"--headless"}
+	if extOf(inputPath) == "pdf" {
+		args = append(args, "--infilter=writer_pdf_import")
+	}
+	args = append(args, "--convert-to", convertTo, "--outdir", outputDir, inputPath)
+
+	stop := tickProgress(ctx, libreOfficeEstimate(ctx, inputPath))
+	cmd := commandContext(ctx, cmdName, args...)
+	output, err := cmd.CombinedOutput()
+	stop()
+	if err != nil {
+		err = fmt.Errorf("ошибка LibreOffice: %v, вывод: %s", err, string(output))
+		if libreOfficeLocked(output) {
+			return transient(err)
+		}
+		return err
+	}
+
+	baseName := strings.TrimSuffix(filepath.Base(inputPath), filepath.Ext(inputPath))
+	generated := filepath.Join(outputDir, baseName+"."+expectedExt)
+	if _, err := os.Stat(generated); os.IsNotExist(err) {
+		generatedAlt := filepath.Join(outputDir, baseName+"."+strings.ToUpper(expectedExt))
+		if _, err2 := os.Stat(generatedAlt); err2 == nil {
+			generated = generatedAlt
+		} else {
+			err := fmt.Errorf("LibreOffice не создал файл: %s\nвывод: %s", generated, string(output))
+			if libreOfficeLocked(output) {
+				return transient(err)
+			}
+			return err
+		}
+	}
+
+	reportProgress(ctx, 1)
+	if filepath.Clean(generated) == filepath.Clean(outputPath) {
+		return nil
+	}
+	defer func() { _ = os.Remove(generated) }()
+	return copyFile(generated, outputPath)
+}
+
+func libreOfficeConvertToArg(targetExt string) (convertTo string, expectedExt string, err error) {
+	targetExt = normalizeExt(targetExt)
+	if targetExt == "" {
+		return "", "", fmt.Errorf("пустой целевой формат для LibreOffice")
+	}
+
+	switch targetExt {
+	case "txt":
+		return "txt:Text", "txt", nil
+	case "docx":
+		return "docx:MS Word 2007 XML", "docx", nil
+	case "odt":
+		return "odt:writer8", "odt", nil
+	default:
+		return targetExt, targetExt, nil
+	}
+}
+
+func libreOfficeLocked(output []byte) bool {
+	text := strings.ToLower(string(output))
+	return strings.Contains(text, "user installation could not be completed") ||
+		strings.Contains(text, ".~lock.")
+}
+
+var pdfInfoPagesRe = regexp.MustCompile(`(?m)^Pages:\s*(\d+)`)
+
+func libreOfficeEstimate(ctx context.Context, inputPath string) time.Duration {
+	if extOf(inputPath) == "pdf" && hasCommand("pdfinfo") {
+		if output, err := commandContext(ctx, "pdfinfo", inputPath).Output(); err == nil {
+			if m := pdfInfoPagesRe.FindSubmatch(output); m != nil {
+				pages, _ := strconv.Atoi(string(m[1]))
+				return 3*time.Second + time.Duration(pages)*500*time.Millisecond
+			}
+		}
+	}
+	estimate := 5 * time.Second
+	if info, err := os.Stat(inputPath); err == nil {
+		estimate += time.Duration(info.Size()/(100*1024)) * time.Second
+	}
+	return estimate
+}
@@ -0,0 +1,320 @@
+package converter
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type pdfToolsBackend struct{}
+
+func (pdfToolsBackend) Name() string { return "qpdf" }
+
+func (pdfToolsBackend) Cost() int { return 1 }
+
+func (pdfToolsBackend) Pairs() []Pair {
+	return []Pair{{From: "pdf", To: "pdf"}}
+}
+
+func (pdfToolsBackend) Requirements() []Requirement {
+	return []Requirement{{Name: "qpdf", Commands: []string{"qpdf", "pdftk"}}}
+}
+
+func (pdfToolsBackend) MatchOptions(options map[string]interface{}) bool {
+	op, _ := optString(options, "pdf_op")
+	switch op {
+	case "split", "split_ranges", "extract", "reorder", "delete", "rotate", "encrypt", "decrypt":
+		return true
+	}
+	return false
+}
+
+func (b pdfToolsBackend) Convert(ctx context.Context, inputPath, outputPath string, options map[string]interface{}) error {
+	tool, ok := requirementMet(b.Requirements()[0])
+	if !ok {
+		return fmt.Errorf("qpdf не установлен")
+	}
+
+	op, _ := optString(options, "pdf_op")
+	if op == "encrypt" || op == "decrypt" {
+		password, ok := options["pdf_password"].(string)
+		if !ok || password == "" {
+			return fmt.Errorf("не указан пароль")
+		}
+		if op == "encrypt" {
+			return pdfEncrypt(ctx, tool, inputPath, outputPath, password)
+		}
+		return pdfDecrypt(ctx, tool, inputPath, outputPath, password)
+	}
+
+	total, err := pdfPageCount(ctx, tool, inputPath)
+	if err != nil {
+		return err
+	}
+
+	var ranges []PageRange
+	if spec, ok := optString(options, "pdf_pages"); ok {
+		ranges, err = ParsePageRanges(spec)
+		if err != nil {
+			return err
+		}
+		if err := checkPageRanges(ranges, total); err != nil {
+			return err
+		}
+	}
+
+	switch op {
+	case "split":
+		return pdfSplitPages(ctx, tool, inputPath, outputPath)
+	case "split_ranges":
+		if len(ranges) == 0 {
+			return fmt.Errorf("не указаны диапазоны страниц")
+		}
+		return pdfSplitRanges(ctx, tool, inputPath, outputPath, ranges)
+	case "extract", "reorder":
+		if len(ranges) == 0 {
+			return fmt.Errorf("не указаны страницы")
+		}
+		return pdfSelectPages(ctx, tool, inputPath, outputPath, ranges)
+	case "delete":
+		if len(ranges) == 0 {
+			return fmt.Errorf("не указаны страницы")
+		}
+		removed := map[int]bool{}
+		for _, r := range ranges {
+			for _, p := range r.pages(total) {
+				removed[p] = true
+			}
+		}
+		kept := make([]int, 0, total)
+		for p := 1; p <= total; p++ {
+			if !removed[p] {
+				kept = append(kept, p)
+			}
+		}
+		if len(kept) == 0 {
+			return fmt.Errorf("нельзя удалить все страницы документа")
+		}
+		return pdfSelectPages(ctx, tool, inputPath, outputPath, pagesToRanges(kept))
+	case "rotate":
+		angle, _ := optInt(options, "pdf_rotate")
+		if angle != 90 && angle != 180 && angle != 270 {
+			return fmt.Errorf("неверный угол поворота: %d", angle)
+		}
+		if len(ranges) == 0 {
+			ranges = []PageRange{{From: 1}}
+		}
+		return pdfRotate(ctx, tool, inputPath, outputPath, angle, ranges, total)
+	default:
+		return fmt.Errorf("неизвестная операция с PDF: %q", op)
+	}
+}
+
+var pdftkPagesRe = regexp.MustCompile(`NumberOfPages:\s*(\d+)`)
+
+func pdfPageCount(ctx context.Context, tool string, inputPath string) (int, error) {
+	var cmd *exec.Cmd
+	if tool == "qpdf" {
+		cmd = commandContext(ctx, "qpdf", "--show-npages", inputPath)
+	} else {
+		cmd = commandContext(ctx, "pdftk", inputPath, "dump_data")
+	}
+	output, err := cmd.Output()
+	if err != nil {
+		return 0, fmt.Errorf("не удалось прочитать PDF: %v", err)
+	}
+
+	text := strings.TrimSpace(string(output))
+	if tool != "qpdf" {
+		m := pdftkPagesRe.FindStringSubmatch(text)
+		if m == nil {
+			return 0, fmt.Errorf("не удалось определить число страниц PDF")
+		}
+		text = m[1]
+	}
+	n, err := strconv.Atoi(text)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("не удалось определить число страниц PDF")
+	}
+	return n, nil
+}
+
+func pagesToRanges(pages []int) []PageRange {
+	out := make([]PageRange, 0)
+	for _, p := range pages {
+		if n := len(out); n > 0 && out[n-1].To == p-1 {
+			out[n-1].To = p
+			continue
+		}
+		out = append(out, PageRange{From: p, To: p})
+	}
+	return out
+}
+
+func pdftkPageSpec(ranges []PageRange, suffix string) []string {
+	out := make([]string, 0, len(ranges))
+	for _, r := range ranges {
+		out = append(out, strings.Replace(r.String(), "z", "end", 1)+suffix)
+	}
+	return out
+}
+
+func runPdfTool(ctx context.Context, tool string, args ...string) error {
+	output, err := commandContext(ctx, tool, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("ошибка %s: %v, вывод: %s", tool, err, string(output))
+	}
+	return nil
+}
+
+func runPdfToolWithInput(ctx context.Context, tool string, input string, args ...string) error {
+	cmd := commandContext(ctx, tool, args...)
+	cmd.Stdin = strings.NewReader(input)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("ошибка %s: %v, вывод: %s", tool, err, string(output))
+	}
+	return nil
+}
+
+func pdfSelectPages(ctx context.Context, tool string, inputPath, outputPath string, ranges []PageRange) error {
+	if tool == "qpdf" {
+		return runPdfTool(ctx, tool, "--empty", "--pages", inputPath, formatPageRanges(ranges, ","), "--", outputPath)
+	}
+	args := append([]string{inputPath, "cat"}, pdftkPageSpec(ranges, "")...)
+	return runPdfTool(ctx, tool, append(args, "output", outputPath)...)
+}
+
+func pdfRotate(ctx context.Context, tool string, inputPath, outputPath string, angle int, ranges []PageRange, total int) error {
+	if tool == "qpdf" {
+		return runPdfTool(ctx, tool, inputPath, outputPath, fmt.Sprintf("--rotate=+%d:%s", angle, formatPageRanges(ranges, ",")))
+	}
+
+	suffix := map[int]string{90: "east", 180: "south", 270: "west"}[angle]
+	selected := map[int]bool{}
+	for _, r := range ranges {
+		for _, p := range r.pages(total) {
+			selected[p] = true
+		}
+	}
+	args := []string{inputPath, "cat"}
+	for p := 1; p <= total; p++ {
+		if selected[p] {
+			args = append(args, strconv.Itoa(p)+suffix)
+		} else {
+			args = append(args, strconv.Itoa(p))
+		}
+	}
+	return runPdfTool(ctx, tool, append(args, "output", outputPath)...)
+}
+
+func pdfSplitPages(ctx context.Context, tool string, inputPath, outputPath string) error {
+	workDir, err := os.MkdirTemp(filepath.Dir(outputPath), "split_*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.RemoveAll(workDir) }()
+
+	if tool == "qpdf" {
+		err = runPdfTool(ctx, tool, "--split-pages", inputPath, filepath.Join(workDir, "page-%d.pdf"))
+	} else {
+		err = runPdfTool(ctx, tool, inputPath, "burst", "output", filepath.Join(workDir, "page-%03d.pdf"))
+	}
+	if err != nil {
+		return err
+	}
+
+	parts, err := filepath.Glob(filepath.Join(workDir, "page-*.pdf"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(parts)
+	return writePdfParts(parts, outputPath)
+}
+
+func pdfSplitRanges(ctx context.Context, tool string, inputPath, outputPath string, ranges []PageRange) error {
+	workDir, err := os.MkdirTemp(filepath.Dir(outputPath), "split_*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.RemoveAll(workDir) }()
+
+	parts := make([]string, 0, len(ranges))
+	for i, r := range ranges {
+		part := filepath.Join(workDir, fmt.Sprintf("part-%02d.pdf", i+1))
+		if err := pdfSelectPages(ctx, tool, inputPath, part, []PageRange{r}); err != nil {
+			return err
+		}
+		parts = append(parts, part)
+	}
+	return writePdfParts(parts, outputPath)
+}
+
+func pdfEncrypt(ctx context.Context, tool string, inputPath, outputPath string, password string) error {
+	if tool == "qpdf" {
+		return runQpdfWithArgFile(ctx, outputPath, []string{"--encrypt", password, password, "256", "--", inputPath, outputPath})
+	}
+	return runPdfToolWithInput(ctx, tool, password+"\n"+password+"\n", inputPath, "output", outputPath, "user_pw", "PROMPT", "owner_pw", "PROMPT", "encrypt_128bit")
+}
+
+func pdfDecrypt(ctx context.Context, tool string, inputPath, outputPath string, password string) error {
+	var err error
+	if tool == "qpdf" {
+		err = runQpdfWithArgFile(ctx, outputPath, []string{"--password=" + password, "--decrypt", inputPath, outputPath})
+	} else {
+		err = runPdfToolWithInput(ctx, tool, password+"\n", inputPath, "input_pw", "PROMPT", "output", outputPath)
+	}
+	if err != nil && strings.Contains(strings.ToLower(err.Error()), "password") {
+		return fmt.Errorf("неверный пароль PDF")
+	}
+	return err
+}
+
+func runQpdfWithArgFile(ctx context.Context, outputPath string, args []string) error {
+	argFile, err := os.CreateTemp(filepath.Dir(outputPath), "qpdf_args_*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(argFile.Name()) }()
+
+	_, err = argFile.WriteString(strings.Join(args, "\n") + "\n")
+	if closeErr := argFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	output, err := commandContext(ctx, "qpdf", "@"+argFile.Name()).CombinedOutput()
+	if err != nil {
+		code := 0
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			code = exitErr.ExitCode()
+		}
+		if code == 3 {
+			return nil
+		}
+		return fmt.Errorf("ошибка qpdf: %v, вывод: %s", err, string(output))
+	}
+	return nil
+}
+
+func writePdfParts(parts []string, outputPath string) error {
+	switch len(parts) {
+	case 0:
+		return fmt.Errorf("не удалось разделить PDF")
+	case 1:
+		return copyFile(parts[0], outputPath)
+	}
+	names := make([]string, 0, len(parts))
+	for _, p := range parts {
+		names = append(names, filepath.Base(p))
+	}
+	return writeZip(ArchivePath(outputPath), parts, names)
+}
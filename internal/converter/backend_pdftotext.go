@@ -0,0 +1,41 @@
+package converter
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+type pdfToTextBackend struct{}
+
+func (pdfToTextBackend) Name() string { return "pdftotext" }
+
+func (pdfToTextBackend) Cost() int { return 1 }
+
+func (pdfToTextBackend) Pairs() []Pair {
+	return []Pair{{From: "pdf", To: "txt"}}
+}
+
+func (pdfToTextBackend) Requirements() []Requirement {
+	return []Requirement{{Name: "poppler-utils", Commands: []string{"pdftotext"}}}
+}
+
+func (pdfToTextBackend) Convert(ctx context.Context, inputPath, outputPath string, options map[string]interface{}) error {
+	cmd := commandContext(ctx, "pdftotext", "-enc", "UTF-8", inputPath, outputPath)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("ошибка pdftotext: %v, вывод: %s", err, string(output))
+	}
+
+	text, err := os.ReadFile(outputPath)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(string(text)) != "" || !canOCRPdf() {
+		return nil
+	}
+	log.Printf("pdftotext returned no text for %s, falling back to OCR", inputPath)
+	return tesseractBackend{}.Convert(ctx, inputPath, outputPath, options)
+}
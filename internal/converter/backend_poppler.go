@@ -0,0 +1,166 @@
+package converter
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type pdfToImageBackend struct{}
+
+func (pdfToImageBackend) Name() string { return "pdftoppm" }
+
+func (pdfToImageBackend) Cost() int { return 1 }
+
+func (pdfToImageBackend) Pairs() []Pair {
+	return pairsFrom([]string{"pdf"}, []string{"png", "jpg", "jpeg"})
+}
+
+func (pdfToImageBackend) Requirements() []Requirement {
+	return []Requirement{{Name: "poppler-utils", Commands: []string{"pdftoppm"}}}
+}
+
+func (pdfToImageBackend) Convert(ctx context.Context, inputPath, outputPath string, options map[string]interface{}) error {
+	dpi := 150
+	if v, ok := optInt(options, "pdf_dpi"); ok && v >= 50 && v <= 600 {
+		dpi = v
+	}
+
+	workDir, err := os.MkdirTemp(filepath.Dir(outputPath), "pages_*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.RemoveAll(workDir) }()
+
+	target := extOf(outputPath)
+	formatFlag := "-png"
+	if target == "jpg" || target == "jpeg" {
+		formatFlag = "-jpeg"
+	}
+
+	output, err := runPdfToPpm(ctx, "-r", fmt.Sprintf("%d", dpi), formatFlag, inputPath, filepath.Join(workDir, "page"))
+	if err != nil {
+		return fmt.Errorf("ошибка pdftoppm: %v, вывод: %s", err, string(output))
+	}
+
+	pages, err := filepath.Glob(filepath.Join(workDir, "page-*"))
+	if err != nil {
+		return err
+	}
+	if len(pages) == 0 {
+		return fmt.Errorf("pdftoppm не создал ни одной страницы\nвывод: %s", string(output))
+	}
+	sort.Strings(pages)
+
+	if len(pages) == 1 {
+		return copyFile(pages[0], outputPath)
+	}
+
+	names := make([]string, 0, len(pages))
+	for _, p := range pages {
+		name := strings.TrimSuffix(filepath.Base(p), filepath.Ext(p)) + "." + target
+		names = append(names, name)
+	}
+	return writeZip(ArchivePath(outputPath), pages, names)
+}
+
+type pdfToHTMLBackend struct{}
+
+func (pdfToHTMLBackend) Name() string { return "pdftohtml" }
+
+func (pdfToHTMLBackend) Cost() int { return 1 }
+
+func (pdfToHTMLBackend) Pairs() []Pair {
+	return []Pair{{From: "pdf", To: "html"}}
+}
+
+func (pdfToHTMLBackend) Requirements() []Requirement {
+	return []Requirement{{Name: "poppler-utils", Commands: []string{"pdftohtml"}}}
+}
+
+func (pdfToHTMLBackend) Convert(ctx context.Context, inputPath, outputPath string, options map[string]interface{}) error {
+	_ = options
+	workDir, err := os.MkdirTemp(filepath.Dir(outputPath), "html_*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.RemoveAll(workDir) }()
+
+	cmd := commandContext(ctx, "pdftohtml", "-s", "-i", "-noframes", "-enc", "UTF-8", inputPath, filepath.Join(workDir, "document"))
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("ошибка pdftohtml: %v, вывод: %s", err, string(output))
+	}
+
+	generated := filepath.Join(workDir, "document.html")
+	if _, err := os.Stat(generated); err != nil {
+		return fmt.Errorf("pdftohtml не создал файл: %s\nвывод: %s", generated, string(output))
+	}
+	return copyFile(generated, outputPath)
+}
+
+func ArchivePath(outputPath string) string {
+	return strings.TrimSuffix(outputPath, filepath.Ext(outputPath)) + ".zip"
+}
+
+func writeZip(zipPath string, files []string, names []string) error {
+	out, err := os.Create(zipPath)
+	if err != nil {
+		return err
+	}
+	zw := zip.NewWriter(out)
+
+	for i, p := range files {
+		if err := addZipFile(zw, p, names[i]); err != nil {
+			_ = zw.Close()
+			_ = out.Close()
+			_ = os.Remove(zipPath)
+			return err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		_ = out.Close()
+		_ = os.Remove(zipPath)
+		return err
+	}
+	return out.Close()
+}
+
+func addZipFile(zw *zip.Writer, path string, name string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, f)
+	return err
+}
+
+func runPdfToPpm(ctx context.Context, args ...string) ([]byte, error) {
+	cmd := commandContext(ctx, "pdftoppm", append([]string{"-progress"}, args...)...)
+	return runWithProgress(cmd, func(line string) bool {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			return false
+		}
+		page, err1 := strconv.Atoi(fields[0])
+		last, err2 := strconv.Atoi(fields[1])
+		if err1 != nil || err2 != nil || last <= 0 {
+			return false
+		}
+		reportProgress(ctx, float64(page)/float64(last))
+		return true
+	})
+}
@@ -0,0 +1,61 @@
+package converter
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/BatmanBruc/bat-bot-convetor/internal/fonts"
+)
+
+type sfntBackend struct{}
+
+func (sfntBackend) Name() string { return "sfnt" }
+
+func (sfntBackend) Cost() int { return 1 }
+
+func (sfntBackend) Pairs() []Pair {
+	return []Pair{
+		{From: "ttf", To: "woff"},
+		{From: "otf", To: "woff"},
+		{From: "woff", To: "ttf"},
+		{From: "woff", To: "otf"},
+		{From: "ttf", To: "eot"},
+		{From: "eot", To: "ttf"},
+	}
+}
+
+func (sfntBackend) Requirements() []Requirement {
+	return nil
+}
+
+func (sfntBackend) Convert(ctx context.Context, inputPath, outputPath string, options map[string]interface{}) error {
+	_ = ctx
+	_ = options
+	data, err := os.ReadFile(inputPath)
+	if err != nil {
+		return err
+	}
+
+	var out []byte
+	switch from, to := extOf(inputPath), extOf(outputPath); {
+	case to == "woff":
+		out, err = fonts.EncodeWOFF(data)
+	case to == "eot":
+		out, err = fonts.EncodeEOT(data)
+	case from == "woff":
+		var f *fonts.Font
+		f, err = fonts.DecodeWOFF(data)
+		if err == nil {
+			out = f.Bytes()
+		}
+	case from == "eot":
+		out, err = fonts.DecodeEOT(data)
+	default:
+		return fmt.Errorf("конвертация шрифта из %s в %s не поддерживается", from, to)
+	}
+	if err != nil {
+		return fmt.Errorf("ошибка конвертации шрифта: %v", err)
+	}
+	return os.WriteFile(outputPath, out, 0644)
+}
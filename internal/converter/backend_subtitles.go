@@ -0,0 +1,37 @@
+package converter
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/BatmanBruc/bat-bot-convetor/internal/subtitles"
+)
+
+type subtitlesBackend struct{}
+
+func (subtitlesBackend) Name() string { return "subtitles" }
+
+func (subtitlesBackend) Cost() int { return 1 }
+
+func (subtitlesBackend) Pairs() []Pair {
+	return filterPairs(pairsFrom(subtitleFormats(), subtitleFormats()), func(p Pair) bool { return p.From != p.To })
+}
+
+func (subtitlesBackend) Requirements() []Requirement {
+	return nil
+}
+
+func (subtitlesBackend) Convert(ctx context.Context, inputPath, outputPath string, options map[string]interface{}) error {
+	_ = ctx
+	_ = options
+	data, err := os.ReadFile(inputPath)
+	if err != nil {
+		return err
+	}
+	out, err := subtitles.Convert(data, extOf(inputPath), extOf(outputPath))
+	if err != nil {
+		return fmt.Errorf("ошибка конвертации субтитров: %v", err)
+	}
+	return os.WriteFile(outputPath, out, 0644)
+}
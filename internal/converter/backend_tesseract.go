@@ -0,0 +1,140 @@
+package converter
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BatmanBruc/bat-bot-convetor/internal/i18n"
+)
+
+type tesseractBackend struct{}
+
+func (tesseractBackend) Name() string { return "tesseract" }
+
+func (tesseractBackend) Cost() int { return 2 }
+
+func ocrImageFormats() []string {
+	return []string{"png", "jpg", "jpeg", "bmp", "tif", "tiff", "webp", "gif"}
+}
+
+func (tesseractBackend) Pairs() []Pair {
+	pairs := pairsFrom(ocrImageFormats(), []string{"txt", "pdf"})
+	return append(pairs, Pair{From: "pdf", To: "txt"}, Pair{From: "pdf", To: "pdf"})
+}
+
+func (tesseractBackend) Requirements() []Requirement {
+	return []Requirement{{Name: "tesseract", Commands: []string{"tesseract"}}}
+}
+
+func (b tesseractBackend) Probe(ctx context.Context) ([]Pair, error) {
+	_ = ctx
+	if hasCommand("pdftoppm") {
+		return b.Pairs(), nil
+	}
+	return filterPairs(b.Pairs(), func(p Pair) bool { return p.From != "pdf" }), nil
+}
+
+func (tesseractBackend) MatchOptions(options map[string]interface{}) bool {
+	op, _ := optString(options, "pdf_op")
+	return op == "ocr"
+}
+
+func (tesseractBackend) Convert(ctx context.Context, inputPath, outputPath string, options map[string]interface{}) error {
+	target := extOf(outputPath)
+	if target != "txt" && target != "pdf" {
+		return fmt.Errorf("OCR не поддерживает формат %s", target)
+	}
+
+	workDir, err := os.MkdirTemp(filepath.Dir(outputPath), "ocr_*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.RemoveAll(workDir) }()
+
+	input := inputPath
+	if extOf(inputPath) == "pdf" {
+		input, err = rasterizePdfForOCR(ctx, inputPath, workDir)
+		if err != nil {
+			return err
+		}
+	}
+	return runTesseract(ctx, input, outputPath, workDir, target, ocrLanguages(ctx, options))
+}
+
+func canOCRPdf() bool {
+	return hasCommand("tesseract") && hasCommand("pdftoppm")
+}
+
+func rasterizePdfForOCR(ctx context.Context, inputPath, workDir string) (string, error) {
+	if !hasCommand("pdftoppm") {
+		return "", fmt.Errorf("poppler-utils не установлен")
+	}
+	output, err := runPdfToPpm(withProgressRange(ctx, 0, 0.5), "-r", "300", "-png", inputPath, filepath.Join(workDir, "page"))
+	if err != nil {
+		return "", fmt.Errorf("ошибка pdftoppm: %v, вывод: %s", err, string(output))
+	}
+
+	pages, err := filepath.Glob(filepath.Join(workDir, "page-*.png"))
+	if err != nil {
+		return "", err
+	}
+	if len(pages) == 0 {
+		return "", fmt.Errorf("pdftoppm не создал ни одной страницы\nвывод: %s", string(output))
+	}
+	sort.Strings(pages)
+
+	listPath := filepath.Join(workDir, "pages.txt")
+	if err := os.WriteFile(listPath, []byte(strings.Join(pages, "\n")+"\n"), 0644); err != nil {
+		return "", err
+	}
+	return listPath, nil
+}
+
+func runTesseract(ctx context.Context, input, outputPath, workDir, target string, langs []string) error {
+	base := filepath.Join(workDir, "ocr")
+	args := []string{input, base}
+	if len(langs) > 0 {
+		args = append(args, "-l", strings.Join(langs, "+"))
+	}
+	args = append(args, target)
+
+	cmd := commandContext(ctx, "tesseract", args...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("ошибка tesseract: %v, вывод: %s", err, string(output))
+	}
+
+	generated := base + "." + target
+	if _, err := os.Stat(generated); err != nil {
+		return fmt.Errorf("tesseract не создал файл: %s\nвывод: %s", generated, string(output))
+	}
+	return copyFile(generated, outputPath)
+}
+
+func ocrLanguages(ctx context.Context, options map[string]interface{}) []string {
+	wanted := []string{"eng"}
+	if lang, _ := optString(options, "lang"); i18n.Parse(lang) == i18n.RU {
+		wanted = []string{"rus", "eng"}
+	}
+
+	output, err := commandContext(ctx, "tesseract", "--list-langs").CombinedOutput()
+	if err != nil {
+		return wanted
+	}
+	installed := map[string]bool{}
+	for _, line := range strings.Split(string(output), "\n") {
+		installed[strings.TrimSpace(line)] = true
+	}
+
+	langs := make([]string, 0, len(wanted))
+	for _, l := range wanted {
+		if installed[l] {
+			langs = append(langs, l)
+		}
+	}
+	return langs
+}
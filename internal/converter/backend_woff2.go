@@ -0,0 +1,60 @@
+package converter
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+type woff2Backend struct{}
+
+func (woff2Backend) Name() string { return "woff2" }
+
+func (woff2Backend) Cost() int { return 1 }
+
+func (woff2Backend) Pairs() []Pair {
+	return []Pair{
+		{From: "ttf", To: "woff2"},
+		{From: "otf", To: "woff2"},
+		{From: "woff2", To: "ttf"},
+		{From: "woff2", To: "otf"},
+	}
+}
+
+func (woff2Backend) Requirements() []Requirement {
+	return []Requirement{
+		{Name: "woff2_compress", Commands: []string{"woff2_compress"}},
+		{Name: "woff2_decompress", Commands: []string{"woff2_decompress"}},
+	}
+}
+
+func (woff2Backend) Convert(ctx context.Context, inputPath, outputPath string, options map[string]interface{}) error {
+	_ = options
+	workDir, err := os.MkdirTemp(filepath.Dir(outputPath), "woff2_*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.RemoveAll(workDir) }()
+
+	from := extOf(inputPath)
+	input := filepath.Join(workDir, "font."+from)
+	if err := copyFile(inputPath, input); err != nil {
+		return err
+	}
+
+	tool, generated := "woff2_compress", filepath.Join(workDir, "font.woff2")
+	if from == "woff2" {
+		tool, generated = "woff2_decompress", filepath.Join(workDir, "font.ttf")
+	}
+
+	cmd := commandContext(ctx, tool, input)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("ошибка %s: %v, вывод: %s", tool, err, string(output))
+	}
+	if _, err := os.Stat(generated); err != nil {
+		return fmt.Errorf("%s не создал файл: %s\nвывод: %s", tool, generated, string(output))
+	}
+	return copyFile(generated, outputPath)
+}
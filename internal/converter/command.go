@@ -0,0 +1,18 @@
+package converter
+
+import (
+	"context"
+	"os/exec"
+	"time"
+)
+
+func commandContext(ctx context.Context, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, args...)
+	setProcessGroup(cmd)
+	cmd.WaitDelay = 5 * time.Second
+	return cmd
+}
+
+func CommandContext(ctx context.Context, name string, args ...string) *exec.Cmd {
+	return commandContext(ctx, name, args...)
+}
@@ -12,27 +12,40 @@ import (
 	"strings"
 	"time"
 
+	"github.com/BatmanBruc/bat-bot-convetor/internal/capability"
 	"github.com/BatmanBruc/bat-bot-convetor/internal/formats"
+	"github.com/BatmanBruc/bat-bot-convetor/internal/subtitles"
+	"github.com/BatmanBruc/bat-bot-convetor/types"
 	"github.com/go-telegram/bot"
 )
 
 type Converter interface {
-	Convert(ctx context.Context, bot *bot.Bot, fileID string, originalExt, targetExt string, originalFileName string, options map[string]interface{}) (resultPath string, resultFileName string, err error)
+	Convert(ctx context.Context, bot *bot.Bot, fileID string, originalExt, targetExt string, originalFileName string, inputs []types.TaskInput, options map[string]interface{}) (resultPath string, resultFileName string, err error)
 }
 
 type DefaultConverter struct {
-	tempDir string
+	tempDir  string
+	registry *Registry
+	caps     *capability.Matrix
 }
 
 func NewDefaultConverter() *DefaultConverter {
+	return NewDefaultConverterWithRegistry(DefaultRegistry())
+}
+
+func NewDefaultConverterWithRegistry(registry *Registry) *DefaultConverter {
 	tempDir := filepath.Join(os.TempDir(), "bot_converter")
 	_ = os.MkdirAll(tempDir, 0755)
+	if registry == nil {
+		registry = DefaultRegistry()
+	}
 	return &DefaultConverter{
-		tempDir: tempDir,
+		tempDir:  tempDir,
+		registry: registry,
 	}
 }
 
-func (c *DefaultConverter) Convert(ctx context.Context, botClient *bot.Bot, fileID string, originalExt, targetExt string, originalFileName string, options map[string]interface{}) (string, string, error) {
+func (c *DefaultConverter) Convert(ctx context.Context, botClient *bot.Bot, fileID string, originalExt, targetExt string, originalFileName string, inputs []types.TaskInput, options map[string]interface{}) (string, string, error) {
 	originalExt = strings.ToLower(strings.TrimPrefix(originalExt, "."))
 	targetExt = strings.ToLower(strings.TrimPrefix(targetExt, "."))
 
@@ -40,32 +53,48 @@ func (c *DefaultConverter) Convert(ctx context.Context, botClient *bot.Bot, file
 		return "", "", fmt.Errorf("неподдерживаемый целевой формат: %s", targetExt)
 	}
 
-	fileInfo, err := botClient.GetFile(ctx, &bot.GetFileParams{
-		FileID: fileID,
-	})
-	if err != nil {
-		return "", "", fmt.Errorf("ошибка получения файла: %v", err)
-	}
-
-	fileURL := fmt.Sprintf("https://api.telegram.org/file/bot%s/%s", botClient.Token(), fileInfo.FilePath)
-
 	nonce := time.Now().UnixNano()
 	originalPath := filepath.Join(c.tempDir, fmt.Sprintf("%s_%d_original.%s", fileID, nonce, originalExt))
 	resultPath := filepath.Join(c.tempDir, fmt.Sprintf("%s_%d_result.%s", fileID, nonce, targetExt))
 	resultFileName := buildResultFileName(originalFileName, targetExt)
 
-	if err := c.downloadFile(ctx, fileURL, originalPath); err != nil {
-		return "", "", fmt.Errorf("ошибка загрузки файла: %v", err)
+	if err := c.fetchFile(ctx, botClient, fileID, originalPath); err != nil {
+		return "", "", err
 	}
 	defer func() { _ = os.Remove(originalPath) }()
 
-	if err := c.convertFile(ctx, originalPath, resultPath, originalExt, targetExt, options); err != nil {
+	inputPaths := []string{originalPath}
+	for i, in := range inputs {
+		ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(in.FileName), "."))
+		path := filepath.Join(c.tempDir, fmt.Sprintf("%s_%d_input%d.%s", fileID, nonce, i+1, ext))
+		if err := c.fetchFile(ctx, botClient, in.FileID, path); err != nil {
+			return "", "", err
+		}
+		defer func() { _ = os.Remove(path) }()
+		inputPaths = append(inputPaths, path)
+	}
+
+	var err error
+	if len(inputPaths) > 1 {
+		err = c.convertInputs(ctx, inputPaths, resultPath, originalExt, targetExt, options)
+	} else {
+		err = c.convertFile(ctx, originalPath, resultPath, originalExt, targetExt, options)
+	}
+	if err != nil {
 		_ = os.Remove(originalPath)
 		_ = os.Remove(resultPath)
-		return "", "", fmt.Errorf("ошибка конвертации: %v", err)
+		_ = os.Remove(ArchivePath(resultPath))
+		return "", "", fmt.Errorf("ошибка конвертации: %w", err)
 	}
 
 	info, err := os.Stat(resultPath)
+	if os.IsNotExist(err) {
+		if archiveInfo, archiveErr := os.Stat(ArchivePath(resultPath)); archiveErr == nil {
+			resultPath = ArchivePath(resultPath)
+			resultFileName = buildResultFileName(originalFileName, "zip")
+			info, err = archiveInfo, nil
+		}
+	}
 	if os.IsNotExist(err) {
 		_ = os.Remove(originalPath)
 		return "", "", fmt.Errorf("файл результата не был создан: %s", resultPath)
@@ -81,6 +110,21 @@ func (c *DefaultConverter) Convert(ctx context.Context, botClient *bot.Bot, file
 	return resultPath, resultFileName, nil
 }
 
+func (c *DefaultConverter) fetchFile(ctx context.Context, botClient *bot.Bot, fileID string, destPath string) error {
+	fileInfo, err := botClient.GetFile(ctx, &bot.GetFileParams{
+		FileID: fileID,
+	})
+	if err != nil {
+		return fmt.Errorf("ошибка получения файла: %w", err)
+	}
+
+	fileURL := fmt.Sprintf("https://api.telegram.org/file/bot%s/%s", botClient.Token(), fileInfo.FilePath)
+	if err := c.downloadFile(ctx, fileURL, destPath); err != nil {
+		return fmt.Errorf("ошибка загрузки файла: %w", err)
+	}
+	return nil
+}
+
 func (c *DefaultConverter) downloadFile(ctx context.Context, url, destPath string) error {
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
@@ -97,7 +141,11 @@ func (c *DefaultConverter) downloadFile(ctx context.Context, url, destPath strin
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("ошибка загрузки файла: статус %d", resp.StatusCode)
+		err := fmt.Errorf("ошибка загрузки файла: статус %d", resp.StatusCode)
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+			return transient(err)
+		}
+		return err
 	}
 
 	out, err := os.Create(destPath)
@@ -109,7 +157,7 @@ func (c *DefaultConverter) downloadFile(ctx context.Context, url, destPath strin
 	_, err = io.Copy(out, resp.Body)
 	if err != nil {
 		_ = os.Remove(destPath)
-		return err
+		return transient(err)
 	}
 	return nil
 }
@@ -119,48 +167,62 @@ func (c *DefaultConverter) convertFile(ctx context.Context, inputPath, outputPat
 	targetExt = strings.ToLower(targetExt)
 
 	if originalExt == targetExt {
-		if c.isImageFormat(originalExt) && c.isImageFormat(targetExt) && hasImageOptions(options) {
-			return c.convertImage(ctx, inputPath, outputPath, originalExt, targetExt, options)
+		if !hasImageOptions(options) && !hasAudioOptions(options) && !hasVideoOptions(options) && !hasPdfOptions(options) {
+			return copyFile(inputPath, outputPath)
 		}
-		return c.copyFile(inputPath, outputPath)
+		return c.convertSameFormat(ctx, inputPath, outputPath, originalExt, options)
 	}
 
-	if c.isImageFormat(originalExt) && c.isImageFormat(targetExt) {
-		return c.convertImage(ctx, inputPath, outputPath, originalExt, targetExt, options)
+	if backend, ok := c.registry.Match(originalExt, targetExt, options); ok {
+		if c.caps != nil && !c.caps.Supports(originalExt, targetExt) {
+			return fmt.Errorf("конвертация из %s в %s недоступна на этом сервере", originalExt, targetExt)
+		}
+		if c.caps != nil && !c.caps.Has(backend.Name()) {
+			return fmt.Errorf("обработка %s недоступна на этом сервере", originalExt)
+		}
+		return c.runStep(ctx, Step{From: originalExt, To: targetExt, Backend: backend.Name()}, inputPath, outputPath, options)
 	}
 
-	if c.isAudioFormat(originalExt) && c.isAudioFormat(targetExt) {
-		return c.convertAudio(ctx, inputPath, outputPath, originalExt, targetExt)
+	steps, err := c.Plan(originalExt, targetExt)
+	if err != nil {
+		return err
 	}
+	return c.runPlan(ctx, steps, inputPath, outputPath, options)
+}
 
-	if c.isVideoFormat(originalExt) && c.isAudioFormat(targetExt) {
-		return c.convertVideoToAudio(ctx, inputPath, outputPath)
+func (c *DefaultConverter) convertSameFormat(ctx context.Context, inputPath, outputPath string, ext string, options map[string]interface{}) error {
+	backend, ok := c.registry.LookupFor(ext, ext, options)
+	if !ok {
+		return fmt.Errorf("обработка %s не поддерживается", ext)
 	}
-
-	if c.isVideoFormat(originalExt) && targetExt == "gif" {
-		return c.convertVideoToGif(ctx, inputPath, outputPath, options)
+	if c.caps != nil && !c.caps.Has(backend.Name()) {
+		return fmt.Errorf("обработка %s недоступна на этом сервере", ext)
 	}
-
-	if c.isVideoFormat(originalExt) && c.isVideoFormat(targetExt) {
-		return c.convertVideo(ctx, inputPath, outputPath, originalExt, targetExt, options)
+	if err := checkRequirements(backend); err != nil {
+		return err
 	}
+	return backend.Convert(ctx, inputPath, outputPath, options)
+}
 
-	if c.isEbookFormat(originalExt) && c.isEbookFormat(targetExt) {
-		return c.convertEbook(ctx, inputPath, outputPath, originalExt, targetExt)
+func (c *DefaultConverter) convertInputs(ctx context.Context, inputPaths []string, outputPath string, originalExt, targetExt string, options map[string]interface{}) error {
+	backend, ok := c.registry.LookupFor(originalExt, targetExt, options)
+	if !ok {
+		return fmt.Errorf("конвертация из %s в %s не поддерживается", originalExt, targetExt)
 	}
-
-	if c.isEbookFormat(originalExt) && targetExt == "pdf" {
-		return c.convertEbook(ctx, inputPath, outputPath, originalExt, targetExt)
+	multi, ok := backend.(MultiInputBackend)
+	if !ok {
+		return fmt.Errorf("конвертер %s не принимает дополнительные файлы", backend.Name())
 	}
-
-	if c.isDocumentFormat(originalExt) && c.isDocumentFormat(targetExt) {
-		return c.convertDocument(ctx, inputPath, outputPath, originalExt, targetExt)
+	if c.caps != nil && !c.caps.Has(backend.Name()) {
+		return fmt.Errorf("обработка %s недоступна на этом сервере", originalExt)
 	}
-
-	return fmt.Errorf("конвертация из %s в %s не поддерживается", originalExt, targetExt)
+	if err := checkRequirements(backend); err != nil {
+		return err
+	}
+	return multi.ConvertInputs(ctx, inputPaths, outputPath, options)
 }
 
-func (c *DefaultConverter) copyFile(src, dst string) error {
+func copyFile(src, dst string) error {
 	if filepath.Clean(src) == filepath.Clean(dst) {
 		return nil
 	}
@@ -181,60 +243,6 @@ func (c *DefaultConverter) copyFile(src, dst string) error {
 	return err
 }
 
-func (c *DefaultConverter) convertImage(ctx context.Context, inputPath, outputPath string, originalExt, targetExt string, options map[string]interface{}) error {
-	_ = originalExt
-
-	if !c.hasCommand("magick") && !c.hasCommand("convert") {
-		return fmt.Errorf("ImageMagick не установлен")
-	}
-
-	if !c.isImageFormat(targetExt) {
-		return fmt.Errorf("целевой формат %s не является форматом изображения", targetExt)
-	}
-
-	cmdName := "magick"
-	if !c.hasCommand("magick") {
-		cmdName = "convert"
-	}
-
-	args := []string{inputPath}
-	if max, ok := optInt(options, "img_max"); ok && max > 0 {
-		args = append(args, "-resize", fmt.Sprintf("%dx%d>", max, max))
-	}
-	if w, ok := optInt(options, "img_w"); ok && w > 0 {
-		if h, ok := optInt(options, "img_h"); ok && h > 0 {
-			bg := "white"
-			if bgs, ok := optString(options, "img_bg"); ok {
-				bg = bgs
-			}
-			args = append(args,
-				"-resize", fmt.Sprintf("%dx%d", w, h),
-				"-background", bg,
-				"-gravity", "center",
-				"-extent", fmt.Sprintf("%dx%d", w, h),
-			)
-		}
-	}
-	if q, ok := optInt(options, "img_quality"); ok && q > 0 {
-		if q > 95 {
-			q = 95
-		}
-		if q < 10 {
-			q = 10
-		}
-		args = append(args, "-quality", fmt.Sprintf("%d", q))
-	}
-	args = append(args, outputPath)
-
-	cmd := exec.CommandContext(ctx, cmdName, args...)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("ошибка ImageMagick: %v, вывод: %s", err, string(output))
-	}
-
-	return nil
-}
-
 func hasImageOptions(options map[string]interface{}) bool {
 	if options == nil {
 		return false
@@ -307,104 +315,12 @@ func optInt(m map[string]interface{}, key string) (int, bool) {
 	}
 }
 
-func (c *DefaultConverter) convertAudio(ctx context.Context, inputPath, outputPath string, originalExt, targetExt string) error {
-	_ = originalExt
-	if !c.hasCommand("ffmpeg") {
-		return fmt.Errorf("ffmpeg не установлен")
-	}
-
-	cmd := exec.CommandContext(ctx, "ffmpeg", "-i", inputPath, "-y", outputPath)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("ошибка ffmpeg: %v, вывод: %s", err, string(output))
-	}
-
-	return nil
-}
-
-func (c *DefaultConverter) convertVideo(ctx context.Context, inputPath, outputPath string, originalExt, targetExt string, options map[string]interface{}) error {
-	_ = originalExt
-	if !c.hasCommand("ffmpeg") {
-		return fmt.Errorf("ffmpeg не установлен")
-	}
-
-	args := []string{"-i", inputPath}
-	vf := ""
-	if w, ok := optInt(options, "vid_w"); ok && w > 0 {
-		if h, ok := optInt(options, "vid_h"); ok && h > 0 {
-			vf = fmt.Sprintf("scale=%d:%d:force_original_aspect_ratio=decrease,pad=%d:%d:(ow-iw)/2:(oh-ih)/2,setsar=1", w, h, w, h)
-		}
-	}
-	if h, ok := optInt(options, "vid_height"); ok && h > 0 {
-		if vf == "" {
-			vf = fmt.Sprintf("scale=-2:%d", h)
-		}
-	}
-	if crf, ok := optInt(options, "vid_crf"); ok && crf > 0 {
-		if crf < 18 {
-			crf = 18
-		}
-		if crf > 40 {
-			crf = 40
-		}
-		if vf != "" {
-			args = append(args, "-vf", vf)
-		}
-		args = append(args, "-c:v", "libx264", "-preset", "veryfast", "-crf", fmt.Sprintf("%d", crf), "-c:a", "aac", "-b:a", "128k")
-	} else if vf != "" {
-		args = append(args, "-vf", vf, "-c:v", "libx264", "-preset", "veryfast", "-crf", "23", "-c:a", "aac", "-b:a", "128k")
-	}
-	args = append(args, "-y", outputPath)
-
-	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("ошибка ffmpeg: %v, вывод: %s", err, string(output))
-	}
-
-	return nil
-}
-
-func (c *DefaultConverter) convertVideoToAudio(ctx context.Context, inputPath, outputPath string) error {
-	if !c.hasCommand("ffmpeg") {
-		return fmt.Errorf("ffmpeg не установлен")
-	}
-
-	cmd := exec.CommandContext(ctx, "ffmpeg", "-i", inputPath, "-vn", "-y", outputPath)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("ошибка ffmpeg (video->audio): %v, вывод: %s", err, string(output))
-	}
-	return nil
-}
-
-func (c *DefaultConverter) convertVideoToGif(ctx context.Context, inputPath, outputPath string, options map[string]interface{}) error {
-	if !c.hasCommand("ffmpeg") {
-		return fmt.Errorf("ffmpeg не установлен")
-	}
-
-	height := 480
-	if hasVideoOptions(options) {
-		op, _ := optString(options, "vid_op")
-		if op == "gif" {
-			if h, ok := optInt(options, "vid_gif_height"); ok && h > 0 {
-				height = h
-			}
-		}
-	}
-	if height < 120 {
-		height = 120
-	}
-	if height > 1080 {
-		height = 1080
-	}
-	filter := fmt.Sprintf("fps=12,scale=-2:%d:flags=lanczos,split[s0][s1];[s0]palettegen[p];[s1][p]paletteuse", height)
-	cmd := exec.CommandContext(ctx, "ffmpeg", "-i", inputPath, "-vf", filter, "-loop", "0", "-y", outputPath)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("ошибка ffmpeg (video->gif): %v, вывод: %s", err, string(output))
+func hasAudioOptions(options map[string]interface{}) bool {
+	if options == nil {
+		return false
 	}
-	return nil
+	_, ok := options["aud_op"]
+	return ok
 }
 
 func hasVideoOptions(options map[string]interface{}) bool {
@@ -432,140 +348,68 @@ func hasVideoOptions(options map[string]interface{}) bool {
 	return false
 }
 
-func (c *DefaultConverter) convertDocument(ctx context.Context, inputPath, outputPath string, originalExt, targetExt string) error {
-	if (c.isOfficeFormat(originalExt) || originalExt == "odt" || originalExt == "rtf" || originalExt == "txt") &&
-		(c.isOfficeFormat(targetExt) || targetExt == "pdf" || targetExt == "rtf" || targetExt == "txt") {
-		if c.hasCommand("libreoffice") || c.hasCommand("soffice") {
-			return c.convertWithLibreOffice(ctx, inputPath, outputPath, targetExt)
-		}
-		return fmt.Errorf("LibreOffice не установлен")
-	}
-
-	if c.isPdfFormat(originalExt) && targetExt == "txt" {
-		if c.hasCommand("pdftotext") {
-			return c.convertPdfToOffice(ctx, inputPath, outputPath, targetExt)
-		}
-		return fmt.Errorf("poppler-utils не установлен")
-	}
-
-	if c.isPdfFormat(originalExt) && c.isOfficeFormat(targetExt) {
-		return fmt.Errorf("конвертация PDF в Office форматы не поддерживается напрямую")
+func hasPdfOptions(options map[string]interface{}) bool {
+	if options == nil {
+		return false
 	}
-
-	return fmt.Errorf("конвертация документов из %s в %s не поддерживается", originalExt, targetExt)
+	_, ok := options["pdf_op"]
+	return ok
 }
 
-func (c *DefaultConverter) libreOfficeConvertToArg(targetExt string) (convertTo string, expectedExt string, err error) {
-	targetExt = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(targetExt), "."))
-	if targetExt == "" {
-		return "", "", fmt.Errorf("пустой целевой формат для LibreOffice")
-	}
-
-	switch targetExt {
-	case "txt":
-		return "txt:Text", "txt", nil
-	default:
-		return targetExt, targetExt, nil
-	}
+func hasCommand(cmd string) bool {
+	_, err := exec.LookPath(cmd)
+	return err == nil
 }
 
-func (c *DefaultConverter) convertWithLibreOffice(ctx context.Context, inputPath, outputPath string, targetExt string) error {
-	cmdName := "libreoffice"
-	if !c.hasCommand("libreoffice") {
-		cmdName = "soffice"
-	}
-
-	outputDir := filepath.Dir(outputPath)
-	convertTo, expectedExt, err := c.libreOfficeConvertToArg(targetExt)
-	if err != nil {
-		return err
-	}
-
-	cmd := exec.CommandContext(ctx, cmdName, "--headless", "--convert-to", convertTo, "--outdir", outputDir, inputPath)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("ошибка LibreOffice: %v, вывод: %s", err, string(output))
-	}
-
-	baseName := strings.TrimSuffix(filepath.Base(inputPath), filepath.Ext(inputPath))
-	generated := filepath.Join(outputDir, baseName+"."+expectedExt)
-	if _, err := os.Stat(generated); os.IsNotExist(err) {
-		generatedAlt := filepath.Join(outputDir, baseName+"."+strings.ToUpper(expectedExt))
-		if _, err2 := os.Stat(generatedAlt); err2 == nil {
-			generated = generatedAlt
-		} else {
-			return fmt.Errorf("LibreOffice не создал файл: %s\nвывод: %s", generated, string(output))
-		}
-	}
+func imageFormats() []string {
+	return []string{"png", "jpg", "jpeg", "jp2", "webp", "bmp", "tif", "tiff", "gif", "ico", "heic", "avif", "tgs", "psd", "svg", "apng", "eps"}
+}
 
-	if filepath.Clean(generated) == filepath.Clean(outputPath) {
-		return nil
-	}
-	return c.copyFile(generated, outputPath)
+func audioFormats() []string {
+	return []string{"mp3", "ogg", "opus", "wav", "flac", "wma", "oga", "m4a", "aac", "aiff", "amr"}
 }
 
-func (c *DefaultConverter) convertEbook(ctx context.Context, inputPath, outputPath string, originalExt, targetExt string) error {
-	_ = originalExt
-	_ = targetExt
-	cmd := exec.CommandContext(ctx, "ebook-convert", inputPath, outputPath)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("ошибка Calibre: %v, вывод: %s", err, string(output))
-	}
-	return nil
+func videoFormats() []string {
+	return []string{"mp4", "avi", "wmv", "mkv", "3gp", "3gpp", "mpg", "mpeg", "webm", "ts", "mov", "flv", "asf", "vob"}
 }
 
-func (c *DefaultConverter) convertPdfToOffice(ctx context.Context, inputPath, outputPath string, targetExt string) error {
-	if targetExt == "txt" {
-		cmd := exec.CommandContext(ctx, "pdftotext", inputPath, outputPath)
-		output, err := cmd.CombinedOutput()
-		if err != nil {
-			return fmt.Errorf("ошибка pdftotext: %v, вывод: %s", err, string(output))
-		}
-		return nil
-	}
-	return fmt.Errorf("конвертация PDF в %s не поддерживается", targetExt)
+func writerFormats() []string {
+	return []string{"doc", "docx", "odt", "rtf", "txt"}
 }
 
-func (c *DefaultConverter) hasCommand(cmd string) bool {
-	_, err := exec.LookPath(cmd)
-	return err == nil
+func sheetFormats() []string {
+	return []string{"xls", "xlsx", "ods"}
 }
 
-func (c *DefaultConverter) isImageFormat(ext string) bool {
-	imageFormats := []string{"png", "jpg", "jpeg", "jp2", "webp", "bmp", "tif", "tiff", "gif", "ico", "heic", "avif", "tgs", "psd", "svg", "apng", "eps"}
-	return c.contains(imageFormats, ext)
+func slideFormats() []string {
+	return []string{"ppt", "pptx", "pptm", "pps", "ppsx", "ppsm", "pot", "potx", "potm", "odp"}
 }
 
-func (c *DefaultConverter) isAudioFormat(ext string) bool {
-	audioFormats := []string{"mp3", "ogg", "opus", "wav", "flac", "wma", "oga", "m4a", "aac", "aiff", "amr"}
-	return c.contains(audioFormats, ext)
+func ebookFormats() []string {
+	return []string{"epub", "mobi", "azw3", "lrf", "pdb", "cbr", "fb2", "cbz", "djvu"}
 }
 
-func (c *DefaultConverter) isVideoFormat(ext string) bool {
-	videoFormats := []string{"mp4", "avi", "wmv", "mkv", "3gp", "3gpp", "mpg", "mpeg", "webm", "ts", "mov", "flv", "asf", "vob"}
-	return c.contains(videoFormats, ext)
+func subtitleFormats() []string {
+	return subtitles.Formats()
 }
 
-func (c *DefaultConverter) isDocumentFormat(ext string) bool {
-	return c.isOfficeFormat(ext) || c.isPdfFormat(ext) || ext == "txt" || ext == "rtf" || ext == "torrent"
+func isSubtitleFormat(ext string) bool {
+	return subtitles.IsFormat(ext)
 }
 
-func (c *DefaultConverter) isOfficeFormat(ext string) bool {
-	officeFormats := []string{"xlsx", "xls", "doc", "docx", "odt", "ods", "ppt", "pptx", "pptm", "pps", "ppsx", "ppsm", "pot", "potx", "potm", "odp"}
-	return c.contains(officeFormats, ext)
+func isImageFormat(ext string) bool {
+	return contains(imageFormats(), ext)
 }
 
-func (c *DefaultConverter) isPdfFormat(ext string) bool {
-	return ext == "pdf"
+func isAudioFormat(ext string) bool {
+	return contains(audioFormats(), ext)
 }
 
-func (c *DefaultConverter) isEbookFormat(ext string) bool {
-	ebookFormats := []string{"epub", "mobi", "azw3", "lrf", "pdb", "cbr", "fb2", "cbz", "djvu"}
-	return c.contains(ebookFormats, ext)
+func isVideoFormat(ext string) bool {
+	return contains(videoFormats(), ext)
 }
 
-func (c *DefaultConverter) contains(slice []string, item string) bool {
+func contains(slice []string, item string) bool {
 	for _, s := range slice {
 		if strings.EqualFold(s, item) {
 			return true
@@ -0,0 +1,69 @@
+package converter
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+type MediaInfo struct {
+	Duration  int
+	Width     int
+	Height    int
+	Title     string
+	Performer string
+	Codec     string
+	Channels  int
+}
+
+func ProbeMedia(ctx context.Context, path string) MediaInfo {
+	var info MediaInfo
+	if !hasCommand("ffprobe") {
+		return info
+	}
+	output, err := commandContext(ctx, "ffprobe", "-v", "error",
+		"-show_entries", "format=duration:format_tags=title,artist:stream=width,height,codec_type,codec_name,channels",
+		"-of", "json", path).Output()
+	if err != nil {
+		return info
+	}
+
+	var probe struct {
+		Streams []struct {
+			Width     int    `json:"width"`
+			Height    int    `json:"height"`
+			CodecType string `json:"codec_type"`
+			CodecName string `json:"codec_name"`
+			Channels  int    `json:"channels"`
+		} `json:"streams"`
+		Format struct {
+			Duration string            `json:"duration"`
+			Tags     map[string]string `json:"tags"`
+		} `json:"format"`
+	}
+	if err := json.Unmarshal(output, &probe); err != nil {
+		return info
+	}
+
+	if seconds, err := strconv.ParseFloat(strings.TrimSpace(probe.Format.Duration), 64); err == nil && seconds > 0 {
+		info.Duration = int(seconds + 0.5)
+	}
+	for _, s := range probe.Streams {
+		if s.Width > 0 && s.Height > 0 && info.Width == 0 {
+			info.Width, info.Height = s.Width, s.Height
+		}
+		if s.CodecType == "audio" && info.Codec == "" {
+			info.Codec, info.Channels = s.CodecName, s.Channels
+		}
+	}
+	for k, v := range probe.Format.Tags {
+		switch strings.ToLower(k) {
+		case "title":
+			info.Title = strings.TrimSpace(v)
+		case "artist":
+			info.Performer = strings.TrimSpace(v)
+		}
+	}
+	return info
+}
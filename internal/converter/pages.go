@@ -0,0 +1,94 @@
+package converter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type PageRange struct {
+	From int
+	To   int
+}
+
+func ParsePageRanges(s string) ([]PageRange, error) {
+	s = strings.NewReplacer(" ", "", "–", "-", "—", "-", ";", ",").Replace(strings.TrimSpace(s))
+	if s == "" {
+		return nil, fmt.Errorf("пустой список страниц")
+	}
+
+	ranges := make([]PageRange, 0)
+	for _, part := range strings.Split(s, ",") {
+		if part == "" {
+			continue
+		}
+		from, to, isRange := strings.Cut(part, "-")
+		start, err := strconv.Atoi(from)
+		if err != nil || start <= 0 {
+			return nil, fmt.Errorf("неверный номер страницы: %q", part)
+		}
+		if !isRange {
+			ranges = append(ranges, PageRange{From: start, To: start})
+			continue
+		}
+		if to == "" {
+			ranges = append(ranges, PageRange{From: start})
+			continue
+		}
+		end, err := strconv.Atoi(to)
+		if err != nil || end <= 0 {
+			return nil, fmt.Errorf("неверный диапазон страниц: %q", part)
+		}
+		ranges = append(ranges, PageRange{From: start, To: end})
+	}
+	if len(ranges) == 0 {
+		return nil, fmt.Errorf("пустой список страниц")
+	}
+	return ranges, nil
+}
+
+func (r PageRange) String() string {
+	switch {
+	case r.To == 0:
+		return fmt.Sprintf("%d-z", r.From)
+	case r.From == r.To:
+		return strconv.Itoa(r.From)
+	default:
+		return fmt.Sprintf("%d-%d", r.From, r.To)
+	}
+}
+
+func (r PageRange) pages(total int) []int {
+	to := r.To
+	if to == 0 {
+		to = total
+	}
+	out := make([]int, 0)
+	if r.From <= to {
+		for p := r.From; p <= to; p++ {
+			out = append(out, p)
+		}
+	} else {
+		for p := r.From; p >= to; p-- {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+func checkPageRanges(ranges []PageRange, total int) error {
+	for _, r := range ranges {
+		if r.From > total || r.To > total {
+			return fmt.Errorf("в документе %d стр., диапазон %s выходит за его пределы", total, r.String())
+		}
+	}
+	return nil
+}
+
+func formatPageRanges(ranges []PageRange, sep string) string {
+	parts := make([]string, 0, len(ranges))
+	for _, r := range ranges {
+		parts = append(parts, r.String())
+	}
+	return strings.Join(parts, sep)
+}
@@ -0,0 +1,86 @@
+package converter
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePageRanges(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []PageRange
+		str  string
+	}{
+		{"5", []PageRange{{From: 5, To: 5}}, "5"},
+		{"1-3", []PageRange{{From: 1, To: 3}}, "1-3"},
+		{"1-3,5,7-", []PageRange{{From: 1, To: 3}, {From: 5, To: 5}, {From: 7}}, "1-3,5,7-z"},
+		{" 2 – 4 ; 6 ", []PageRange{{From: 2, To: 4}, {From: 6, To: 6}}, "2-4,6"},
+		{"9—7", []PageRange{{From: 9, To: 7}}, "9-7"},
+		{"1,,2,", []PageRange{{From: 1, To: 1}, {From: 2, To: 2}}, "1,2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParsePageRanges(tt.in)
+			if err != nil {
+				t.Fatalf("ParsePageRanges: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+			if s := formatPageRanges(got, ","); s != tt.str {
+				t.Errorf("formatPageRanges = %q, want %q", s, tt.str)
+			}
+			again, err := ParsePageRanges(formatPageRanges(got, ","))
+			if tt.str[len(tt.str)-1] != 'z' && (err != nil || !reflect.DeepEqual(again, got)) {
+				t.Errorf("round trip: got %#v, %v", again, err)
+			}
+		})
+	}
+}
+
+func TestParsePageRangesRejectsMalformedInput(t *testing.T) {
+	for _, in := range []string{"", "   ", ",", "0", "-3", "a", "1-b", "1-0", "2-3-4", "1.5"} {
+		t.Run(in, func(t *testing.T) {
+			if got, err := ParsePageRanges(in); err == nil {
+				t.Errorf("expected an error, got %#v", got)
+			}
+		})
+	}
+}
+
+func TestPageRangePages(t *testing.T) {
+	tests := []struct {
+		r     PageRange
+		total int
+		want  []int
+	}{
+		{PageRange{From: 2, To: 2}, 5, []int{2}},
+		{PageRange{From: 1, To: 3}, 5, []int{1, 2, 3}},
+		{PageRange{From: 3}, 5, []int{3, 4, 5}},
+		{PageRange{From: 4, To: 2}, 5, []int{4, 3, 2}},
+	}
+	for _, tt := range tests {
+		if got := tt.r.pages(tt.total); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s.pages(%d) = %v, want %v", tt.r, tt.total, got, tt.want)
+		}
+	}
+}
+
+func TestCheckPageRanges(t *testing.T) {
+	tests := []struct {
+		ranges []PageRange
+		total  int
+		ok     bool
+	}{
+		{[]PageRange{{From: 1, To: 5}}, 5, true},
+		{[]PageRange{{From: 5}}, 5, true},
+		{[]PageRange{{From: 1, To: 6}}, 5, false},
+		{[]PageRange{{From: 6}}, 5, false},
+		{[]PageRange{{From: 2, To: 2}, {From: 9, To: 1}}, 5, false},
+	}
+	for _, tt := range tests {
+		if err := checkPageRanges(tt.ranges, tt.total); (err == nil) != tt.ok {
+			t.Errorf("checkPageRanges(%v, %d) error = %v, want ok=%v", tt.ranges, tt.total, err, tt.ok)
+		}
+	}
+}
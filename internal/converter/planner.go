@@ -0,0 +1,141 @@
+package converter
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BatmanBruc/bat-bot-convetor/internal/capability"
+)
+
+type Step struct {
+	From    string
+	To      string
+	Backend string
+}
+
+type Coster interface {
+	Cost() int
+}
+
+func pivotFormats() []string {
+	return []string{"pdf", "epub", "docx", "odt", "ttf", "srt"}
+}
+
+func backendCost(b Backend) int {
+	if c, ok := b.(Coster); ok && c.Cost() > 0 {
+		return c.Cost()
+	}
+	return 1
+}
+
+func (c *DefaultConverter) graph() *capability.Matrix {
+	if c.caps != nil {
+		return c.caps
+	}
+	m := capability.NewMatrix()
+	for _, b := range c.registry.Backends() {
+		addBackendEdges(m, b, b.Pairs())
+	}
+	addPivots(m)
+	return m
+}
+
+func addBackendEdges(m *capability.Matrix, b Backend, pairs []Pair) {
+	cost := backendCost(b)
+	_, gated := b.(OptionMatcher)
+	for _, pair := range pairs {
+		pair = normalizePair(pair)
+		m.Add(capability.Edge{From: pair.From, To: pair.To, Backend: b.Name(), Cost: cost, Gated: gated})
+	}
+}
+
+func addPivots(m *capability.Matrix) {
+	for _, ext := range pivotFormats() {
+		m.AddPivot(ext)
+	}
+}
+
+func (c *DefaultConverter) Plan(from, to string) ([]Step, error) {
+	from = normalizeExt(from)
+	to = normalizeExt(to)
+
+	if from == to {
+		backend, ok := c.registry.Lookup(from, to)
+		if !ok {
+			return nil, fmt.Errorf("конвертация из %s в %s не поддерживается", from, to)
+		}
+		if c.caps != nil && !c.caps.Supports(from, to) {
+			return nil, fmt.Errorf("конвертация из %s в %s недоступна на этом сервере", from, to)
+		}
+		return []Step{{From: from, To: to, Backend: backend.Name()}}, nil
+	}
+
+	edges, ok := c.graph().Path(from, to)
+	if !ok {
+		if c.caps != nil {
+			if _, known := c.registry.Lookup(from, to); known {
+				return nil, fmt.Errorf("конвертация из %s в %s недоступна на этом сервере", from, to)
+			}
+		}
+		return nil, fmt.Errorf("конвертация из %s в %s не поддерживается", from, to)
+	}
+
+	steps := make([]Step, 0, len(edges))
+	for _, e := range edges {
+		steps = append(steps, Step{From: e.From, To: e.To, Backend: e.Backend})
+	}
+	return steps, nil
+}
+
+func ChainString(steps []Step) string {
+	if len(steps) == 0 {
+		return ""
+	}
+	parts := []string{strings.ToUpper(steps[0].From)}
+	for _, st := range steps {
+		parts = append(parts, strings.ToUpper(st.To))
+	}
+	return strings.Join(parts, " → ")
+}
+
+func (c *DefaultConverter) runPlan(ctx context.Context, steps []Step, inputPath, outputPath string, options map[string]interface{}) error {
+	if len(steps) == 1 {
+		return c.runStep(ctx, steps[0], inputPath, outputPath, options)
+	}
+
+	workDir, err := os.MkdirTemp(c.tempDir, "chain_*")
+	if err != nil {
+		return fmt.Errorf("не удалось создать рабочую папку: %v", err)
+	}
+	defer func() { _ = os.RemoveAll(workDir) }()
+
+	current := inputPath
+	for i, st := range steps {
+		out := outputPath
+		if i < len(steps)-1 {
+			out = filepath.Join(workDir, fmt.Sprintf("step%d.%s", i+1, st.To))
+		}
+		n := float64(len(steps))
+		stepCtx := withProgressRange(ctx, float64(i)/n, float64(i+1)/n)
+		if err := c.runStep(stepCtx, st, current, out, options); err != nil {
+			return fmt.Errorf("шаг %s → %s: %v", strings.ToUpper(st.From), strings.ToUpper(st.To), err)
+		}
+		reportProgress(ctx, float64(i+1)/n)
+		current = out
+	}
+	return nil
+}
+
+func (c *DefaultConverter) runStep(ctx context.Context, st Step, inputPath, outputPath string, options map[string]interface{}) error {
+	backend, ok := c.registry.Get(st.Backend)
+	if !ok {
+		return fmt.Errorf("конвертер %s не найден", st.Backend)
+	}
+	if err := checkRequirements(backend); err != nil {
+		return err
+	}
+	return backend.Convert(ctx, inputPath, outputPath, options)
+}
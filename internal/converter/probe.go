@@ -0,0 +1,217 @@
+package converter
+
+import (
+	"bufio"
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/BatmanBruc/bat-bot-convetor/internal/capability"
+)
+
+type Prober interface {
+	Probe(ctx context.Context) ([]Pair, error)
+}
+
+func (c *DefaultConverter) Probe(ctx context.Context) *capability.Matrix {
+	m := capability.NewMatrix()
+	for _, b := range c.registry.Backends() {
+		if err := checkRequirements(b); err != nil {
+			log.Printf("Capability probe: backend %s disabled: %v", b.Name(), err)
+			continue
+		}
+
+		pairs := b.Pairs()
+		if p, ok := b.(Prober); ok {
+			probeCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+			probed, err := p.Probe(probeCtx)
+			cancel()
+			if err != nil {
+				log.Printf("Capability probe: backend %s probe failed, assuming all pairs: %v", b.Name(), err)
+			} else {
+				pairs = probed
+			}
+		}
+
+		addBackendEdges(m, b, pairs)
+		m.AddFeature(b.Name())
+	}
+	addPivots(m)
+
+	c.caps = m
+	return m
+}
+
+func (c *DefaultConverter) Capabilities() *capability.Matrix {
+	return c.caps
+}
+
+func filterPairs(pairs []Pair, keep func(p Pair) bool) []Pair {
+	out := make([]Pair, 0, len(pairs))
+	for _, p := range pairs {
+		if keep(normalizePair(p)) {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+func commandOutputLines(ctx context.Context, name string, args ...string) ([]string, error) {
+	out, err := commandContext(ctx, name, args...).Output()
+	if err != nil {
+		return nil, err
+	}
+	lines := make([]string, 0)
+	sc := bufio.NewScanner(strings.NewReader(string(out)))
+	for sc.Scan() {
+		lines = append(lines, sc.Text())
+	}
+	return lines, sc.Err()
+}
+
+func (b ffmpegBackend) Probe(ctx context.Context) ([]Pair, error) {
+	lines, err := commandOutputLines(ctx, "ffmpeg", "-hide_banner", "-encoders")
+	if err != nil {
+		return nil, err
+	}
+	encoders := map[string]struct{}{}
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || len(fields[0]) != 6 {
+			continue
+		}
+		encoders[fields[1]] = struct{}{}
+	}
+
+	return filterPairs(b.Pairs(), func(p Pair) bool {
+		needed, ok := ffmpegTargetEncoders[p.To]
+		if !ok {
+			return true
+		}
+		for _, enc := range needed {
+			if _, ok := encoders[enc]; ok {
+				return true
+			}
+		}
+		return false
+	}), nil
+}
+
+var ffmpegTargetEncoders = map[string][]string{
+	"mp3":  {"libmp3lame"},
+	"ogg":  {"libvorbis", "libopus", "flac"},
+	"oga":  {"libvorbis", "libopus", "flac"},
+	"opus": {"libopus", "opus"},
+	"wav":  {"pcm_s16le"},
+	"flac": {"flac"},
+	"wma":  {"wmav2", "wmav1"},
+	"m4a":  {"aac", "libfdk_aac"},
+	"aac":  {"aac", "libfdk_aac"},
+	"aiff": {"pcm_s16be"},
+	"amr":  {"libopencore_amrnb"},
+
+	"mp4":  {"libx264", "mpeg4"},
+	"mov":  {"libx264", "mpeg4"},
+	"mkv":  {"libx264", "mpeg4"},
+	"3gp":  {"libx264", "h263", "mpeg4"},
+	"3gpp": {"libx264", "h263", "mpeg4"},
+	"avi":  {"mpeg4", "libxvid"},
+	"webm": {"libvpx-vp9", "libvpx", "libaom-av1"},
+	"wmv":  {"wmv2", "wmv1"},
+	"asf":  {"wmv2", "wmv1"},
+	"flv":  {"flv"},
+	"mpg":  {"mpeg1video", "mpeg2video"},
+	"mpeg": {"mpeg1video", "mpeg2video"},
+	"vob":  {"mpeg2video"},
+	"ts":   {"libx264", "mpeg2video"},
+	"gif":  {"gif"},
+
+	"srt": {"srt", "subrip"},
+	"vtt": {"webvtt"},
+	"ass": {"ass", "ssa"},
+}
+
+func (b imageMagickBackend) Probe(ctx context.Context) ([]Pair, error) {
+	cmdName, _ := requirementMet(b.Requirements()[0])
+	lines, err := commandOutputLines(ctx, cmdName, "-list", "format")
+	if err != nil {
+		return nil, err
+	}
+	modes := map[string]string{}
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			continue
+		}
+		mode := fields[2]
+		if len(mode) != 3 || (mode[0] != 'r' && mode[0] != '-') || (mode[1] != 'w' && mode[1] != '-') {
+			continue
+		}
+		name := strings.ToLower(strings.TrimRight(fields[0], "*"))
+		modes[name] = mode
+	}
+
+	canRead := func(ext string) bool {
+		for _, name := range imageMagickNames(ext) {
+			if m, ok := modes[name]; ok && m[0] == 'r' {
+				return true
+			}
+		}
+		return false
+	}
+	canWrite := func(ext string) bool {
+		for _, name := range imageMagickNames(ext) {
+			if m, ok := modes[name]; ok && m[1] == 'w' {
+				return true
+			}
+		}
+		return false
+	}
+
+	return filterPairs(b.Pairs(), func(p Pair) bool {
+		return canRead(p.From) && canWrite(p.To)
+	}), nil
+}
+
+func imageMagickNames(ext string) []string {
+	switch ext {
+	case "jpg", "jpeg":
+		return []string{"jpeg", "jpg"}
+	case "tif", "tiff":
+		return []string{"tiff", "tif"}
+	case "svg":
+		return []string{"svg", "msvg", "rsvg"}
+	default:
+		return []string{ext}
+	}
+}
+
+func (b calibreBackend) Probe(ctx context.Context) ([]Pair, error) {
+	if _, err := commandOutputLines(ctx, "ebook-convert", "--version"); err != nil {
+		return nil, err
+	}
+	writable := calibreOutputFormats()
+	return filterPairs(b.Pairs(), func(p Pair) bool {
+		return contains(writable, p.To)
+	}), nil
+}
+
+func (b libreOfficeBackend) Probe(ctx context.Context) ([]Pair, error) {
+	cmdName, _ := requirementMet(b.Requirements()[0])
+	if _, err := commandOutputLines(ctx, cmdName, "--headless", "--version"); err != nil {
+		return nil, err
+	}
+	writable := libreOfficeOutputFormats()
+	return filterPairs(b.Pairs(), func(p Pair) bool {
+		return contains(writable, p.To)
+	}), nil
+}
+
+func libreOfficeOutputFormats() []string {
+	return []string{
+		"doc", "docx", "odt", "rtf", "txt", "pdf",
+		"xls", "xlsx", "ods",
+		"ppt", "pptx", "pps", "ppsx", "pot", "potx", "odp",
+	}
+}
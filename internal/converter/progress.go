@@ -0,0 +1,110 @@
+package converter
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"math"
+	"os/exec"
+	"time"
+)
+
+const progressTick = 2 * time.Second
+
+type ProgressFunc func(fraction float64)
+
+type progressKey struct{}
+
+func WithProgress(ctx context.Context, fn ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+func progressFrom(ctx context.Context) ProgressFunc {
+	fn, _ := ctx.Value(progressKey{}).(ProgressFunc)
+	return fn
+}
+
+func hasProgress(ctx context.Context) bool {
+	return progressFrom(ctx) != nil
+}
+
+func reportProgress(ctx context.Context, fraction float64) {
+	fn := progressFrom(ctx)
+	if fn == nil {
+		return
+	}
+	if fraction < 0 {
+		fraction = 0
+	}
+	if fraction > 1 {
+		fraction = 1
+	}
+	fn(fraction)
+}
+
+func withProgressRange(ctx context.Context, from, to float64) context.Context {
+	fn := progressFrom(ctx)
+	if fn == nil {
+		return ctx
+	}
+	return WithProgress(ctx, func(fraction float64) {
+		fn(from + (to-from)*fraction)
+	})
+}
+
+func runWithProgress(cmd *exec.Cmd, parse func(line string) bool) ([]byte, error) {
+	r, w := io.Pipe()
+	cmd.Stdout = w
+	cmd.Stderr = w
+	if err := cmd.Start(); err != nil {
+		_ = w.Close()
+		return nil, err
+	}
+
+	var output bytes.Buffer
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		sc := bufio.NewScanner(r)
+		sc.Buffer(make([]byte, 64*1024), 1024*1024)
+		for sc.Scan() {
+			line := sc.Text()
+			if parse(line) {
+				continue
+			}
+			output.WriteString(line)
+			output.WriteByte('\n')
+		}
+		_, _ = io.Copy(io.Discard, r)
+	}()
+
+	err := cmd.Wait()
+	_ = w.Close()
+	<-done
+	return output.Bytes(), err
+}
+
+func tickProgress(ctx context.Context, estimate time.Duration) func() {
+	if !hasProgress(ctx) || estimate <= 0 {
+		return func() {}
+	}
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(progressTick)
+		defer ticker.Stop()
+		start := time.Now()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				elapsed := float64(time.Since(start)) / float64(estimate)
+				reportProgress(ctx, 0.95*(1-math.Exp(-elapsed)))
+			}
+		}
+	}()
+	return func() { close(done) }
+}
@@ -0,0 +1,143 @@
+package fonts
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"unicode/utf16"
+)
+
+const (
+	eotVersion        = 0x00010000
+	eotMagic          = 0x504C
+	eotFlagCompressed = 0x00000004
+	eotFlagXOR        = 0x10000000
+)
+
+func EncodeEOT(sfnt []byte) ([]byte, error) {
+	f, err := ParseSfnt(sfnt)
+	if err != nil {
+		return nil, err
+	}
+	if f.IsCFF() {
+		return nil, fmt.Errorf("EOT поддерживает только шрифты с контурами TrueType")
+	}
+
+	os2 := f.Table("OS/2")
+	head := f.Table("head")
+	if len(os2) < 78 || len(head) < 12 {
+		return nil, fmt.Errorf("в шрифте нет таблиц OS/2 или head")
+	}
+
+	var buf bytes.Buffer
+	le := binary.LittleEndian
+	write := func(v interface{}) { _ = binary.Write(&buf, le, v) }
+
+	write(uint32(0))
+	write(uint32(len(sfnt)))
+	write(uint32(eotVersion))
+	write(uint32(0))
+	buf.Write(os2[32:42])
+	write(uint8(1))
+	write(uint8(binary.BigEndian.Uint16(os2[62:64]) & 1))
+	write(uint32(binary.BigEndian.Uint16(os2[4:6])))
+	write(binary.BigEndian.Uint16(os2[8:10]))
+	write(uint16(eotMagic))
+	for i := 0; i < 4; i++ {
+		write(binary.BigEndian.Uint32(os2[42+i*4 : 46+i*4]))
+	}
+	if len(os2) >= 86 {
+		write(binary.BigEndian.Uint32(os2[78:82]))
+		write(binary.BigEndian.Uint32(os2[82:86]))
+	} else {
+		write(uint32(0))
+		write(uint32(0))
+	}
+	write(binary.BigEndian.Uint32(head[8:12]))
+	for i := 0; i < 4; i++ {
+		write(uint32(0))
+	}
+
+	for _, nameID := range []uint16{1, 2, 5, 4} {
+		name := utf16le(fontName(f.Table("name"), nameID))
+		write(uint16(0))
+		write(uint16(len(name)))
+		buf.Write(name)
+	}
+	buf.Write(sfnt)
+
+	out := buf.Bytes()
+	le.PutUint32(out[0:4], uint32(len(out)))
+	return out, nil
+}
+
+func DecodeEOT(data []byte) ([]byte, error) {
+	if len(data) < 82 {
+		return nil, fmt.Errorf("файл не является шрифтом EOT")
+	}
+	le := binary.LittleEndian
+	eotSize := le.Uint32(data[0:4])
+	fontDataSize := le.Uint32(data[4:8])
+	flags := le.Uint32(data[12:16])
+	if le.Uint16(data[34:36]) != eotMagic || eotSize > uint32(len(data)) || fontDataSize > eotSize {
+		return nil, fmt.Errorf("файл не является шрифтом EOT")
+	}
+	if flags&eotFlagCompressed != 0 {
+		return nil, fmt.Errorf("сжатые (MTX) шрифты EOT не поддерживаются")
+	}
+
+	fontData := append([]byte{}, data[eotSize-fontDataSize:eotSize]...)
+	if flags&eotFlagXOR != 0 {
+		for i := range fontData {
+			fontData[i] ^= 0x50
+		}
+	}
+	if _, err := ParseSfnt(fontData); err != nil {
+		return nil, err
+	}
+	return fontData, nil
+}
+
+func fontName(table []byte, nameID uint16) string {
+	if len(table) < 6 {
+		return ""
+	}
+	be := binary.BigEndian
+	count := int(be.Uint16(table[2:4]))
+	storage := int(be.Uint16(table[4:6]))
+	for i := 0; i < count; i++ {
+		rec := 6 + i*12
+		if rec+12 > len(table) {
+			break
+		}
+		platformID := be.Uint16(table[rec : rec+2])
+		encodingID := be.Uint16(table[rec+2 : rec+4])
+		languageID := be.Uint16(table[rec+4 : rec+6])
+		id := be.Uint16(table[rec+6 : rec+8])
+		length := int(be.Uint16(table[rec+8 : rec+10]))
+		offset := int(be.Uint16(table[rec+10 : rec+12]))
+		if id != nameID || platformID != 3 || encodingID != 1 || languageID != 0x409 {
+			continue
+		}
+		start := storage + offset
+		if start+length > len(table) {
+			return ""
+		}
+		raw := table[start : start+length]
+		units := make([]uint16, 0, len(raw)/2)
+		for j := 0; j+1 < len(raw); j += 2 {
+			units = append(units, be.Uint16(raw[j:j+2]))
+		}
+		return string(utf16.Decode(units))
+	}
+	return ""
+}
+
+func utf16le(s string) []byte {
+	units := utf16.Encode([]rune(s))
+	out := make([]byte, len(units)*2)
+	for i, u := range units {
+		binary.LittleEndian.PutUint16(out[i*2:], u)
+	}
+	return out
+}
@@ -0,0 +1,264 @@
+package fonts
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+	"unicode/utf16"
+)
+
+func nameTable(family string) []byte {
+	units := utf16.Encode([]rune(family))
+	str := make([]byte, len(units)*2)
+	for i, u := range units {
+		binary.BigEndian.PutUint16(str[i*2:], u)
+	}
+	out := make([]byte, 6+12)
+	binary.BigEndian.PutUint16(out[2:4], 1)
+	binary.BigEndian.PutUint16(out[4:6], uint16(len(out)))
+	rec := out[6:18]
+	binary.BigEndian.PutUint16(rec[0:2], 3)
+	binary.BigEndian.PutUint16(rec[2:4], 1)
+	binary.BigEndian.PutUint16(rec[4:6], 0x409)
+	binary.BigEndian.PutUint16(rec[6:8], 1)
+	binary.BigEndian.PutUint16(rec[8:10], uint16(len(str)))
+	return append(out, str...)
+}
+
+func testFont(flavor uint32) []byte {
+	head := make([]byte, 54)
+	binary.BigEndian.PutUint32(head[8:12], 0x12345678)
+	os2 := make([]byte, 96)
+	binary.BigEndian.PutUint16(os2[4:6], 400)
+	for i := 32; i < 42; i++ {
+		os2[i] = byte(i)
+	}
+	glyf := bytes.Repeat([]byte{0, 1, 2, 3, 4, 5, 6, 7}, 64)
+	f := &Font{Flavor: flavor, Tables: []Table{
+		{Tag: "head", Data: head},
+		{Tag: "OS/2", Data: os2},
+		{Tag: "name", Data: nameTable("Test Sans")},
+		{Tag: "glyf", Data: glyf},
+		{Tag: "odd ", Data: []byte{1, 2, 3}},
+	}}
+	return f.Bytes()
+}
+
+func TestSfntRoundTrip(t *testing.T) {
+	data := testFont(sfntTrueType)
+	f, err := ParseSfnt(data)
+	if err != nil {
+		t.Fatalf("ParseSfnt: %v", err)
+	}
+	if len(f.Tables) != 5 {
+		t.Fatalf("got %d tables, want 5", len(f.Tables))
+	}
+	if got := f.Table("odd "); !bytes.Equal(got, []byte{1, 2, 3}) {
+		t.Errorf("odd table = %v", got)
+	}
+	if !bytes.Equal(f.Bytes(), data) {
+		t.Error("re-serialised font differs from the original")
+	}
+	if f.IsCFF() {
+		t.Error("TrueType font reported as CFF")
+	}
+	if got := fontName(f.Table("name"), 1); got != "Test Sans" {
+		t.Errorf("fontName = %q, want %q", got, "Test Sans")
+	}
+}
+
+func TestWOFFRoundTrip(t *testing.T) {
+	for _, flavor := range []uint32{sfntTrueType, sfntOpenType} {
+		data := testFont(flavor)
+		woff, err := EncodeWOFF(data)
+		if err != nil {
+			t.Fatalf("EncodeWOFF: %v", err)
+		}
+		if len(woff) >= len(data)+woffHeaderSize {
+			t.Errorf("flavor %#x: WOFF did not compress the glyf table (%d bytes)", flavor, len(woff))
+		}
+		f, err := DecodeWOFF(woff)
+		if err != nil {
+			t.Fatalf("DecodeWOFF: %v", err)
+		}
+		if f.Flavor != flavor {
+			t.Errorf("flavor = %#x, want %#x", f.Flavor, flavor)
+		}
+		if !bytes.Equal(f.Bytes(), data) {
+			t.Errorf("flavor %#x: decoded font differs from the original", flavor)
+		}
+	}
+}
+
+func TestEOTRoundTrip(t *testing.T) {
+	data := testFont(sfntTrueType)
+	eot, err := EncodeEOT(data)
+	if err != nil {
+		t.Fatalf("EncodeEOT: %v", err)
+	}
+	if got := binary.LittleEndian.Uint32(eot[0:4]); got != uint32(len(eot)) {
+		t.Errorf("EOTSize = %d, want %d", got, len(eot))
+	}
+	if !bytes.Contains(eot, utf16le("Test Sans")) {
+		t.Error("EOT header does not carry the family name")
+	}
+	decoded, err := DecodeEOT(eot)
+	if err != nil {
+		t.Fatalf("DecodeEOT: %v", err)
+	}
+	if !bytes.Equal(decoded, data) {
+		t.Error("decoded EOT font differs from the original")
+	}
+}
+
+func TestDecodeEOTXOR(t *testing.T) {
+	data := testFont(sfntTrueType)
+	eot, err := EncodeEOT(data)
+	if err != nil {
+		t.Fatalf("EncodeEOT: %v", err)
+	}
+	binary.LittleEndian.PutUint32(eot[12:16], eotFlagXOR)
+	for i := len(eot) - len(data); i < len(eot); i++ {
+		eot[i] ^= 0x50
+	}
+	decoded, err := DecodeEOT(eot)
+	if err != nil {
+		t.Fatalf("DecodeEOT: %v", err)
+	}
+	if !bytes.Equal(decoded, data) {
+		t.Error("XOR-obfuscated EOT was not restored")
+	}
+}
+
+func TestEncodeEOTRejectsCFF(t *testing.T) {
+	if _, err := EncodeEOT(testFont(sfntOpenType)); err == nil {
+		t.Error("expected an error for a CFF font")
+	}
+}
+
+func TestParseSfntRejectsMalformedInput(t *testing.T) {
+	valid := testFont(sfntTrueType)
+
+	outOfRange := append([]byte{}, valid...)
+	binary.BigEndian.PutUint32(outOfRange[12+8:12+12], uint32(len(valid)))
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"short", valid[:8]},
+		{"wrong flavor", append([]byte("wOFF"), valid[4:]...)},
+		{"truncated directory", valid[:12+16*2]},
+		{"table out of range", outOfRange},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if f, err := ParseSfnt(tt.data); err == nil {
+				t.Errorf("expected an error, got %d tables", len(f.Tables))
+			}
+		})
+	}
+}
+
+func TestDecodeWOFFRejectsMalformedInput(t *testing.T) {
+	woff, err := EncodeWOFF(testFont(sfntTrueType))
+	if err != nil {
+		t.Fatalf("EncodeWOFF: %v", err)
+	}
+	recOffset := -1
+	for i := 0; i < int(binary.BigEndian.Uint16(woff[12:14])); i++ {
+		if off := woffHeaderSize + i*woffEntrySize; string(woff[off:off+4]) == "glyf" {
+			recOffset = off
+		}
+	}
+	if recOffset < 0 {
+		t.Fatal("no glyf entry")
+	}
+	glyfOffset := binary.BigEndian.Uint32(woff[recOffset+4 : recOffset+8])
+	mutate := func(fn func(data []byte)) []byte {
+		data := append([]byte{}, woff...)
+		fn(data)
+		return data
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"sfnt instead of woff", testFont(sfntTrueType)},
+		{"truncated directory", woff[:woffHeaderSize+woffEntrySize]},
+		{"table out of range", mutate(func(d []byte) {
+			binary.BigEndian.PutUint32(d[recOffset+4:recOffset+8], uint32(len(woff)))
+		})},
+		{"corrupt zlib stream", mutate(func(d []byte) {
+			d[glyfOffset] ^= 0xff
+			d[glyfOffset+1] ^= 0xff
+		})},
+		{"wrong original length", mutate(func(d []byte) {
+			orig := binary.BigEndian.Uint32(d[recOffset+12 : recOffset+16])
+			binary.BigEndian.PutUint32(d[recOffset+12:recOffset+16], orig+4)
+		})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if f, err := DecodeWOFF(tt.data); err == nil {
+				t.Errorf("expected an error, got %d tables", len(f.Tables))
+			}
+		})
+	}
+}
+
+func TestDecodeEOTRejectsMalformedInput(t *testing.T) {
+	eot, err := EncodeEOT(testFont(sfntTrueType))
+	if err != nil {
+		t.Fatalf("EncodeEOT: %v", err)
+	}
+	mutate := func(fn func(data []byte)) []byte {
+		data := append([]byte{}, eot...)
+		fn(data)
+		return data
+	}
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"short", eot[:40]},
+		{"bad magic", mutate(func(d []byte) { binary.LittleEndian.PutUint16(d[34:36], 0) })},
+		{"size beyond file", mutate(func(d []byte) { binary.LittleEndian.PutUint32(d[0:4], uint32(len(eot)+1)) })},
+		{"compressed", mutate(func(d []byte) { binary.LittleEndian.PutUint32(d[12:16], eotFlagCompressed) })},
+		{"not an sfnt inside", mutate(func(d []byte) {
+			copy(d[len(d)-int(binary.LittleEndian.Uint32(d[4:8])):], "junk")
+		})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecodeEOT(tt.data); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestTableChecksum(t *testing.T) {
+	tests := []struct {
+		data []byte
+		want uint32
+	}{
+		{nil, 0},
+		{[]byte{0, 0, 0, 1}, 1},
+		{[]byte{0, 0, 0, 1, 0, 0, 0, 2}, 3},
+		{[]byte{1}, 0x01000000},
+		{[]byte{0xff, 0xff, 0xff, 0xff, 0, 0, 0, 2}, 1},
+	}
+	for _, tt := range tests {
+		if got := tableChecksum(tt.data); got != tt.want {
+			t.Errorf("tableChecksum(%v) = %#x, want %#x", tt.data, got, tt.want)
+		}
+	}
+	if got := []int{pad4(0), pad4(1), pad4(4), pad4(5)}; !reflect.DeepEqual(got, []int{0, 4, 4, 8}) {
+		t.Errorf("pad4 = %v", got)
+	}
+}
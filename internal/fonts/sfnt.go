@@ -0,0 +1,123 @@
+package fonts
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+const (
+	sfntTrueType = 0x00010000
+	sfntOpenType = 0x4F54544F
+	sfntApple    = 0x74727565
+)
+
+var ErrNotSfnt = errors.New("файл не является шрифтом TrueType/OpenType")
+
+type Table struct {
+	Tag      string
+	Checksum uint32
+	Data     []byte
+}
+
+type Font struct {
+	Flavor uint32
+	Tables []Table
+}
+
+func ParseSfnt(data []byte) (*Font, error) {
+	if len(data) < 12 {
+		return nil, ErrNotSfnt
+	}
+	flavor := binary.BigEndian.Uint32(data[0:4])
+	if flavor != sfntTrueType && flavor != sfntOpenType && flavor != sfntApple {
+		return nil, ErrNotSfnt
+	}
+	numTables := int(binary.BigEndian.Uint16(data[4:6]))
+	if len(data) < 12+numTables*16 {
+		return nil, fmt.Errorf("повреждённый шрифт: обрезан каталог таблиц")
+	}
+
+	f := &Font{Flavor: flavor, Tables: make([]Table, 0, numTables)}
+	for i := 0; i < numTables; i++ {
+		rec := data[12+i*16 : 12+(i+1)*16]
+		offset := binary.BigEndian.Uint32(rec[8:12])
+		length := binary.BigEndian.Uint32(rec[12:16])
+		if uint64(offset)+uint64(length) > uint64(len(data)) {
+			return nil, fmt.Errorf("повреждённый шрифт: таблица %s выходит за пределы файла", string(rec[0:4]))
+		}
+		f.Tables = append(f.Tables, Table{
+			Tag:      string(rec[0:4]),
+			Checksum: binary.BigEndian.Uint32(rec[4:8]),
+			Data:     data[offset : offset+length],
+		})
+	}
+	return f, nil
+}
+
+func (f *Font) IsCFF() bool {
+	return f.Flavor == sfntOpenType
+}
+
+func (f *Font) Table(tag string) []byte {
+	for _, t := range f.Tables {
+		if t.Tag == tag {
+			return t.Data
+		}
+	}
+	return nil
+}
+
+func (f *Font) Bytes() []byte {
+	tables := append([]Table{}, f.Tables...)
+	sort.Slice(tables, func(i, j int) bool { return tables[i].Tag < tables[j].Tag })
+
+	numTables := len(tables)
+	entrySelector := 0
+	for (1 << (entrySelector + 1)) <= numTables {
+		entrySelector++
+	}
+	searchRange := (1 << entrySelector) * 16
+
+	size := 12 + 16*numTables
+	for _, t := range tables {
+		size += pad4(len(t.Data))
+	}
+	out := make([]byte, size)
+	binary.BigEndian.PutUint32(out[0:4], f.Flavor)
+	binary.BigEndian.PutUint16(out[4:6], uint16(numTables))
+	binary.BigEndian.PutUint16(out[6:8], uint16(searchRange))
+	binary.BigEndian.PutUint16(out[8:10], uint16(entrySelector))
+	binary.BigEndian.PutUint16(out[10:12], uint16(numTables*16-searchRange))
+
+	offset := 12 + 16*numTables
+	for i, t := range tables {
+		rec := out[12+i*16 : 12+(i+1)*16]
+		copy(rec[0:4], t.Tag)
+		checksum := t.Checksum
+		if checksum == 0 {
+			checksum = tableChecksum(t.Data)
+		}
+		binary.BigEndian.PutUint32(rec[4:8], checksum)
+		binary.BigEndian.PutUint32(rec[8:12], uint32(offset))
+		binary.BigEndian.PutUint32(rec[12:16], uint32(len(t.Data)))
+		copy(out[offset:], t.Data)
+		offset += pad4(len(t.Data))
+	}
+	return out
+}
+
+func tableChecksum(data []byte) uint32 {
+	var sum uint32
+	for i := 0; i < len(data); i += 4 {
+		var word [4]byte
+		copy(word[:], data[i:])
+		sum += binary.BigEndian.Uint32(word[:])
+	}
+	return sum
+}
+
+func pad4(n int) int {
+	return (n + 3) &^ 3
+}
@@ -0,0 +1,124 @@
+package fonts
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+)
+
+const (
+	woffSignature  = 0x774F4646
+	woffHeaderSize = 44
+	woffEntrySize  = 20
+)
+
+func EncodeWOFF(sfnt []byte) ([]byte, error) {
+	f, err := ParseSfnt(sfnt)
+	if err != nil {
+		return nil, err
+	}
+
+	tables := append([]Table{}, f.Tables...)
+	sort.Slice(tables, func(i, j int) bool { return tables[i].Tag < tables[j].Tag })
+
+	type entry struct {
+		table      Table
+		compressed []byte
+	}
+	entries := make([]entry, 0, len(tables))
+	totalSfntSize := 12 + 16*len(tables)
+	for _, t := range tables {
+		var buf bytes.Buffer
+		zw, err := zlib.NewWriterLevel(&buf, zlib.BestCompression)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := zw.Write(t.Data); err != nil {
+			return nil, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+		data := t.Data
+		if buf.Len() < len(t.Data) {
+			data = buf.Bytes()
+		}
+		entries = append(entries, entry{table: t, compressed: data})
+		totalSfntSize += pad4(len(t.Data))
+	}
+
+	size := woffHeaderSize + woffEntrySize*len(entries)
+	for _, e := range entries {
+		size += pad4(len(e.compressed))
+	}
+
+	out := make([]byte, size)
+	binary.BigEndian.PutUint32(out[0:4], woffSignature)
+	binary.BigEndian.PutUint32(out[4:8], f.Flavor)
+	binary.BigEndian.PutUint32(out[8:12], uint32(size))
+	binary.BigEndian.PutUint16(out[12:14], uint16(len(entries)))
+	binary.BigEndian.PutUint32(out[16:20], uint32(totalSfntSize))
+	binary.BigEndian.PutUint16(out[20:22], 1)
+
+	offset := woffHeaderSize + woffEntrySize*len(entries)
+	for i, e := range entries {
+		rec := out[woffHeaderSize+i*woffEntrySize : woffHeaderSize+(i+1)*woffEntrySize]
+		checksum := e.table.Checksum
+		if checksum == 0 {
+			checksum = tableChecksum(e.table.Data)
+		}
+		copy(rec[0:4], e.table.Tag)
+		binary.BigEndian.PutUint32(rec[4:8], uint32(offset))
+		binary.BigEndian.PutUint32(rec[8:12], uint32(len(e.compressed)))
+		binary.BigEndian.PutUint32(rec[12:16], uint32(len(e.table.Data)))
+		binary.BigEndian.PutUint32(rec[16:20], checksum)
+		copy(out[offset:], e.compressed)
+		offset += pad4(len(e.compressed))
+	}
+	return out, nil
+}
+
+func DecodeWOFF(data []byte) (*Font, error) {
+	if len(data) < woffHeaderSize || binary.BigEndian.Uint32(data[0:4]) != woffSignature {
+		return nil, fmt.Errorf("файл не является шрифтом WOFF")
+	}
+	flavor := binary.BigEndian.Uint32(data[4:8])
+	numTables := int(binary.BigEndian.Uint16(data[12:14]))
+	if len(data) < woffHeaderSize+numTables*woffEntrySize {
+		return nil, fmt.Errorf("повреждённый WOFF: обрезан каталог таблиц")
+	}
+
+	f := &Font{Flavor: flavor, Tables: make([]Table, 0, numTables)}
+	for i := 0; i < numTables; i++ {
+		rec := data[woffHeaderSize+i*woffEntrySize : woffHeaderSize+(i+1)*woffEntrySize]
+		tag := string(rec[0:4])
+		offset := binary.BigEndian.Uint32(rec[4:8])
+		compLength := binary.BigEndian.Uint32(rec[8:12])
+		origLength := binary.BigEndian.Uint32(rec[12:16])
+		checksum := binary.BigEndian.Uint32(rec[16:20])
+		if uint64(offset)+uint64(compLength) > uint64(len(data)) {
+			return nil, fmt.Errorf("повреждённый WOFF: таблица %s выходит за пределы файла", tag)
+		}
+		raw := data[offset : offset+compLength]
+		if compLength < origLength {
+			zr, err := zlib.NewReader(bytes.NewReader(raw))
+			if err != nil {
+				return nil, fmt.Errorf("повреждённый WOFF: таблица %s: %v", tag, err)
+			}
+			unpacked, err := io.ReadAll(io.LimitReader(zr, int64(origLength)+1))
+			_ = zr.Close()
+			if err != nil {
+				return nil, fmt.Errorf("повреждённый WOFF: таблица %s: %v", tag, err)
+			}
+			raw = unpacked
+		}
+		if uint32(len(raw)) != origLength {
+			return nil, fmt.Errorf("повреждённый WOFF: неверный размер таблицы %s", tag)
+		}
+		f.Tables = append(f.Tables, Table{Tag: tag, Checksum: checksum, Data: raw})
+	}
+	return f, nil
+}
@@ -5,6 +5,7 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/BatmanBruc/bat-bot-convetor/internal/capability"
 	"github.com/BatmanBruc/bat-bot-convetor/internal/i18n"
 	"github.com/BatmanBruc/bat-bot-convetor/internal/messages"
 )
@@ -24,6 +25,7 @@ type BatchFile struct {
 	FileID   string
 	FileName string
 	FileSize int64
+	Duration int
 }
 
 func normalizeExt(ext string) string {
@@ -78,6 +80,18 @@ func ebookFormats() []string {
 	return SupportedFormats["ebook"][0].Formats
 }
 
+func ebookTargetFormats() []string {
+	return []string{"EPUB", "MOBI", "AZW3", "FB2", "LRF", "PDB"}
+}
+
+func fontFormats() []string {
+	return SupportedFormats["font"][0].Formats
+}
+
+func subtitleFormats() []string {
+	return SupportedFormats["subtitles"][0].Formats
+}
+
 func officeFormats() []string {
 	return append(append(append([]string{}, writerFormats()...), sheetFormats()...), slideFormats()...)
 }
@@ -104,12 +118,49 @@ func containsCaseInsensitive(list []string, item string) bool {
 	return false
 }
 
+func canConvert(sourceExt string, targetExt string) bool {
+	return capability.Supports(normalizeExt(sourceExt), normalizeExt(targetExt))
+}
+
+func availableTargets(sourceExt string, targets []string) []string {
+	reachable, ok := capability.Reachable(normalizeExt(sourceExt))
+	if !ok {
+		return targets
+	}
+	out := make([]string, 0, len(targets))
+	for _, t := range targets {
+		if containsCaseInsensitive(reachable, normalizeExt(t)) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
 func GetTargetFormatsForSourceExt(sourceExt string) []string {
 	sourceExt = normalizeExt(sourceExt)
 	if sourceExt == "" {
 		return nil
 	}
+	reachable, ok := capability.Reachable(sourceExt)
+	if !ok {
+		return staticTargetFormats(sourceExt)
+	}
+	targets := make([]string, 0, len(reachable))
+	for _, t := range reachable {
+		if FormatExists(t) {
+			targets = append(targets, t)
+		}
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+	targets = uniqUpper(targets)
+	targets = withoutSameExt(targets, sourceExt)
+	sort.Strings(targets)
+	return targets
+}
 
+func staticTargetFormats(sourceExt string) []string {
 	switch {
 	case containsCaseInsensitive(imageFormats(), sourceExt):
 		targets := uniqUpper(imageFormats())
@@ -133,7 +184,7 @@ func GetTargetFormatsForSourceExt(sourceExt string) []string {
 		return targets
 
 	case containsCaseInsensitive(ebookFormats(), sourceExt):
-		targets := append([]string{}, ebookFormats()...)
+		targets := append([]string{}, ebookTargetFormats()...)
 		targets = append(targets, "PDF")
 		targets = uniqUpper(targets)
 		targets = withoutSameExt(targets, sourceExt)
@@ -142,7 +193,21 @@ func GetTargetFormatsForSourceExt(sourceExt string) []string {
 	}
 
 	if sourceExt == "pdf" {
-		return []string{"TXT"}
+		return []string{"DOCX", "HTML", "JPG", "ODT", "PNG", "TXT"}
+	}
+
+	if containsCaseInsensitive(subtitleFormats(), sourceExt) {
+		targets := uniqUpper(subtitleFormats())
+		targets = withoutSameExt(targets, sourceExt)
+		sort.Strings(targets)
+		return targets
+	}
+
+	if containsCaseInsensitive(fontFormats(), sourceExt) {
+		targets := uniqUpper(fontFormats())
+		targets = withoutSameExt(targets, sourceExt)
+		sort.Strings(targets)
+		return targets
 	}
 
 	if containsCaseInsensitive(writerFormats(), sourceExt) {
@@ -164,8 +229,7 @@ func GetTargetFormatsForSourceExt(sourceExt string) []string {
 	}
 
 	if containsCaseInsensitive(slideFormats(), sourceExt) {
-		targets := append([]string{}, slideFormats()...)
-		targets = append(targets, "PDF")
+		targets := []string{"PPT", "PPTX", "PPS", "PPSX", "POT", "POTX", "ODP", "PDF"}
 		targets = uniqUpper(targets)
 		targets = withoutSameExt(targets, sourceExt)
 		sort.Strings(targets)
@@ -194,15 +258,18 @@ func GetButtonsForSourceExt(sourceExt string, taskID string, lang i18n.Lang) []F
 	if containsCaseInsensitive(videoFormats(), sourceExt) {
 		return getVideoActionButtons(sourceExt, taskID, lang)
 	}
+	if containsCaseInsensitive(audioFormats(), sourceExt) {
+		return getAudioActionButtons(sourceExt, taskID, lang)
+	}
 	if sourceExt == "pdf" {
-		return getPdfActionButtons(taskID)
+		return getPdfActionButtons(taskID, lang)
 	}
 	return GetFormatButtonsBySourceExt(sourceExt, taskID)
 }
 
 func getImageActionButtons(sourceExt string, taskID string, lang i18n.Lang) []FormatButton {
 	buttons := make([]FormatButton, 0)
-	{
+	if canConvert(sourceExt, "jpg") {
 		buttons = append(buttons, FormatButton{
 			Text:         strings.TrimSpace(pick(lang, "🛒 Авито (JPG)", "🛒 Avito (JPG)")),
 			CallbackData: fmt.Sprintf("pimg_avito_for_%s", taskID),
@@ -222,7 +289,17 @@ func getImageActionButtons(sourceExt string, taskID string, lang i18n.Lang) []Fo
 	}
 	buttons = append(buttons, GetFormatButtonsBySourceExt(sourceExt, taskID)...)
 
+	if capability.Has("tesseract") && canConvert(sourceExt, "pdf") {
+		buttons = append(buttons, FormatButton{
+			Text:         pick(lang, "🔍 PDF с распознанным текстом", "🔍 Searchable PDF (OCR)"),
+			CallbackData: fmt.Sprintf("pdfop_ocr_for_%s", taskID),
+		})
+	}
+
 	resizePresets := []int{1080, 720}
+	if !canConvert(sourceExt, sourceExt) {
+		resizePresets = nil
+	}
 	for _, max := range resizePresets {
 		label := fmt.Sprintf("%s %dpx", pick(lang, "📏", "📏"), max)
 		buttons = append(buttons, FormatButton{
@@ -241,6 +318,9 @@ func getImageActionButtons(sourceExt string, taskID string, lang i18n.Lang) []Fo
 		{"webp", 70},
 	}
 	for _, ct := range compressTargets {
+		if !canConvert(sourceExt, ct.ext) {
+			continue
+		}
 		label := fmt.Sprintf("%s %s %d%%", pick(lang, "🗜", "🗜"), strings.ToUpper(ct.ext), ct.quality)
 		buttons = append(buttons, FormatButton{
 			Text:         strings.TrimSpace(label),
@@ -251,9 +331,24 @@ func getImageActionButtons(sourceExt string, taskID string, lang i18n.Lang) []Fo
 	return buttons
 }
 
+func getAudioActionButtons(sourceExt string, taskID string, lang i18n.Lang) []FormatButton {
+	buttons := make([]FormatButton, 0)
+	if canConvert(sourceExt, "ogg") {
+		buttons = append(buttons, FormatButton{
+			Text:         pick(lang, "🎙 Голосовое сообщение", "🎙 Voice message"),
+			CallbackData: fmt.Sprintf("tgm_voice_for_%s", taskID),
+		})
+	}
+	return append(buttons, GetFormatButtonsBySourceExt(sourceExt, taskID)...)
+}
+
 func getVideoActionButtons(sourceExt string, taskID string, lang i18n.Lang) []FormatButton {
 	buttons := make([]FormatButton, 0)
-	{
+	if canConvert(sourceExt, "mp4") {
+		buttons = append(buttons, FormatButton{
+			Text:         pick(lang, "⭕ Видеосообщение (кружок)", "⭕ Video note"),
+			CallbackData: fmt.Sprintf("tgm_note_for_%s", taskID),
+		})
 		buttons = append(buttons, FormatButton{
 			Text:         strings.TrimSpace(pick(lang, "🎵 TikTok 9:16 1080×1920", "🎵 TikTok 9:16 1080×1920")),
 			CallbackData: fmt.Sprintf("pvid_tiktok_for_%s", taskID),
@@ -275,9 +370,41 @@ func getVideoActionButtons(sourceExt string, taskID string, lang i18n.Lang) []Fo
 			CallbackData: fmt.Sprintf("pvid_youtube_1080p_for_%s", taskID),
 		})
 	}
-	buttons = append(buttons, GetFormatButtonsBySourceExt(sourceExt, taskID)...)
+	targets := make([]string, 0)
+	for _, t := range GetTargetFormatsForSourceExt(sourceExt) {
+		if !containsCaseInsensitive(subtitleFormats(), t) {
+			targets = append(targets, t)
+		}
+	}
+	buttons = append(buttons, GetFormatButtonsByList(targets, taskID)...)
+
+	for _, ext := range []string{"srt", "vtt", "ass"} {
+		if !canConvert(sourceExt, ext) {
+			continue
+		}
+		buttons = append(buttons, FormatButton{
+			Text:         fmt.Sprintf("%s %s", pick(lang, "💬 Субтитры", "💬 Subtitles"), strings.ToUpper(ext)),
+			CallbackData: fmt.Sprintf("%s_for_%s", ext, taskID),
+		})
+	}
+
+	if canConvert(sourceExt, "mp4") || canConvert(sourceExt, "mkv") {
+		buttons = append(buttons, FormatButton{
+			Text:         pick(lang, "🔥 Вшить субтитры", "🔥 Burn in subtitles"),
+			CallbackData: fmt.Sprintf("vsub_burn_for_%s", taskID),
+		})
+		buttons = append(buttons, FormatButton{
+			Text:         pick(lang, "📎 Добавить дорожку субтитров", "📎 Add subtitle track"),
+			CallbackData: fmt.Sprintf("vsub_mux_for_%s", taskID),
+		})
+	}
 
 	resizeHeights := []int{720, 480}
+	crfPresets := []int{28, 35}
+	if !canConvert(sourceExt, "mp4") {
+		resizeHeights = nil
+		crfPresets = nil
+	}
 	for _, h := range resizeHeights {
 		label := fmt.Sprintf("%s %dp", pick(lang, "📏", "📏"), h)
 		buttons = append(buttons, FormatButton{
@@ -286,7 +413,6 @@ func getVideoActionButtons(sourceExt string, taskID string, lang i18n.Lang) []Fo
 		})
 	}
 
-	crfPresets := []int{28, 35}
 	for _, crf := range crfPresets {
 		label := fmt.Sprintf("%s MP4 CRF %d", pick(lang, "🗜", "🗜"), crf)
 		buttons = append(buttons, FormatButton{
@@ -296,6 +422,9 @@ func getVideoActionButtons(sourceExt string, taskID string, lang i18n.Lang) []Fo
 	}
 
 	gifHeights := []int{480, 320}
+	if !canConvert(sourceExt, "gif") {
+		gifHeights = nil
+	}
 	for _, h := range gifHeights {
 		label := fmt.Sprintf("%s GIF %dp", pick(lang, "🎞", "🎞"), h)
 		buttons = append(buttons, FormatButton{
@@ -307,10 +436,59 @@ func getVideoActionButtons(sourceExt string, taskID string, lang i18n.Lang) []Fo
 	return buttons
 }
 
-func getPdfActionButtons(taskID string) []FormatButton {
-	return []FormatButton{
-		{Text: "TXT", CallbackData: "txt_for_" + taskID},
+func getPdfActionButtons(taskID string, lang i18n.Lang) []FormatButton {
+	buttons := GetFormatButtonsBySourceExt("pdf", taskID)
+
+	if capability.Has("tesseract") && canConvert("pdf", "pdf") {
+		buttons = append(buttons, FormatButton{
+			Text:         pick(lang, "🔍 Распознать текст (OCR)", "🔍 Recognize text (OCR)"),
+			CallbackData: fmt.Sprintf("pdfop_ocr_for_%s", taskID),
+		})
+	}
+
+	if capability.Has("ghostscript") {
+		presets := []struct {
+			preset string
+			text   string
+		}{
+			{"screen", pick(lang, "🗜 Сжать сильно", "🗜 Max compression")},
+			{"ebook", pick(lang, "🗜 Сжать", "🗜 Compress")},
+			{"printer", pick(lang, "🗜 Для печати", "🗜 Print quality")},
+		}
+		for _, p := range presets {
+			buttons = append(buttons, FormatButton{
+				Text:         p.text,
+				CallbackData: fmt.Sprintf("pdfc_%s_for_%s", p.preset, taskID),
+			})
+		}
+	}
+
+	if !capability.Has("qpdf") {
+		return buttons
+	}
+
+	ops := []struct {
+		op   string
+		text string
+	}{
+		{"split", pick(lang, "✂️ Разделить по страницам", "✂️ Split into pages")},
+		{"splitr", pick(lang, "✂️ Разделить по диапазонам", "✂️ Split by ranges")},
+		{"extract", pick(lang, "📄 Извлечь страницы", "📄 Extract pages")},
+		{"delete", pick(lang, "🗑 Удалить страницы", "🗑 Delete pages")},
+		{"reorder", pick(lang, "🔀 Изменить порядок", "🔀 Reorder pages")},
+		{"rot90", pick(lang, "↻ Повернуть 90°", "↻ Rotate 90°")},
+		{"rot180", pick(lang, "🔄 Повернуть 180°", "🔄 Rotate 180°")},
+		{"rot270", pick(lang, "↺ Повернуть 90° влево", "↺ Rotate 90° left")},
+		{"encrypt", pick(lang, "🔒 Поставить пароль", "🔒 Set password")},
+		{"decrypt", pick(lang, "🔓 Снять пароль", "🔓 Remove password")},
+	}
+	for _, o := range ops {
+		buttons = append(buttons, FormatButton{
+			Text:         o.text,
+			CallbackData: fmt.Sprintf("pdfop_%s_for_%s", o.op, taskID),
+		})
 	}
+	return buttons
 }
 
 func GetBatchButtonsBySourceExt(sourceExt string, taskID string, files []BatchFile, lang i18n.Lang) []FormatButton {
@@ -352,7 +530,9 @@ func GetFormatButtonsByList(formatList []string, taskID string) []FormatButton {
 }
 
 func GetTextOutputButtons(taskID string) []FormatButton {
-	return GetFormatButtonsByList([]string{"TXT", "PDF", "DOCX", "RTF", "ODT"}, taskID)
+	targets := []string{"TXT"}
+	targets = append(targets, availableTargets("txt", []string{"PDF", "DOCX", "RTF", "ODT"})...)
+	return GetFormatButtonsByList(targets, taskID)
 }
 
 var SupportedFormats = map[string][]FormatCategory{
@@ -381,7 +561,7 @@ var SupportedFormats = map[string][]FormatCategory{
 		{
 			Name:    "Document",
 			Icon:    "💼",
-			Formats: []string{"XLSX", "XLS", "TXT", "RTF", "DOC", "DOCX", "ODT", "PDF", "ODS", "TORRENT"},
+			Formats: []string{"XLSX", "XLS", "TXT", "RTF", "DOC", "DOCX", "ODT", "PDF", "ODS", "HTML", "TORRENT"},
 		},
 	},
 	"presentation": {
@@ -405,6 +585,13 @@ var SupportedFormats = map[string][]FormatCategory{
 			Formats: []string{"TTF", "OTF", "EOT", "WOFF", "WOFF2", "SVG", "PFB"},
 		},
 	},
+	"subtitles": {
+		{
+			Name:    "Subtitles",
+			Icon:    "💬",
+			Formats: []string{"SRT", "VTT", "ASS", "SSA", "SBV", "TTML", "DFXP"},
+		},
+	},
 }
 
 func GetAllFormats() []FormatCategory {
@@ -451,7 +638,7 @@ func GetCategoryByExtension(ext string) string {
 		"xlsx": "document", "xls": "document", "txt": "document",
 		"rtf": "document", "doc": "document", "docx": "document",
 		"odt": "document", "pdf": "document", "ods": "document",
-		"torrent": "document",
+		"html": "document", "torrent": "document",
 
 		"ppt": "presentation", "pptx": "presentation", "pptm": "presentation",
 		"pps": "presentation", "ppsx": "presentation", "ppsm": "presentation",
@@ -464,6 +651,10 @@ func GetCategoryByExtension(ext string) string {
 
 		"ttf": "font", "otf": "font", "eot": "font", "woff": "font",
 		"woff2": "font", "pfb": "font",
+
+		"srt": "subtitles", "vtt": "subtitles", "ass": "subtitles",
+		"ssa": "subtitles", "sbv": "subtitles", "ttml": "subtitles",
+		"dfxp": "subtitles",
 	}
 
 	if category, ok := extToCategory[ext]; ok {
@@ -499,6 +690,7 @@ func GetHelpMessage(lang i18n.Lang) string {
 		{"presentation", "🖼 Presentation"},
 		{"ebook", "📚 eBook"},
 		{"font", "🔤 Font"},
+		{"subtitles", "💬 Subtitles"},
 	}
 
 	for _, cat := range categories {
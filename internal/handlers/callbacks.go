@@ -11,6 +11,7 @@ import (
 	"github.com/BatmanBruc/bat-bot-convetor/internal/formats"
 	"github.com/BatmanBruc/bat-bot-convetor/internal/i18n"
 	"github.com/BatmanBruc/bat-bot-convetor/internal/messages"
+	"github.com/BatmanBruc/bat-bot-convetor/internal/utils"
 	"github.com/BatmanBruc/bat-bot-convetor/types"
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
@@ -41,6 +42,11 @@ func (bh *Handlers) HandleClickButton(ctx context.Context, b *bot.Bot, update *m
 		return
 	}
 
+	if strings.HasPrefix(data, "img2pdf_") {
+		bh.handleImagesToPDFClick(ctx, b, update, userID, lang, data)
+		return
+	}
+
 	format, taskID, err := bh.parseClickButtonData(data)
 	if err != nil {
 		_ = bh.answerCallback(ctx, b, update.CallbackQuery.ID, messages.CallbackInvalidButtonData(lang))
@@ -53,6 +59,10 @@ func (bh *Handlers) HandleClickButton(ctx context.Context, b *bot.Bot, update *m
 		bh.handleBatchChoice(ctx, b, update, userID, lang, taskID, format)
 		return
 	}
+	if format == "cancel" {
+		bh.handleCancelTask(ctx, b, update, userID, lang, taskID)
+		return
+	}
 	action := ""
 	targetExt := format
 	quality := 0
@@ -128,6 +138,61 @@ func (bh *Handlers) HandleClickButton(ctx context.Context, b *bot.Bot, update *m
 			action = ""
 		}
 	}
+	pdfOp := ""
+	pdfRotate := 0
+	if len(p) == 2 && p[0] == "pdfop" {
+		targetExt = "pdf"
+		switch p[1] {
+		case "split":
+			pdfOp = "split"
+		case "ocr":
+			pdfOp = "ocr"
+		case "rot90", "rot180", "rot270":
+			pdfOp = "rotate"
+			pdfRotate, _ = strconv.Atoi(strings.TrimPrefix(p[1], "rot"))
+		case "splitr":
+			bh.askPdfPages(ctx, b, update, userID, lang, taskID, "split_ranges")
+			return
+		case "extract", "delete", "reorder":
+			bh.askPdfPages(ctx, b, update, userID, lang, taskID, p[1])
+			return
+		case "encrypt", "decrypt":
+			bh.askPdfPassword(ctx, b, update, userID, lang, taskID, p[1])
+			return
+		default:
+			_ = bh.answerCallbackAlert(ctx, b, update.CallbackQuery.ID, messages.CallbackInvalidButtonData(lang))
+			return
+		}
+	}
+	if len(p) == 2 && p[0] == "tgm" {
+		switch p[1] {
+		case "voice":
+			action = "tg_voice"
+			targetExt = "ogg"
+		case "note":
+			action = "tg_note"
+			targetExt = "mp4"
+		default:
+			_ = bh.answerCallbackAlert(ctx, b, update.CallbackQuery.ID, messages.CallbackInvalidButtonData(lang))
+			return
+		}
+	}
+	if len(p) == 2 && p[0] == "vsub" {
+		bh.askVideoSubtitles(ctx, b, update, userID, lang, taskID, p[1])
+		return
+	}
+	pdfPreset := ""
+	if len(p) == 2 && p[0] == "pdfc" {
+		targetExt = "pdf"
+		switch p[1] {
+		case "screen", "ebook", "printer":
+			pdfOp = "compress"
+			pdfPreset = p[1]
+		default:
+			_ = bh.answerCallbackAlert(ctx, b, update.CallbackQuery.ID, messages.CallbackInvalidButtonData(lang))
+			return
+		}
+	}
 	if (len(p) >= 1 && (p[0] == "pimg" || p[0] == "pvid")) && action == "" {
 		_ = bh.answerCallbackAlert(ctx, b, update.CallbackQuery.ID, messages.CallbackInvalidButtonData(lang))
 		return
@@ -149,6 +214,10 @@ func (bh *Handlers) HandleClickButton(ctx context.Context, b *bot.Bot, update *m
 		_ = bh.answerCallback(ctx, b, update.CallbackQuery.ID, messages.CallbackTaskNotInSession(lang))
 		return
 	}
+	if task.State != types.StateChooseExt {
+		_ = bh.answerCallback(ctx, b, update.CallbackQuery.ID, messages.CallbackTaskAlreadyStarted(lang))
+		return
+	}
 
 	if task.Options == nil {
 		task.Options = map[string]interface{}{}
@@ -160,27 +229,12 @@ func (bh *Handlers) HandleClickButton(ctx context.Context, b *bot.Bot, update *m
 			return
 		}
 	}
-	unlimited := false
-	remaining := 0
-	if bh.billing != nil {
-		u, _ := bh.billing.IsUnlimited(userID)
-		unlimited = u
-	}
-
-	if update.CallbackQuery.Message.Message != nil {
-		msg := update.CallbackQuery.Message.Message
-		_, _ = b.EditMessageReplyMarkup(ctx, &bot.EditMessageReplyMarkupParams{
-			ChatID:    msg.Chat.ID,
-			MessageID: msg.ID,
-			ReplyMarkup: &models.InlineKeyboardMarkup{
-				InlineKeyboard: [][]models.InlineKeyboardButton{},
-			},
-		})
-	}
+	plan := bh.userPlan(userID)
 
 	task.TargetExt = targetExt
 	task.State = types.StateProcessing
-	task.Options["unlimited"] = unlimited
+	task.Inputs = nil
+	task.Options["unlimited"] = plan.Unlimited
 	task.Options["lang"] = string(lang)
 	delete(task.Options, "img_op")
 	delete(task.Options, "img_quality")
@@ -193,6 +247,23 @@ func (bh *Handlers) HandleClickButton(ctx context.Context, b *bot.Bot, update *m
 	delete(task.Options, "vid_gif_height")
 	delete(task.Options, "vid_w")
 	delete(task.Options, "vid_h")
+	delete(task.Options, "pdf_op")
+	delete(task.Options, "pdf_pages")
+	delete(task.Options, "pdf_rotate")
+	delete(task.Options, "pdf_preset")
+	delete(task.Options, "aud_op")
+	delete(task.Options, "tg_media")
+	delete(task.Options, "credits_charged")
+	delete(task.Options, "credits_purchased")
+	if pdfOp != "" {
+		task.Options["pdf_op"] = pdfOp
+		if pdfRotate > 0 {
+			task.Options["pdf_rotate"] = pdfRotate
+		}
+		if pdfPreset != "" {
+			task.Options["pdf_preset"] = pdfPreset
+		}
+	}
 	if action != "" {
 		if action == "compress" || action == "resize" {
 			task.Options["img_op"] = action
@@ -216,6 +287,14 @@ func (bh *Handlers) HandleClickButton(ctx context.Context, b *bot.Bot, update *m
 				task.Options["img_h"] = imgH
 			}
 		}
+		if action == "tg_voice" {
+			task.Options["aud_op"] = "voice"
+			task.Options["tg_media"] = "voice"
+		}
+		if action == "tg_note" {
+			task.Options["vid_op"] = "note"
+			task.Options["tg_media"] = "video_note"
+		}
 		if action == "vid_resize" {
 			task.Options["vid_op"] = "resize"
 			if videoHeight > 0 {
@@ -245,23 +324,84 @@ func (bh *Handlers) HandleClickButton(ctx context.Context, b *bot.Bot, update *m
 			}
 		}
 	}
+
+	remaining, denial, err := bh.chargeCredits(lang, plan, userID, task)
+	if err != nil {
+		log.Printf("Error charging credits for task %s: %v", taskID, err)
+		_ = bh.answerCallbackAlert(ctx, b, update.CallbackQuery.ID, messages.ErrorDefault(lang))
+		return
+	}
+	if denial != "" {
+		_ = bh.answerCallbackAlert(ctx, b, update.CallbackQuery.ID, denial)
+		return
+	}
+
+	if update.CallbackQuery.Message.Message != nil {
+		msg := update.CallbackQuery.Message.Message
+		_, _ = b.EditMessageReplyMarkup(ctx, &bot.EditMessageReplyMarkupParams{
+			ChatID:    msg.Chat.ID,
+			MessageID: msg.ID,
+			ReplyMarkup: &models.InlineKeyboardMarkup{
+				InlineKeyboard: [][]models.InlineKeyboardButton{},
+			},
+		})
+	}
+
 	if err := bh.store.UpdateTask(task); err != nil {
 		log.Printf("Error updating task %s: %v", taskID, err)
+		bh.refundCredits(task)
 		_ = bh.answerCallbackAlert(ctx, b, update.CallbackQuery.ID, messages.CallbackTaskUpdateFailed(lang))
 		return
 	}
 
-	priority := unlimited
+	bh.enqueueAndReport(ctx, b, userID, chatID, messageID, lang, task, plan, remaining)
+	_ = bh.answerCallback(ctx, b, update.CallbackQuery.ID, "")
+}
+
+func (bh *Handlers) enqueueAndReport(ctx context.Context, b *bot.Bot, userID int64, chatID int64, messageID int, lang i18n.Lang, task *types.Task, plan *types.Plan, remaining int) {
+	priority := plan.PriorityWeight > 1
 	task.Options["priority"] = priority
-	position := bh.scheduler.EnqueueTask(taskID, chatID, messageID, task.FileName, lang, priority)
+	if mode := bh.deliveryMode(userID); mode != "" {
+		task.Options["delivery"] = mode
+	}
+	if err := bh.store.UpdateTask(task); err != nil {
+		log.Printf("Error updating task %s: %v", task.ID, err)
+	}
+	position, err := bh.scheduler.EnqueueTask(task.ID, userID, chatID, messageID, task.FileName, lang, plan.PriorityWeight)
 	statusText := ""
-	if position < 0 {
+	var markup models.ReplyMarkup = utils.CancelKeyboard(lang, task.ID)
+	charged := intOption(task.Options, "credits_charged")
+	if err != nil {
+		log.Printf("Error enqueueing task %s: %v", task.ID, err)
+		bh.refundCredits(task)
+		if _, ok := task.Options["pdf_password"]; ok {
+			delete(task.Options, "pdf_password")
+			if err := bh.store.UpdateTask(task); err != nil {
+				log.Printf("Error clearing secrets for task %s: %v", task.ID, err)
+			}
+		}
+		statusText = messages.ErrorDefault(lang)
+		markup = nil
+	} else if position < 0 {
+		if charged > 0 && bh.billing != nil {
+			purchased := intOption(task.Options, "credits_purchased")
+			if err := bh.billing.Refund(userID, charged, purchased, task.ID+":duplicate"); err != nil {
+				log.Printf("Error refunding duplicate charge for task %s: %v", task.ID, err)
+			}
+		}
 		statusText = messages.QueueAlreadyQueued(lang, task.FileName)
 	} else if position > 0 {
 		statusText = messages.QueueQueued(lang, task.FileName, position)
 	} else {
 		statusText = messages.QueueStarted(lang, task.FileName)
 	}
+	if err == nil {
+		heavy := bh.scheduler.ClassifyTask(task) == types.ResourceHeavy
+		statusText = statusText + "\n" + messages.TaskTypeLine(lang, heavy)
+		if charged > 0 && position >= 0 {
+			statusText = statusText + "\n" + messages.CreditsCostLine(lang, charged) + "\n" + messages.CreditsRemainingLine(lang, remaining, plan.DailyCredits)
+		}
+	}
 	if priority {
 		if lang == i18n.RU {
 			statusText = statusText + "\n" + "Очередь: приоритетная"
@@ -270,20 +410,18 @@ func (bh *Handlers) HandleClickButton(ctx context.Context, b *bot.Bot, update *m
 		}
 	}
 
-	if update.CallbackQuery.Message.Message != nil {
-		msg := update.CallbackQuery.Message.Message
-
+	if messageID != 0 {
 		_, _ = b.EditMessageText(ctx, &bot.EditMessageTextParams{
-			ChatID:    msg.Chat.ID,
-			MessageID: msg.ID,
-			Text:      statusText,
-			ParseMode: messages.ParseModeHTML,
+			ChatID:      chatID,
+			MessageID:   messageID,
+			Text:        statusText,
+			ParseMode:   messages.ParseModeHTML,
+			ReplyMarkup: markup,
 		})
 	}
 
-	bh.removePendingSelection(userID, messageID, taskID)
-	bh.refreshPendingSelections(ctx, b, userID, lang, unlimited, remaining, messageID, taskID)
-	_ = bh.answerCallback(ctx, b, update.CallbackQuery.ID, "")
+	bh.removePendingSelection(userID, messageID, task.ID)
+	bh.refreshPendingSelections(ctx, b, userID, lang, plan.Unlimited, remaining, messageID, task.ID)
 }
 
 func (bh *Handlers) parseClickButtonData(data string) (format string, taskID string, err error) {
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"context"
+	"log"
+
+	"github.com/BatmanBruc/bat-bot-convetor/internal/i18n"
+	"github.com/BatmanBruc/bat-bot-convetor/internal/messages"
+	"github.com/BatmanBruc/bat-bot-convetor/types"
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+func (bh *Handlers) handleCancelTask(ctx context.Context, b *bot.Bot, update *models.Update, userID int64, lang i18n.Lang, taskID string) {
+	task, err := bh.store.GetTask(taskID)
+	if err != nil || task == nil {
+		_ = bh.answerCallback(ctx, b, update.CallbackQuery.ID, messages.CallbackTaskNotFound(lang))
+		return
+	}
+	if task.UserID != userID {
+		_ = bh.answerCallback(ctx, b, update.CallbackQuery.ID, messages.CallbackTaskNotInSession(lang))
+		return
+	}
+
+	msg := update.CallbackQuery.Message.Message
+	if task.State != types.StateProcessing {
+		if msg != nil {
+			_, _ = b.EditMessageReplyMarkup(ctx, &bot.EditMessageReplyMarkupParams{
+				ChatID:    msg.Chat.ID,
+				MessageID: msg.ID,
+				ReplyMarkup: &models.InlineKeyboardMarkup{
+					InlineKeyboard: [][]models.InlineKeyboardButton{},
+				},
+			})
+		}
+		_ = bh.answerCallback(ctx, b, update.CallbackQuery.ID, messages.CallbackTaskAlreadyFinished(lang))
+		return
+	}
+
+	task.State = types.StateCancelled
+	delete(task.Options, "pdf_password")
+	if err := bh.store.UpdateTask(task); err != nil {
+		log.Printf("Error cancelling task %s: %v", task.ID, err)
+		_ = bh.answerCallbackAlert(ctx, b, update.CallbackQuery.ID, messages.CallbackTaskUpdateFailed(lang))
+		return
+	}
+	removed, err := bh.scheduler.CancelTask(task.ID)
+	if err != nil {
+		log.Printf("Error removing task %s from queue: %v", task.ID, err)
+	} else if removed {
+		bh.refundCredits(task)
+	}
+
+	_ = bh.answerCallback(ctx, b, update.CallbackQuery.ID, "")
+	if msg != nil {
+		_, _ = b.EditMessageText(ctx, &bot.EditMessageTextParams{
+			ChatID:    msg.Chat.ID,
+			MessageID: msg.ID,
+			Text:      messages.TaskCancelled(lang, task.FileName),
+			ParseMode: messages.ParseModeHTML,
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+
+	"github.com/BatmanBruc/bat-bot-convetor/internal/billing"
+	"github.com/BatmanBruc/bat-bot-convetor/internal/i18n"
+	"github.com/BatmanBruc/bat-bot-convetor/internal/messages"
+	"github.com/BatmanBruc/bat-bot-convetor/types"
+)
+
+func (bh *Handlers) userPlan(userID int64) *types.Plan {
+	fallback := &types.Plan{Name: types.FreePlan, Title: "Free", PriorityWeight: 1}
+	if bh.billing == nil {
+		return fallback
+	}
+	plan, err := bh.billing.UserPlan(userID)
+	if err != nil || plan == nil {
+		log.Printf("Error loading plan for user %d: %v", userID, err)
+		return fallback
+	}
+	return plan
+}
+
+func (bh *Handlers) chargeCredits(lang i18n.Lang, plan *types.Plan, userID int64, tasks ...*types.Task) (remaining int, denial string, err error) {
+	if bh.billing == nil {
+		return 0, "", nil
+	}
+	total := 0
+	for _, t := range tasks {
+		switch billing.CheckPlan(plan, t) {
+		case billing.ErrFileTooLarge:
+			return 0, messages.CallbackFileTooLargeForPlan(lang, plan), nil
+		case billing.ErrCategoryNotAllowed:
+			return 0, messages.CallbackCategoryNotInPlan(lang, plan), nil
+		}
+		total += billing.Cost(t)
+	}
+	remaining, purchased, unlimited, err := bh.billing.Consume(userID, total, chargeRef(tasks))
+	if errors.Is(err, types.ErrInsufficientCredits) {
+		return remaining, messages.CallbackInsufficientCredits(lang, remaining, plan.DailyCredits), nil
+	}
+	if err != nil {
+		return 0, "", err
+	}
+	if unlimited {
+		return 0, "", nil
+	}
+	for _, t := range tasks {
+		if t.Options == nil {
+			t.Options = map[string]interface{}{}
+		}
+		cost := billing.Cost(t)
+		t.Options["credits_charged"] = cost
+		if fromPurchased := min(cost, purchased); fromPurchased > 0 {
+			t.Options["credits_purchased"] = fromPurchased
+			purchased -= fromPurchased
+		}
+	}
+	return remaining, "", nil
+}
+
+func (bh *Handlers) refundCredits(task *types.Task) {
+	credits := intOption(task.Options, "credits_charged")
+	if credits <= 0 || bh.billing == nil {
+		return
+	}
+	purchased := intOption(task.Options, "credits_purchased")
+	if err := bh.billing.Refund(task.UserID, credits, purchased, task.ID); err != nil {
+		log.Printf("Error refunding %d credits for task %s: %v", credits, task.ID, err)
+		return
+	}
+	delete(task.Options, "credits_charged")
+	delete(task.Options, "credits_purchased")
+	if err := bh.store.UpdateTask(task); err != nil {
+		log.Printf("Error saving refund for task %s: %v", task.ID, err)
+	}
+}
+
+func chargeRef(tasks []*types.Task) string {
+	if len(tasks) == 0 {
+		return ""
+	}
+	if len(tasks) == 1 && tasks[0].ID != "" {
+		return tasks[0].ID
+	}
+	parent, _ := tasks[0].Options["batch_parent_task"].(string)
+	return parent
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/BatmanBruc/bat-bot-convetor/internal/i18n"
+	"github.com/BatmanBruc/bat-bot-convetor/internal/messages"
+	"github.com/BatmanBruc/bat-bot-convetor/types"
+	"github.com/go-telegram/bot"
+)
+
+const deadLettersShown = 10
+
+func (bh *Handlers) handleDeadLetters(ctx context.Context, b *bot.Bot, chatID int64, lang i18n.Lang, args []string) {
+	text := ""
+	switch {
+	case len(args) == 0:
+		letters, err := bh.scheduler.DeadLetters(deadLettersShown)
+		if err != nil {
+			log.Printf("Error reading dead letters: %v", err)
+			text = messages.ErrorDefault(lang)
+		} else {
+			text = messages.AdminDeadLetters(lang, letters)
+		}
+	case len(args) == 2 && strings.EqualFold(args[0], "retry"):
+		taskID := strings.TrimSpace(args[1])
+		ok, err := bh.scheduler.RedriveTask(taskID)
+		if errors.Is(err, types.ErrRedriveNeedsPassword) {
+			text = messages.AdminRedriveNeedsPassword(lang, taskID)
+		} else if err != nil {
+			log.Printf("Error re-enqueueing dead task %s: %v", taskID, err)
+			text = messages.ErrorDefault(lang)
+		} else if !ok {
+			text = messages.AdminRedriveNotFound(lang, taskID)
+		} else {
+			text = messages.AdminRedriveDone(lang, taskID)
+		}
+	default:
+		text = messages.AdminDeadLettersUsage(lang)
+	}
+
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    chatID,
+		Text:      text,
+		ParseMode: messages.ParseModeHTML,
+	})
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"context"
+	"log"
 	"os"
 	"strconv"
 	"strings"
@@ -74,7 +75,7 @@ func (bh *Handlers) HandleCommand(ctx context.Context, b *bot.Bot, update *model
 			arg = "30"
 		}
 		if strings.EqualFold(arg, "forever") {
-			sub := types.Subscription{UserID: userID, Plan: "unlimited", Status: "active", ExpiresAt: nil}
+			sub := types.Subscription{UserID: userID, Plan: types.UnlimitedPlan, Status: "active", ExpiresAt: nil}
 			_ = bh.userStore.UpsertSubscription(sub)
 			b.SendMessage(ctx, &bot.SendMessageParams{
 				ChatID:    update.Message.Chat.ID,
@@ -92,7 +93,7 @@ func (bh *Handlers) HandleCommand(ctx context.Context, b *bot.Bot, update *model
 			})
 			return
 		}
-		sub, err := bh.userStore.ActivateOrExtendUnlimited(userID, time.Duration(days)*24*time.Hour)
+		sub, err := bh.userStore.ActivateOrExtendPlan(userID, types.UnlimitedPlan, time.Duration(days)*24*time.Hour)
 		if err != nil {
 			b.SendMessage(ctx, &bot.SendMessageParams{
 				ChatID:    update.Message.Chat.ID,
@@ -107,6 +108,71 @@ func (bh *Handlers) HandleCommand(ctx context.Context, b *bot.Bot, update *model
 			ParseMode: messages.ParseModeHTML,
 		})
 		return
+	case "/dead_letters":
+		if !isAdminUser(userID) {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:    update.Message.Chat.ID,
+				Text:      messages.ErrorUnknownCommand(lang),
+				ParseMode: messages.ParseModeHTML,
+			})
+			return
+		}
+		bh.handleDeadLetters(ctx, b, update.Message.Chat.ID, lang, fields[1:])
+		return
+	case "/promo_codes":
+		if !isAdminUser(userID) {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:    update.Message.Chat.ID,
+				Text:      messages.ErrorUnknownCommand(lang),
+				ParseMode: messages.ParseModeHTML,
+			})
+			return
+		}
+		bh.handlePromoCodes(ctx, b, update.Message.Chat.ID, userID, lang, fields[1:])
+		return
+	case "/promo":
+		bh.handlePromo(ctx, b, update.Message.Chat.ID, userID, lang, fields[1:])
+		return
+	case "/delivery":
+		arg := ""
+		if len(fields) >= 2 {
+			arg = strings.ToLower(strings.TrimSpace(fields[1]))
+		}
+		mode := ""
+		switch arg {
+		case "file":
+			mode = "file"
+		case "auto":
+		default:
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:    update.Message.Chat.ID,
+				Text:      messages.DeliveryUsage(lang, bh.deliveryMode(userID) == "file"),
+				ParseMode: messages.ParseModeHTML,
+			})
+			return
+		}
+		if bh.userStore == nil {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:    update.Message.Chat.ID,
+				Text:      messages.ErrorDefault(lang),
+				ParseMode: messages.ParseModeHTML,
+			})
+			return
+		}
+		if err := bh.userStore.SetDeliveryMode(userID, mode); err != nil {
+			log.Printf("Error saving delivery mode for user %d: %v", userID, err)
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:    update.Message.Chat.ID,
+				Text:      messages.ErrorDefault(lang),
+				ParseMode: messages.ParseModeHTML,
+			})
+			return
+		}
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:    update.Message.Chat.ID,
+			Text:      messages.DeliverySet(lang, arg == "file"),
+			ParseMode: messages.ParseModeHTML,
+		})
 	case "/start":
 		bh.sendMainMenu(ctx, b, update.Message.Chat.ID, lang)
 	case "/lang":
@@ -150,18 +216,21 @@ func (bh *Handlers) HandleCommand(ctx context.Context, b *bot.Bot, update *model
 		}
 	case "/balance":
 		text := messages.BalanceUnavailable(lang)
+		if bh.billing != nil {
+			balance, err := bh.billing.GetBalance(userID)
+			if err != nil {
+				log.Printf("Error loading balance for user %d: %v", userID, err)
+			} else {
+				text = messages.BalanceInfo(lang, bh.userPlan(userID), balance)
+			}
+		}
 		b.SendMessage(ctx, &bot.SendMessageParams{
 			ChatID:    update.Message.Chat.ID,
 			Text:      text,
 			ParseMode: messages.ParseModeHTML,
 		})
 	case "/subscribe":
-		unlimited := false
-		if bh.billing != nil {
-			u, _ := bh.billing.IsUnlimited(userID)
-			unlimited = u
-		}
-		text := messages.SubscriptionInfo(lang, unlimited) + "\n\n" + messages.MenuBtnContact(lang) + ": @esteticcus"
+		text := messages.SubscriptionInfo(lang, bh.userPlan(userID)) + "\n\n" + messages.MenuBtnContact(lang) + ": @esteticcus"
 		b.SendMessage(ctx, &bot.SendMessageParams{
 			ChatID:    update.Message.Chat.ID,
 			Text:      text,
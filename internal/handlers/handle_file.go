@@ -3,6 +3,7 @@ package handlers
 import (
 	"context"
 	"fmt"
+	"log"
 	"strings"
 	"time"
 
@@ -38,13 +39,23 @@ func (bh *Handlers) HandleFile(ctx context.Context, b *bot.Bot, update *models.U
 		return
 	}
 
+	if st, ok := options["img2pdf_state"].(string); ok && strings.TrimSpace(st) == "waiting" {
+		bh.handleImagesToPDFFile(ctx, b, userID, lang, filesInfo.Files)
+		return
+	}
+
+	if st, ok := options["vsub_state"].(string); ok && strings.TrimSpace(st) == "waiting" {
+		bh.handleVideoSubsFile(ctx, b, userID, lang, filesInfo.Files)
+		return
+	}
+
 	if st, ok := options["mb_state"].(string); ok && strings.TrimSpace(st) == "collect" {
 		bh.manualBatchAddFiles(ctx, b, userID, lang, filesInfo.Files)
 		return
 	}
 
 	for _, fi := range filesInfo.Files {
-		f := formats.BatchFile{FileID: fi.FileID, FileName: fi.FileName, FileSize: fi.FileSize}
+		f := formats.BatchFile{FileID: fi.FileID, FileName: fi.FileName, FileSize: fi.FileSize, Duration: fi.Duration}
 		bh.createAndAskFormatForSingleFile(ctx, b, userID, lang, f)
 	}
 }
@@ -128,6 +139,9 @@ func (bh *Handlers) createAndAskFormatForSingleFile(ctx context.Context, b *bot.
 		task.Options = map[string]interface{}{}
 	}
 	task.Options["lang"] = string(lang)
+	if f.Duration > 0 {
+		task.Options["duration"] = f.Duration
+	}
 	_ = bh.store.UpdateTask(task)
 	buttons := formats.GetButtonsForSourceExt(ext, task.ID, lang)
 	if len(buttons) == 0 {
@@ -159,17 +173,14 @@ func (bh *Handlers) handleBatchFormatSelection(ctx context.Context, b *bot.Bot,
 		_ = bh.answerCallbackAlert(ctx, b, update.CallbackQuery.ID, messages.CallbackInvalidButtonData(lang))
 		return
 	}
-	unlimited := false
-	if bh.billing != nil {
-		u, _ := bh.billing.IsUnlimited(userID)
-		unlimited = u
-	}
+	plan := bh.userPlan(userID)
 
 	chatID := getChatIDFromUpdate(update)
 	if chatID == 0 {
 		chatID = userID
 	}
 
+	tasks := make([]*types.Task, 0, len(files))
 	for _, f := range files {
 		task := &types.Task{
 			UserID:      userID,
@@ -181,13 +192,34 @@ func (bh *Handlers) handleBatchFormatSelection(ctx context.Context, b *bot.Bot,
 			Options: map[string]interface{}{
 				"file_size":         f.FileSize,
 				"lang":              string(lang),
-				"unlimited":         unlimited,
-				"priority":          unlimited,
+				"unlimited":         plan.Unlimited,
+				"priority":          plan.PriorityWeight > 1,
 				"batch_parent_task": batchTask.ID,
 			},
 		}
+		if mode := bh.deliveryMode(userID); mode != "" {
+			task.Options["delivery"] = mode
+		}
+		tasks = append(tasks, task)
+	}
+
+	_, denial, err := bh.chargeCredits(lang, plan, userID, tasks...)
+	if err != nil {
+		log.Printf("Error charging credits for batch %s: %v", batchTask.ID, err)
+		_ = bh.answerCallbackAlert(ctx, b, update.CallbackQuery.ID, messages.ErrorDefault(lang))
+		return
+	}
+	if denial != "" {
+		_ = bh.answerCallbackAlert(ctx, b, update.CallbackQuery.ID, denial)
+		return
+	}
+
+	for _, task := range tasks {
 		_ = bh.store.CreateTask(task)
-		bh.scheduler.EnqueueTask(task.ID, chatID, 0, task.FileName, lang, unlimited)
+		if _, err := bh.scheduler.EnqueueTask(task.ID, userID, chatID, 0, task.FileName, lang, plan.PriorityWeight); err != nil {
+			log.Printf("Error enqueueing task %s: %v", task.ID, err)
+			bh.refundCredits(task)
+		}
 	}
 
 	_ = bh.store.DeleteTask(batchTask.ID)
@@ -35,6 +35,14 @@ func (bh *Handlers) HandleText(ctx context.Context, b *bot.Bot, update *models.U
 
 	options, _ := bh.userState.GetUserOptions(userID)
 	if options != nil {
+		if st, ok := options["pdf_state"].(string); ok && strings.TrimSpace(st) == "await_pages" {
+			bh.handlePdfPagesText(ctx, b, userID, chatID, lang, options, text)
+			return
+		}
+		if st, ok := options["pdf_state"].(string); ok && strings.TrimSpace(st) == "await_password" {
+			bh.handlePdfPasswordText(ctx, b, userID, chatID, update.Message.ID, lang, options, update.Message.Text)
+			return
+		}
 		if st, ok := options["mb_state"].(string); ok && strings.TrimSpace(st) == "await_count" {
 			n, err := strconv.Atoi(strings.TrimSpace(text))
 			if err != nil || n <= 0 || n > 100 {
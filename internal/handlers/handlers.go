@@ -16,7 +16,11 @@ import (
 )
 
 type TaskEnqueuer interface {
-	EnqueueTask(taskID string, chatID int64, messageID int, fileName string, lang i18n.Lang, priority bool) int
+	EnqueueTask(taskID string, userID int64, chatID int64, messageID int, fileName string, lang i18n.Lang, weight int) (int, error)
+	CancelTask(taskID string) (bool, error)
+	ClassifyTask(task *types.Task) types.ResourceClass
+	DeadLetters(limit int) ([]*types.DeadLetter, error)
+	RedriveTask(taskID string) (bool, error)
 }
 
 type Handlers struct {
@@ -25,6 +29,7 @@ type Handlers struct {
 	scheduler TaskEnqueuer
 	userStore types.UserStore
 	billing   types.BillingStore
+	promos    types.PromoStore
 
 	batchMu     sync.Mutex
 	batchTimers map[string]*time.Timer
@@ -66,13 +71,25 @@ func (bh *Handlers) langFromUserOrCtx(ctx context.Context, userID int64) i18n.La
 	return i18n.EN
 }
 
-func NewHandlers(store types.TaskStore, userState types.UserStateStore, scheduler TaskEnqueuer, userStore types.UserStore, billing types.BillingStore) *Handlers {
+func (bh *Handlers) deliveryMode(userID int64) string {
+	if bh.userStore == nil {
+		return ""
+	}
+	user, err := bh.userStore.GetUser(userID)
+	if err != nil || user == nil {
+		return ""
+	}
+	return user.Delivery
+}
+
+func NewHandlers(store types.TaskStore, userState types.UserStateStore, scheduler TaskEnqueuer, userStore types.UserStore, billing types.BillingStore, promos types.PromoStore) *Handlers {
 	return &Handlers{
 		store:       store,
 		userState:   userState,
 		scheduler:   scheduler,
 		userStore:   userStore,
 		billing:     billing,
+		promos:      promos,
 		batchTimers: make(map[string]*time.Timer),
 		batchTaskID: make(map[string]string),
 	}
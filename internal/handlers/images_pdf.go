@@ -0,0 +1,422 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BatmanBruc/bat-bot-convetor/internal/contextkeys"
+	"github.com/BatmanBruc/bat-bot-convetor/internal/converter"
+	"github.com/BatmanBruc/bat-bot-convetor/internal/formats"
+	"github.com/BatmanBruc/bat-bot-convetor/internal/i18n"
+	"github.com/BatmanBruc/bat-bot-convetor/internal/messages"
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+const imagesToPDFDPI = 150
+
+type imagesToPDFSettings struct {
+	page        string
+	orientation string
+	marginMM    int
+}
+
+func imagesToPDFSettingsFromOptions(options map[string]interface{}) imagesToPDFSettings {
+	s := imagesToPDFSettings{page: "a4", orientation: "portrait"}
+	if v, ok := options["img2pdf_page"].(string); ok && (v == "a4" || v == "letter" || v == "fit") {
+		s.page = v
+	}
+	if v, ok := options["img2pdf_orient"].(string); ok && (v == "portrait" || v == "landscape") {
+		s.orientation = v
+	}
+	s.marginMM = intOption(options, "img2pdf_margin")
+	return s
+}
+
+func intOption(options map[string]interface{}, key string) int {
+	switch t := options[key].(type) {
+	case int:
+		return t
+	case int64:
+		return int(t)
+	case float64:
+		return int(t)
+	}
+	return 0
+}
+
+func imagesToPDFFileNames(options map[string]interface{}) []string {
+	names := []string{}
+	if arr, ok := options["img2pdf_files"].([]interface{}); ok {
+		for _, item := range arr {
+			if m, ok := item.(map[string]interface{}); ok {
+				if name, ok := m["file_name"].(string); ok {
+					names = append(names, name)
+				}
+			}
+		}
+	}
+	return names
+}
+
+func (bh *Handlers) imagesToPDFKeyboard(lang i18n.Lang, s imagesToPDFSettings) *models.InlineKeyboardMarkup {
+	mark := func(selected bool, text string) string {
+		if selected {
+			return "✅ " + text
+		}
+		return text
+	}
+
+	pageRow := []models.InlineKeyboardButton{}
+	for _, page := range []string{"a4", "letter", "fit"} {
+		pageRow = append(pageRow, models.InlineKeyboardButton{
+			Text:         mark(s.page == page, messages.ImagesToPDFPageLabel(lang, page)),
+			CallbackData: "img2pdf_page_" + page,
+		})
+	}
+	orientRow := []models.InlineKeyboardButton{}
+	for _, orient := range []string{"portrait", "landscape"} {
+		orientRow = append(orientRow, models.InlineKeyboardButton{
+			Text:         mark(s.orientation == orient, messages.ImagesToPDFOrientationLabel(lang, orient)),
+			CallbackData: "img2pdf_orient_" + orient,
+		})
+	}
+	marginRow := []models.InlineKeyboardButton{}
+	for _, margin := range []int{0, 10, 20} {
+		marginRow = append(marginRow, models.InlineKeyboardButton{
+			Text:         mark(s.marginMM == margin, messages.ImagesToPDFMarginLabel(lang, margin)),
+			CallbackData: "img2pdf_margin_" + strconv.Itoa(margin),
+		})
+	}
+
+	rows := [][]models.InlineKeyboardButton{pageRow}
+	if s.page != "fit" {
+		rows = append(rows, orientRow)
+	}
+	rows = append(rows, marginRow)
+	rows = append(rows, []models.InlineKeyboardButton{
+		{Text: messages.ImagesToPDFBtn(lang), CallbackData: "img2pdf_build"},
+	})
+	return &models.InlineKeyboardMarkup{InlineKeyboard: rows}
+}
+
+func (bh *Handlers) handleImagesToPDFFile(ctx context.Context, b *bot.Bot, userID int64, lang i18n.Lang, files []contextkeys.FileInfo) {
+	if len(files) == 0 {
+		return
+	}
+
+	options, _ := bh.userState.GetUserOptions(userID)
+	if options == nil {
+		options = map[string]interface{}{}
+	}
+
+	chatID := userID
+
+	for _, fi := range files {
+		if formats.GetCategoryByExtension(filepath.Ext(fi.FileName)) != "images" {
+			_, _ = b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:    chatID,
+				Text:      messages.ErrorUnsupportedFormat(lang),
+				ParseMode: messages.ParseModeHTML,
+			})
+			return
+		}
+	}
+
+	list := []interface{}{}
+	if arr, ok := options["img2pdf_files"].([]interface{}); ok {
+		list = arr
+	}
+	for _, fi := range files {
+		list = append(list, map[string]interface{}{
+			"file_id":   fi.FileID,
+			"file_name": fi.FileName,
+			"file_size": fi.FileSize,
+		})
+	}
+	options["img2pdf_files"] = list
+
+	if oldMsgID := intOption(options, "img2pdf_msg_id"); oldMsgID > 0 {
+		_, err := b.DeleteMessage(ctx, &bot.DeleteMessageParams{
+			ChatID:    chatID,
+			MessageID: oldMsgID,
+		})
+		if err != nil {
+			log.Printf("Error deleting images-to-PDF message %d: %v", oldMsgID, err)
+		}
+	}
+
+	_ = bh.userState.SetUserOptions(userID, options)
+
+	s := imagesToPDFSettingsFromOptions(options)
+	sent, err := b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:      chatID,
+		Text:        messages.ImagesToPDFFilesList(lang, imagesToPDFFileNames(options), s.page, s.orientation, s.marginMM),
+		ParseMode:   messages.ParseModeHTML,
+		ReplyMarkup: bh.imagesToPDFKeyboard(lang, s),
+	})
+	if err != nil {
+		log.Printf("Error sending images-to-PDF message: %v", err)
+		return
+	}
+	options["img2pdf_msg_id"] = sent.ID
+	_ = bh.userState.SetUserOptions(userID, options)
+}
+
+func (bh *Handlers) handleImagesToPDFClick(ctx context.Context, b *bot.Bot, update *models.Update, userID int64, lang i18n.Lang, data string) {
+	options, _ := bh.userState.GetUserOptions(userID)
+	if options == nil {
+		_ = bh.answerCallbackAlert(ctx, b, update.CallbackQuery.ID, messages.CallbackTaskNotFound(lang))
+		return
+	}
+	if st, ok := options["img2pdf_state"].(string); !ok || st != "waiting" {
+		_ = bh.answerCallbackAlert(ctx, b, update.CallbackQuery.ID, messages.CallbackTaskNotFound(lang))
+		return
+	}
+
+	chatID := getChatIDFromUpdate(update)
+	if chatID == 0 {
+		chatID = userID
+	}
+
+	if data == "img2pdf_build" {
+		bh.handleImagesToPDFBuild(ctx, b, update, userID, chatID, lang, options)
+		return
+	}
+
+	setting := strings.TrimPrefix(data, "img2pdf_")
+	switch {
+	case strings.HasPrefix(setting, "page_"):
+		options["img2pdf_page"] = strings.TrimPrefix(setting, "page_")
+	case strings.HasPrefix(setting, "orient_"):
+		options["img2pdf_orient"] = strings.TrimPrefix(setting, "orient_")
+	case strings.HasPrefix(setting, "margin_"):
+		margin, err := strconv.Atoi(strings.TrimPrefix(setting, "margin_"))
+		if err != nil || margin < 0 || margin > 50 {
+			_ = bh.answerCallback(ctx, b, update.CallbackQuery.ID, messages.CallbackInvalidButtonData(lang))
+			return
+		}
+		options["img2pdf_margin"] = margin
+	default:
+		_ = bh.answerCallback(ctx, b, update.CallbackQuery.ID, messages.CallbackInvalidButtonData(lang))
+		return
+	}
+	_ = bh.userState.SetUserOptions(userID, options)
+	_ = bh.answerCallback(ctx, b, update.CallbackQuery.ID, "")
+
+	if update.CallbackQuery.Message.Message == nil {
+		return
+	}
+	s := imagesToPDFSettingsFromOptions(options)
+	_, _ = b.EditMessageText(ctx, &bot.EditMessageTextParams{
+		ChatID:      chatID,
+		MessageID:   update.CallbackQuery.Message.Message.ID,
+		Text:        messages.ImagesToPDFFilesList(lang, imagesToPDFFileNames(options), s.page, s.orientation, s.marginMM),
+		ParseMode:   messages.ParseModeHTML,
+		ReplyMarkup: bh.imagesToPDFKeyboard(lang, s),
+	})
+}
+
+func (bh *Handlers) handleImagesToPDFBuild(ctx context.Context, b *bot.Bot, update *models.Update, userID int64, chatID int64, lang i18n.Lang, options map[string]interface{}) {
+	fileInfos := []contextkeys.FileInfo{}
+	if arr, ok := options["img2pdf_files"].([]interface{}); ok {
+		for _, item := range arr {
+			if m, ok := item.(map[string]interface{}); ok {
+				fileID, _ := m["file_id"].(string)
+				fileName, _ := m["file_name"].(string)
+				fileSize, _ := m["file_size"].(float64)
+				fileInfos = append(fileInfos, contextkeys.FileInfo{
+					FileID:   fileID,
+					FileName: fileName,
+					FileSize: int64(fileSize),
+				})
+			}
+		}
+	}
+	if len(fileInfos) == 0 {
+		_ = bh.answerCallbackAlert(ctx, b, update.CallbackQuery.ID, messages.CallbackInvalidAction(lang))
+		return
+	}
+
+	_ = bh.answerCallback(ctx, b, update.CallbackQuery.ID, "")
+
+	if msgID := intOption(options, "img2pdf_msg_id"); msgID > 0 {
+		_, err := b.DeleteMessage(ctx, &bot.DeleteMessageParams{
+			ChatID:    chatID,
+			MessageID: msgID,
+		})
+		if err != nil {
+			log.Printf("Error deleting images-to-PDF message %d: %v", msgID, err)
+		}
+	}
+
+	s := imagesToPDFSettingsFromOptions(options)
+	delete(options, "img2pdf_state")
+	delete(options, "img2pdf_files")
+	delete(options, "img2pdf_msg_id")
+	_ = bh.userState.SetUserOptions(userID, options)
+
+	_, _ = b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    chatID,
+		Text:      messages.ImagesToPDFStarted(lang),
+		ParseMode: messages.ParseModeHTML,
+	})
+
+	go bh.processImagesToPDF(b, userID, chatID, lang, fileInfos, s)
+}
+
+func (bh *Handlers) processImagesToPDF(b *bot.Bot, userID int64, chatID int64, lang i18n.Lang, fileInfos []contextkeys.FileInfo, s imagesToPDFSettings) {
+	ctx, cancel := context.WithTimeout(context.Background(), 12*time.Minute)
+	defer cancel()
+
+	log.Printf("Starting images-to-PDF for user %d with %d images", userID, len(fileInfos))
+
+	sendError := func() {
+		_, _ = b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:    chatID,
+			Text:      messages.ImagesToPDFError(lang),
+			ParseMode: messages.ParseModeHTML,
+		})
+	}
+
+	tempDir := tempWorkDir()
+	imagePaths := make([]string, 0, len(fileInfos))
+	defer func() {
+		for _, path := range imagePaths {
+			_ = os.Remove(path)
+		}
+	}()
+
+	downloadClient := newDownloadClient()
+	for i, fi := range fileInfos {
+		ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(fi.FileName), "."))
+		safeName := fmt.Sprintf("img2pdf_%03d.%s", i+1, ext)
+		path, err := bh.downloadFile(ctx, downloadClient, b, fi.FileID, tempDir, safeName)
+		if err != nil {
+			log.Printf("Error downloading image %q: %v", fi.FileName, err)
+			sendError()
+			return
+		}
+		imagePaths = append(imagePaths, path)
+	}
+
+	outputPath := filepath.Join(tempDir, fmt.Sprintf("images_%d_%s.pdf", userID, time.Now().Format("20060102_150405")))
+	defer os.Remove(outputPath)
+
+	if err := buildImagesPDF(ctx, imagePaths, outputPath, s); err != nil {
+		log.Printf("Error building PDF from images for user %d: %v", userID, err)
+		sendError()
+		return
+	}
+
+	file, err := os.Open(outputPath)
+	if err != nil {
+		log.Printf("Error opening images PDF: %v", err)
+		sendError()
+		return
+	}
+	defer file.Close()
+
+	_, err = b.SendDocument(ctx, &bot.SendDocumentParams{
+		ChatID: chatID,
+		Document: &models.InputFileUpload{
+			Filename: "images.pdf",
+			Data:     file,
+		},
+		Caption:   messages.ImagesToPDFSuccess(lang),
+		ParseMode: messages.ParseModeHTML,
+	})
+	if err != nil {
+		log.Printf("Error sending images PDF: %v", err)
+	} else {
+		log.Printf("Successfully sent images PDF to user %d", userID)
+	}
+}
+
+func buildImagesPDF(ctx context.Context, imagePaths []string, outputPath string, s imagesToPDFSettings) error {
+	if _, err := exec.LookPath("img2pdf"); err == nil && img2pdfCanRead(imagePaths) {
+		args := []string{"--output", outputPath}
+		switch s.page {
+		case "fit":
+			if s.marginMM > 0 {
+				args = append(args, "--border", fmt.Sprintf("%dmm", s.marginMM))
+			}
+		default:
+			size := "A4"
+			if s.page == "letter" {
+				size = "Letter"
+			}
+			if s.orientation == "landscape" {
+				size += "^T"
+			}
+			args = append(args, "--pagesize", size, "--fit", "into")
+			if s.marginMM > 0 {
+				args = append(args, "--border", fmt.Sprintf("%dmm", s.marginMM))
+			}
+		}
+		args = append(args, imagePaths...)
+		output, err := converter.CommandContext(ctx, "img2pdf", args...).CombinedOutput()
+		if err == nil {
+			return nil
+		}
+		log.Printf("img2pdf failed, falling back to ImageMagick: %v, output: %s", err, string(output))
+	}
+
+	cmdName := "magick"
+	if _, err := exec.LookPath(cmdName); err != nil {
+		cmdName = "convert"
+		if _, err := exec.LookPath(cmdName); err != nil {
+			return fmt.Errorf("neither img2pdf nor ImageMagick is available")
+		}
+	}
+
+	margin := s.marginMM * imagesToPDFDPI * 10 / 254
+	args := append([]string{}, imagePaths...)
+	args = append(args, "-auto-orient", "-background", "white", "-alpha", "remove", "-alpha", "off")
+	if s.page == "fit" {
+		if margin > 0 {
+			args = append(args, "-bordercolor", "white", "-border", strconv.Itoa(margin))
+		}
+	} else {
+		w, h := 1240, 1754
+		if s.page == "letter" {
+			w, h = 1275, 1650
+		}
+		if s.orientation == "landscape" {
+			w, h = h, w
+		}
+		args = append(args,
+			"-resize", fmt.Sprintf("%dx%d", w-2*margin, h-2*margin),
+			"-gravity", "center",
+			"-extent", fmt.Sprintf("%dx%d", w, h),
+		)
+	}
+	args = append(args, "-units", "PixelsPerInch", "-density", strconv.Itoa(imagesToPDFDPI), outputPath)
+
+	output, err := converter.CommandContext(ctx, cmdName, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("ImageMagick failed: %v, output: %s", err, string(output))
+	}
+	if _, err := os.Stat(outputPath); err != nil {
+		return fmt.Errorf("output file was not created: %v", err)
+	}
+	return nil
+}
+
+func img2pdfCanRead(paths []string) bool {
+	for _, p := range paths {
+		switch strings.ToLower(strings.TrimPrefix(filepath.Ext(p), ".")) {
+		case "jpg", "jpeg", "png", "tif", "tiff", "jp2", "gif", "bmp":
+		default:
+			return false
+		}
+	}
+	return true
+}
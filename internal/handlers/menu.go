@@ -9,6 +9,7 @@ import (
 	"github.com/BatmanBruc/bat-bot-convetor/internal/formats"
 	"github.com/BatmanBruc/bat-bot-convetor/internal/i18n"
 	"github.com/BatmanBruc/bat-bot-convetor/internal/messages"
+	"github.com/BatmanBruc/bat-bot-convetor/types"
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
 )
@@ -22,9 +23,15 @@ func (bh *Handlers) buildMenuKeyboard(lang i18n.Lang, withBack bool) models.Inli
 	rows = append(rows, []models.InlineKeyboardButton{
 		{Text: pad(messages.MenuBtnMergePDF(lang)), CallbackData: "menu_merge_pdf"},
 	})
+	rows = append(rows, []models.InlineKeyboardButton{
+		{Text: pad(messages.MenuBtnImagesToPDF(lang)), CallbackData: "menu_img2pdf"},
+	})
 	rows = append(rows, []models.InlineKeyboardButton{
 		{Text: pad(messages.MenuBtnSubscription(lang)), CallbackData: "menu_sub"},
 	})
+	rows = append(rows, []models.InlineKeyboardButton{
+		{Text: pad(messages.MenuBtnCredits(lang)), CallbackData: "menu_packs"},
+	})
 	rows = append(rows, []models.InlineKeyboardButton{
 		{Text: pad(messages.MenuBtnContact(lang)), URL: "https://t.me/esteticcus"},
 	})
@@ -74,7 +81,8 @@ func (bh *Handlers) HandleMenuClick(ctx context.Context, b *bot.Bot, update *mod
 	text := messages.MainMenuText(lang)
 	keyboard := bh.buildMenuKeyboard(lang, false)
 
-	switch data {
+	action, itemName, _ := strings.Cut(data, ":")
+	switch action {
 	case "menu_batch":
 		options, _ := bh.userState.GetUserOptions(userID)
 		if options == nil {
@@ -102,6 +110,8 @@ func (bh *Handlers) HandleMenuClick(ctx context.Context, b *bot.Bot, update *mod
 		delete(options, "merge_state")
 		delete(options, "merge_files")
 		delete(options, "merge_msg_id")
+		delete(options, "img2pdf_state")
+		delete(options, "vsub_state")
 		options["merge_state"] = "waiting"
 		_ = bh.userState.SetUserOptions(userID, options)
 		_ = bh.answerCallback(ctx, b, update.CallbackQuery.ID, "")
@@ -111,81 +121,128 @@ func (bh *Handlers) HandleMenuClick(ctx context.Context, b *bot.Bot, update *mod
 			ParseMode: messages.ParseModeHTML,
 		})
 		return
+	case "menu_img2pdf":
+		options, _ := bh.userState.GetUserOptions(userID)
+		if options == nil {
+			options = map[string]interface{}{}
+		}
+		delete(options, "merge_state")
+		delete(options, "img2pdf_files")
+		delete(options, "img2pdf_msg_id")
+		delete(options, "vsub_state")
+		options["img2pdf_state"] = "waiting"
+		_ = bh.userState.SetUserOptions(userID, options)
+		_ = bh.answerCallback(ctx, b, update.CallbackQuery.ID, "")
+		_, _ = b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:    chatID,
+			Text:      messages.ImagesToPDFWaiting(lang),
+			ParseMode: messages.ParseModeHTML,
+		})
+		return
 	case "menu_sub":
-		active := false
+		plan := bh.userPlan(userID)
+		active := plan.Name != types.FreePlan
 		var expiresAt *time.Time
-		if bh.userStore != nil {
-			sub, err := bh.userStore.GetSubscription(userID)
-			if err == nil && sub != nil {
-				if strings.EqualFold(strings.TrimSpace(sub.Status), "active") && strings.EqualFold(strings.TrimSpace(sub.Plan), "unlimited") {
-					if sub.ExpiresAt == nil || sub.ExpiresAt.After(time.Now()) {
-						active = true
-						expiresAt = sub.ExpiresAt
-					}
-				}
+		if active && bh.userStore != nil {
+			if sub, err := bh.userStore.GetSubscription(userID); err == nil && sub != nil {
+				expiresAt = sub.ExpiresAt
 			}
-		} else if bh.billing != nil {
-			u, _ := bh.billing.IsUnlimited(userID)
-			active = u
 		}
+		plans := bh.purchasablePlans()
 
 		btnPad := func(s string) string { return "   " + s + "   " }
 		if active {
-			text = messages.SubscriptionActiveDetails(lang, expiresAt)
-			rows := [][]models.InlineKeyboardButton{
-				{
-					{Text: btnPad(messages.MenuBtnSubscribeNow(lang, true)), CallbackData: "menu_pay"},
-				},
-				{
-					{Text: btnPad(messages.MenuBtnBack(lang)), CallbackData: "menu_back"},
-				},
-			}
-			keyboard = models.InlineKeyboardMarkup{InlineKeyboard: rows}
+			text = messages.SubscriptionActiveDetails(lang, plan, expiresAt)
 		} else {
-			text = messages.SubscriptionOffer(lang)
-			rows := [][]models.InlineKeyboardButton{
-				{
-					{Text: btnPad(messages.MenuBtnSubscribeNow(lang, false)), CallbackData: "menu_pay"},
-				},
-				{
-					{Text: btnPad(messages.MenuBtnBack(lang)), CallbackData: "menu_back"},
-				},
+			text = messages.SubscriptionOffer(lang, plans)
+		}
+		rows := make([][]models.InlineKeyboardButton, 0, len(plans)+1)
+		for i := range plans {
+			label := messages.MenuBtnPlan(&plans[i])
+			if active && plans[i].Name == plan.Name {
+				label = messages.MenuBtnSubscribeNow(lang, true)
 			}
-			keyboard = models.InlineKeyboardMarkup{InlineKeyboard: rows}
+			rows = append(rows, []models.InlineKeyboardButton{
+				{Text: btnPad(label), CallbackData: "menu_pay:" + plans[i].Name},
+			})
 		}
+		rows = append(rows, []models.InlineKeyboardButton{
+			{Text: btnPad(messages.MenuBtnBack(lang)), CallbackData: "menu_back"},
+		})
+		keyboard = models.InlineKeyboardMarkup{InlineKeyboard: rows}
 	case "menu_pay":
-		text = messages.PayMethodTitle(lang)
-		btnPad := func(s string) string { return "   " + s + "   " }
-		rows := [][]models.InlineKeyboardButton{
-			{
-				{Text: btnPad(messages.PayBtnStars(lang)), CallbackData: "menu_pay_stars"},
-			},
-			{
-				{Text: btnPad(messages.PayBtnYooKassa(lang)), CallbackData: "menu_pay_yk"},
-			},
-			{
-				{Text: btnPad(messages.MenuBtnBack(lang)), CallbackData: "menu_sub"},
-			},
+		plan := bh.menuPlan(itemName)
+		if plan == nil {
+			_ = bh.answerCallback(ctx, b, update.CallbackQuery.ID, messages.PaymentNotConfigured(lang))
+			return
 		}
-		keyboard = models.InlineKeyboardMarkup{InlineKeyboard: rows}
-	case "menu_pay_stars":
-		ok := bh.sendSubscriptionInvoiceStars(ctx, b, msg.Chat.ID, lang)
-		if ok {
-			_ = bh.answerCallback(ctx, b, update.CallbackQuery.ID, messages.PaymentCreated(lang))
-		} else {
+		text = messages.PayMethodTitle(lang) + "\n\n<b>" + messages.Escape(plan.Title) + "</b> — " + messages.PriceList(plan.Prices)
+		keyboard = payMethodKeyboard(lang, plan.Prices, "menu_pay_stars:"+plan.Name, "menu_pay_yk:"+plan.Name, "menu_sub")
+		if bh.promos != nil && plan.DurationDays > 0 {
+			btnPad := func(s string) string { return "   " + s + "   " }
+			rows := keyboard.InlineKeyboard
+			back := rows[len(rows)-1]
+			keyboard.InlineKeyboard = append(rows[:len(rows)-1], []models.InlineKeyboardButton{
+				{Text: btnPad(messages.MenuBtnGift(lang)), CallbackData: "menu_gift:" + plan.Name},
+			}, back)
+		}
+	case "menu_gift":
+		plan := bh.menuPlan(itemName)
+		if plan == nil || bh.promos == nil {
 			_ = bh.answerCallback(ctx, b, update.CallbackQuery.ID, messages.PaymentNotConfigured(lang))
+			return
 		}
+		text = messages.GiftMethodTitle(lang) + "\n\n<b>" + messages.Escape(plan.Title) + "</b> — " + messages.PriceList(plan.Prices)
+		keyboard = payMethodKeyboard(lang, plan.Prices, "menu_gift_stars:"+plan.Name, "menu_gift_yk:"+plan.Name, "menu_pay:"+plan.Name)
+	case "menu_gift_stars", "menu_gift_yk":
+		ok := false
+		if plan := bh.menuPlan(itemName); plan != nil && bh.promos != nil {
+			ok = bh.sendGiftInvoice(ctx, b, msg.Chat.ID, lang, plan, menuCurrency(action))
+		}
+		bh.answerInvoiceSent(ctx, b, update.CallbackQuery.ID, lang, ok)
 		return
-	case "menu_pay_yk":
-		ok := bh.sendSubscriptionInvoiceYooKassa(ctx, b, msg.Chat.ID, lang)
-		if ok {
-			_ = bh.answerCallback(ctx, b, update.CallbackQuery.ID, messages.PaymentCreated(lang))
-		} else {
+	case "menu_pay_stars", "menu_pay_yk":
+		ok := false
+		if plan := bh.menuPlan(itemName); plan != nil {
+			ok = bh.sendPlanInvoice(ctx, b, msg.Chat.ID, lang, plan, menuCurrency(action))
+		}
+		bh.answerInvoiceSent(ctx, b, update.CallbackQuery.ID, lang, ok)
+		return
+	case "menu_packs":
+		packs := bh.creditPacks()
+		text = messages.CreditPacksOffer(lang, packs)
+		btnPad := func(s string) string { return "   " + s + "   " }
+		rows := make([][]models.InlineKeyboardButton, 0, len(packs)+1)
+		for i := range packs {
+			rows = append(rows, []models.InlineKeyboardButton{
+				{Text: btnPad(messages.MenuBtnPack(&packs[i])), CallbackData: "menu_buy:" + packs[i].Name},
+			})
+		}
+		rows = append(rows, []models.InlineKeyboardButton{
+			{Text: btnPad(messages.MenuBtnBack(lang)), CallbackData: "menu_back"},
+		})
+		keyboard = models.InlineKeyboardMarkup{InlineKeyboard: rows}
+	case "menu_buy":
+		pack := bh.lookupPack(itemName)
+		if pack == nil {
 			_ = bh.answerCallback(ctx, b, update.CallbackQuery.ID, messages.PaymentNotConfigured(lang))
+			return
+		}
+		text = messages.PayMethodTitle(lang) + "\n\n<b>" + messages.Escape(pack.Title) + "</b> — " + messages.PriceList(pack.Prices)
+		keyboard = payMethodKeyboard(lang, pack.Prices, "menu_buy_stars:"+pack.Name, "menu_buy_yk:"+pack.Name, "menu_packs")
+	case "menu_buy_stars", "menu_buy_yk":
+		ok := false
+		if pack := bh.lookupPack(itemName); pack != nil {
+			ok = bh.sendPackInvoice(ctx, b, msg.Chat.ID, lang, pack, menuCurrency(action))
 		}
+		bh.answerInvoiceSent(ctx, b, update.CallbackQuery.ID, lang, ok)
 		return
 	case "menu_about":
-		text = formats.GetHelpMessage(lang) + "\n\n" + messages.AboutCreditsBlock(lang)
+		freeCredits := 0
+		if free := bh.lookupPlan(types.FreePlan); free != nil {
+			freeCredits = free.DailyCredits
+		}
+		text = formats.GetHelpMessage(lang) + "\n\n" + messages.AboutCreditsBlock(lang, freeCredits)
 		keyboard = bh.buildMenuKeyboard(lang, true)
 	case "menu_back":
 	default:
@@ -202,3 +259,44 @@ func (bh *Handlers) HandleMenuClick(ctx context.Context, b *bot.Bot, update *mod
 		},
 	})
 }
+
+func (bh *Handlers) menuPlan(name string) *types.Plan {
+	if strings.TrimSpace(name) == "" {
+		name = types.UnlimitedPlan
+	}
+	return bh.lookupPlan(name)
+}
+
+func menuCurrency(action string) string {
+	if strings.HasSuffix(action, "_yk") {
+		return "RUB"
+	}
+	return "XTR"
+}
+
+func payMethodKeyboard(lang i18n.Lang, prices types.Prices, starsData string, yooKassaData string, backData string) models.InlineKeyboardMarkup {
+	btnPad := func(s string) string { return "   " + s + "   " }
+	rows := make([][]models.InlineKeyboardButton, 0, 3)
+	if _, ok := prices.Amount("XTR"); ok {
+		rows = append(rows, []models.InlineKeyboardButton{
+			{Text: btnPad(messages.PayBtnStars(lang)), CallbackData: starsData},
+		})
+	}
+	if _, ok := prices.Amount("RUB"); ok {
+		rows = append(rows, []models.InlineKeyboardButton{
+			{Text: btnPad(messages.PayBtnYooKassa(lang)), CallbackData: yooKassaData},
+		})
+	}
+	rows = append(rows, []models.InlineKeyboardButton{
+		{Text: btnPad(messages.MenuBtnBack(lang)), CallbackData: backData},
+	})
+	return models.InlineKeyboardMarkup{InlineKeyboard: rows}
+}
+
+func (bh *Handlers) answerInvoiceSent(ctx context.Context, b *bot.Bot, callbackID string, lang i18n.Lang, ok bool) {
+	if ok {
+		_ = bh.answerCallback(ctx, b, callbackID, messages.PaymentCreated(lang))
+	} else {
+		_ = bh.answerCallback(ctx, b, callbackID, messages.PaymentNotConfigured(lang))
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"time"
 
 	"github.com/BatmanBruc/bat-bot-convetor/internal/contextkeys"
+	"github.com/BatmanBruc/bat-bot-convetor/internal/converter"
 	"github.com/BatmanBruc/bat-bot-convetor/internal/i18n"
 	"github.com/BatmanBruc/bat-bot-convetor/internal/messages"
 	"github.com/go-telegram/bot"
@@ -239,6 +240,35 @@ func (bh *Handlers) downloadFile(ctx context.Context, client *http.Client, b *bo
 	return destPath, nil
 }
 
+func newDownloadClient() *http.Client {
+	return &http.Client{
+		Timeout: 10 * time.Minute,
+		Transport: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			DialContext: (&net.Dialer{
+				Timeout:   30 * time.Second,
+				KeepAlive: 30 * time.Second,
+			}).DialContext,
+			ForceAttemptHTTP2:     true,
+			MaxIdleConns:          100,
+			MaxIdleConnsPerHost:   10,
+			MaxConnsPerHost:       10,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: 1 * time.Second,
+		},
+	}
+}
+
+func tempWorkDir() string {
+	tempDir := strings.TrimSpace(os.Getenv("TMPDIR"))
+	if tempDir == "" {
+		tempDir = os.TempDir()
+	}
+	_ = os.MkdirAll(tempDir, 0755)
+	return tempDir
+}
+
 func (bh *Handlers) processMergePDF(b *bot.Bot, userID int64, chatID int64, lang i18n.Lang, fileInfos []contextkeys.FileInfo) {
 	ctx, cancel := context.WithTimeout(context.Background(), 12*time.Minute)
 	defer cancel()
@@ -250,11 +280,7 @@ func (bh *Handlers) processMergePDF(b *bot.Bot, userID int64, chatID int64, lang
 		return
 	}
 
-	tempDir := strings.TrimSpace(os.Getenv("TMPDIR"))
-	if tempDir == "" {
-		tempDir = os.TempDir()
-	}
-	_ = os.MkdirAll(tempDir, 0755)
+	tempDir := tempWorkDir()
 	pdfPaths := make([]string, len(fileInfos))
 	defer func() {
 		for _, path := range pdfPaths {
@@ -264,23 +290,7 @@ func (bh *Handlers) processMergePDF(b *bot.Bot, userID int64, chatID int64, lang
 		}
 	}()
 
-	downloadClient := &http.Client{
-		Timeout: 10 * time.Minute,
-		Transport: &http.Transport{
-			Proxy: http.ProxyFromEnvironment,
-			DialContext: (&net.Dialer{
-				Timeout:   30 * time.Second,
-				KeepAlive: 30 * time.Second,
-			}).DialContext,
-			ForceAttemptHTTP2:     true,
-			MaxIdleConns:          100,
-			MaxIdleConnsPerHost:   10,
-			MaxConnsPerHost:       10,
-			IdleConnTimeout:       90 * time.Second,
-			TLSHandshakeTimeout:   10 * time.Second,
-			ExpectContinueTimeout: 1 * time.Second,
-		},
-	}
+	downloadClient := newDownloadClient()
 
 	g, gctx := errgroup.WithContext(ctx)
 	sem := make(chan struct{}, 3)
@@ -346,7 +356,7 @@ func (bh *Handlers) processMergePDF(b *bot.Bot, userID int64, chatID int64, lang
 
 	if _, err := exec.LookPath("pdftk"); err == nil {
 		args := append(pdfPaths, "cat", "output", outputPath)
-		cmd = exec.Command("pdftk", args...)
+		cmd = converter.CommandContext(ctx, "pdftk", args...)
 		toolName = "pdftk"
 		log.Printf("Using pdftk with args: %v", args)
 	} else if _, err := exec.LookPath("qpdf"); err == nil {
@@ -355,7 +365,7 @@ func (bh *Handlers) processMergePDF(b *bot.Bot, userID int64, chatID int64, lang
 			args = append(args, path, "1-z")
 		}
 		args = append(args, "--", outputPath)
-		cmd = exec.Command("qpdf", args...)
+		cmd = converter.CommandContext(ctx, "qpdf", args...)
 		toolName = "qpdf"
 		log.Printf("Using qpdf with args: %v", args)
 	} else {
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"github.com/BatmanBruc/bat-bot-convetor/internal/converter"
+	"github.com/BatmanBruc/bat-bot-convetor/internal/i18n"
+	"github.com/BatmanBruc/bat-bot-convetor/internal/messages"
+	"github.com/BatmanBruc/bat-bot-convetor/types"
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+func (bh *Handlers) askPdfPages(ctx context.Context, b *bot.Bot, update *models.Update, userID int64, lang i18n.Lang, taskID string, op string) {
+	bh.awaitPdfInput(ctx, b, update, userID, lang, taskID, op, "await_pages", func(fileName string) string {
+		return messages.PdfPagesPrompt(lang, op, fileName)
+	})
+}
+
+func (bh *Handlers) askPdfPassword(ctx context.Context, b *bot.Bot, update *models.Update, userID int64, lang i18n.Lang, taskID string, op string) {
+	bh.awaitPdfInput(ctx, b, update, userID, lang, taskID, op, "await_password", func(fileName string) string {
+		return messages.PdfPasswordPrompt(lang, op, fileName)
+	})
+}
+
+func (bh *Handlers) awaitPdfInput(ctx context.Context, b *bot.Bot, update *models.Update, userID int64, lang i18n.Lang, taskID string, op string, state string, prompt func(fileName string) string) {
+	task, err := bh.store.GetTask(taskID)
+	if err != nil || task == nil {
+		_ = bh.answerCallback(ctx, b, update.CallbackQuery.ID, messages.CallbackTaskNotFound(lang))
+		return
+	}
+	if task.UserID != userID {
+		_ = bh.answerCallback(ctx, b, update.CallbackQuery.ID, messages.CallbackTaskNotInSession(lang))
+		return
+	}
+	if task.State != types.StateChooseExt {
+		_ = bh.answerCallback(ctx, b, update.CallbackQuery.ID, messages.CallbackTaskAlreadyStarted(lang))
+		return
+	}
+	if update.CallbackQuery.Message.Message == nil {
+		_ = bh.answerCallback(ctx, b, update.CallbackQuery.ID, messages.CallbackInvalidAction(lang))
+		return
+	}
+	msg := update.CallbackQuery.Message.Message
+
+	options, _ := bh.userState.GetUserOptions(userID)
+	if options == nil {
+		options = map[string]interface{}{}
+	}
+	options["pdf_state"] = state
+	options["pdf_task_id"] = task.ID
+	options["pdf_op"] = op
+	options["pdf_msg_id"] = msg.ID
+	_ = bh.userState.SetUserOptions(userID, options)
+
+	_ = bh.answerCallback(ctx, b, update.CallbackQuery.ID, "")
+	_, _ = b.EditMessageText(ctx, &bot.EditMessageTextParams{
+		ChatID:    msg.Chat.ID,
+		MessageID: msg.ID,
+		Text:      prompt(task.FileName),
+		ParseMode: messages.ParseModeHTML,
+	})
+}
+
+func (bh *Handlers) handlePdfPagesText(ctx context.Context, b *bot.Bot, userID int64, chatID int64, lang i18n.Lang, options map[string]interface{}, text string) {
+	if _, err := converter.ParsePageRanges(text); err != nil {
+		_, _ = b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:    chatID,
+			Text:      messages.PdfPagesInvalid(lang),
+			ParseMode: messages.ParseModeHTML,
+		})
+		return
+	}
+	bh.startPdfTask(ctx, b, userID, chatID, lang, options, map[string]interface{}{
+		"pdf_pages": strings.TrimSpace(text),
+	})
+}
+
+func (bh *Handlers) handlePdfPasswordText(ctx context.Context, b *bot.Bot, userID int64, chatID int64, messageID int, lang i18n.Lang, options map[string]interface{}, text string) {
+	_, _ = b.DeleteMessage(ctx, &bot.DeleteMessageParams{
+		ChatID:    chatID,
+		MessageID: messageID,
+	})
+
+	if len(text) > 128 || strings.ContainsAny(text, "\r\n") {
+		_, _ = b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:    chatID,
+			Text:      messages.PdfPasswordInvalid(lang),
+			ParseMode: messages.ParseModeHTML,
+		})
+		return
+	}
+	bh.startPdfTask(ctx, b, userID, chatID, lang, options, map[string]interface{}{
+		"pdf_password": text,
+	})
+}
+
+func (bh *Handlers) startPdfTask(ctx context.Context, b *bot.Bot, userID int64, chatID int64, lang i18n.Lang, options map[string]interface{}, taskOptions map[string]interface{}) {
+	taskID, _ := options["pdf_task_id"].(string)
+	op, _ := options["pdf_op"].(string)
+	messageID := intOption(options, "pdf_msg_id")
+	delete(options, "pdf_state")
+	delete(options, "pdf_task_id")
+	delete(options, "pdf_op")
+	delete(options, "pdf_msg_id")
+	_ = bh.userState.SetUserOptions(userID, options)
+
+	task, err := bh.store.GetTask(taskID)
+	if err != nil || task == nil || task.UserID != userID {
+		_, _ = b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:    chatID,
+			Text:      messages.CallbackTaskNotFound(lang),
+			ParseMode: messages.ParseModeHTML,
+		})
+		return
+	}
+	if task.State != types.StateChooseExt {
+		_, _ = b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:    chatID,
+			Text:      messages.CallbackTaskAlreadyStarted(lang),
+			ParseMode: messages.ParseModeHTML,
+		})
+		return
+	}
+
+	plan := bh.userPlan(userID)
+
+	if task.Options == nil {
+		task.Options = map[string]interface{}{}
+	}
+	task.TargetExt = "pdf"
+	task.State = types.StateProcessing
+	task.Options["unlimited"] = plan.Unlimited
+	task.Options["lang"] = string(lang)
+	delete(task.Options, "pdf_pages")
+	delete(task.Options, "pdf_rotate")
+	delete(task.Options, "pdf_preset")
+	delete(task.Options, "pdf_password")
+	task.Options["pdf_op"] = op
+	for k, v := range taskOptions {
+		task.Options[k] = v
+	}
+	delete(task.Options, "credits_charged")
+	delete(task.Options, "credits_purchased")
+
+	remaining, denial, err := bh.chargeCredits(lang, plan, userID, task)
+	if err != nil || denial != "" {
+		text := denial
+		if err != nil {
+			log.Printf("Error charging credits for task %s: %v", task.ID, err)
+			text = messages.ErrorDefault(lang)
+		}
+		_, _ = b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:    chatID,
+			Text:      text,
+			ParseMode: messages.ParseModeHTML,
+		})
+		return
+	}
+
+	if err := bh.store.UpdateTask(task); err != nil {
+		log.Printf("Error updating task %s: %v", task.ID, err)
+		_, _ = b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:    chatID,
+			Text:      messages.CallbackTaskUpdateFailed(lang),
+			ParseMode: messages.ParseModeHTML,
+		})
+		bh.refundCredits(task)
+		return
+	}
+
+	bh.enqueueAndReport(ctx, b, userID, chatID, messageID, lang, task, plan, remaining)
+}
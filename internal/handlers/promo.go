@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"log"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BatmanBruc/bat-bot-convetor/internal/i18n"
+	"github.com/BatmanBruc/bat-bot-convetor/internal/messages"
+	"github.com/BatmanBruc/bat-bot-convetor/types"
+	"github.com/go-telegram/bot"
+)
+
+const (
+	promoCodesShown  = 20
+	giftCodePrefix   = "GIFT-"
+	giftCodeLength   = 8
+	giftCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	giftCodeTTL      = 365 * 24 * time.Hour
+)
+
+var promoCodePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{3,32}$`)
+
+func (bh *Handlers) handlePromo(ctx context.Context, b *bot.Bot, chatID int64, userID int64, lang i18n.Lang, args []string) {
+	text := ""
+	switch {
+	case len(args) != 1:
+		text = messages.PromoUsage(lang)
+	case bh.promos == nil:
+		text = messages.ErrorDefault(lang)
+	default:
+		text = bh.redeemPromo(userID, lang, args[0])
+	}
+
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    chatID,
+		Text:      text,
+		ParseMode: messages.ParseModeHTML,
+	})
+}
+
+func (bh *Handlers) redeemPromo(userID int64, lang i18n.Lang, code string) string {
+	res, err := bh.promos.RedeemPromoCode(userID, code)
+	switch {
+	case errors.Is(err, types.ErrPromoNotFound):
+		return messages.PromoNotFound(lang)
+	case errors.Is(err, types.ErrPromoInactive):
+		return messages.PromoInactive(lang)
+	case errors.Is(err, types.ErrPromoAlreadyUsed):
+		return messages.PromoAlreadyUsed(lang)
+	case err != nil:
+		log.Printf("Error redeeming promo code %q for user %d: %v", code, userID, err)
+		return messages.ErrorDefault(lang)
+	}
+
+	lines := make([]string, 0, 2)
+	if res.Subscription != nil {
+		title := res.Subscription.Plan
+		if plan := bh.lookupPlan(res.Subscription.Plan); plan != nil {
+			title = plan.Title
+		}
+		lines = append(lines, messages.PromoRedeemedPlan(lang, title, res.Subscription.ExpiresAt))
+	}
+	if res.Balance != nil {
+		lines = append(lines, messages.PromoRedeemedCredits(lang, res.Code.Credits, res.Balance))
+	}
+	return strings.Join(lines, "\n\n")
+}
+
+func (bh *Handlers) handlePromoCodes(ctx context.Context, b *bot.Bot, chatID int64, userID int64, lang i18n.Lang, args []string) {
+	text := ""
+	switch {
+	case bh.promos == nil:
+		text = messages.ErrorDefault(lang)
+	case len(args) == 0:
+		codes, err := bh.promos.ListPromoCodes(promoCodesShown)
+		if err != nil {
+			log.Printf("Error listing promo codes: %v", err)
+			text = messages.ErrorDefault(lang)
+		} else {
+			text = messages.AdminPromoList(lang, codes, time.Now().UTC())
+		}
+	case len(args) >= 3 && strings.EqualFold(args[0], "create"):
+		promo, ok := bh.parsePromoCode(args[1], args[2:])
+		if !ok {
+			text = messages.AdminPromoUsage(lang)
+			break
+		}
+		promo.CreatedBy = userID
+		err := bh.promos.CreatePromoCode(promo)
+		if errors.Is(err, types.ErrPromoExists) {
+			text = messages.AdminPromoExists(lang, promo.Code)
+		} else if err != nil {
+			log.Printf("Error creating promo code %s: %v", promo.Code, err)
+			text = messages.ErrorDefault(lang)
+		} else {
+			text = messages.AdminPromoCreated(lang, promo)
+		}
+	case len(args) == 2 && strings.EqualFold(args[0], "disable"):
+		code := strings.ToUpper(strings.TrimSpace(args[1]))
+		ok, err := bh.promos.DisablePromoCode(code)
+		if err != nil {
+			log.Printf("Error disabling promo code %s: %v", code, err)
+			text = messages.ErrorDefault(lang)
+		} else if !ok {
+			text = messages.AdminPromoNotFound(lang, code)
+		} else {
+			text = messages.AdminPromoDisabled(lang, code)
+		}
+	default:
+		text = messages.AdminPromoUsage(lang)
+	}
+
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    chatID,
+		Text:      text,
+		ParseMode: messages.ParseModeHTML,
+	})
+}
+
+func (bh *Handlers) parsePromoCode(code string, params []string) (types.PromoCode, bool) {
+	promo := types.PromoCode{Code: strings.ToUpper(strings.TrimSpace(code)), MaxUses: 1}
+	if !promoCodePattern.MatchString(promo.Code) {
+		return promo, false
+	}
+	for _, param := range params {
+		key, value, ok := strings.Cut(param, "=")
+		if !ok {
+			return promo, false
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		switch key {
+		case "uses":
+			if strings.EqualFold(value, "unlimited") {
+				promo.MaxUses = 0
+				break
+			}
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 || n > 100000 {
+				return promo, false
+			}
+			promo.MaxUses = n
+		case "days", "credits":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 || n > 100000 {
+				return promo, false
+			}
+			if key == "days" {
+				promo.Days = n
+			} else {
+				promo.Credits = n
+			}
+		case "plan":
+			promo.Plan = value
+		case "expires":
+			t, err := time.Parse("2006-01-02", value)
+			if err != nil {
+				return promo, false
+			}
+			t = t.Add(24 * time.Hour).UTC()
+			promo.ExpiresAt = &t
+		default:
+			return promo, false
+		}
+	}
+	if promo.Days > 0 && promo.Plan == "" {
+		promo.Plan = types.UnlimitedPlan
+	}
+	if promo.Days > 0 && (promo.Plan == types.FreePlan || bh.lookupPlan(promo.Plan) == nil) {
+		return promo, false
+	}
+	if promo.Days == 0 {
+		promo.Plan = ""
+	}
+	return promo, promo.Days > 0 || promo.Credits > 0
+}
+
+func (bh *Handlers) grantGift(ctx context.Context, b *bot.Bot, chatID int64, userID int64, lang i18n.Lang, plan *types.Plan, chargeID string) {
+	expires := time.Now().UTC().Add(giftCodeTTL)
+	promo := types.PromoCode{
+		Plan:        plan.Name,
+		Days:        plan.DurationDays,
+		MaxUses:     1,
+		ExpiresAt:   &expires,
+		CreatedBy:   userID,
+		GiftPayment: chargeID,
+	}
+	var err error
+	for attempt := 0; attempt < 3; attempt++ {
+		promo.Code, err = newGiftCode()
+		if err != nil {
+			break
+		}
+		err = bh.promos.CreatePromoCode(promo)
+		if !errors.Is(err, types.ErrPromoExists) {
+			break
+		}
+	}
+	if err != nil {
+		log.Printf("Error creating gift code for payment %s: %v", chargeID, err)
+		_, _ = b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:    chatID,
+			Text:      messages.ErrorDefault(lang),
+			ParseMode: messages.ParseModeHTML,
+		})
+		return
+	}
+	_, _ = b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    chatID,
+		Text:      messages.GiftPurchased(lang, promo.Code, plan, expires),
+		ParseMode: messages.ParseModeHTML,
+	})
+}
+
+func newGiftCode() (string, error) {
+	max := big.NewInt(int64(len(giftCodeAlphabet)))
+	code := make([]byte, giftCodeLength)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code[i] = giftCodeAlphabet[n.Int64()]
+	}
+	return giftCodePrefix + string(code), nil
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/BatmanBruc/bat-bot-convetor/internal/contextkeys"
+	"github.com/BatmanBruc/bat-bot-convetor/internal/converter"
+	"github.com/BatmanBruc/bat-bot-convetor/internal/messages"
+	"github.com/BatmanBruc/bat-bot-convetor/types"
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+const (
+	reminderInterval  = 10 * time.Minute
+	reminderBatchSize = 100
+)
+
+func (bh *Handlers) RunSubscriptionReminders(ctx context.Context, b *bot.Bot) {
+	if bh.userStore == nil {
+		return
+	}
+	ticker := time.NewTicker(reminderInterval)
+	defer ticker.Stop()
+
+	for {
+		bh.sendSubscriptionReminders(ctx, b)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (bh *Handlers) sendSubscriptionReminders(ctx context.Context, b *bot.Bot) {
+	for ctx.Err() == nil {
+		notices, err := bh.userStore.ClaimSubscriptionNotices(reminderBatchSize)
+		if err != nil {
+			log.Printf("Reminders: failed to claim subscription notices: %v", err)
+			return
+		}
+		released := false
+		for _, notice := range notices {
+			if err := bh.sendSubscriptionNotice(ctx, b, notice); err != nil {
+				log.Printf("Reminders: failed to send %s notice to user %d: %v", notice.Kind, notice.UserID, err)
+				if converter.IsTransient(err) || ctx.Err() != nil {
+					if err := bh.userStore.ReleaseSubscriptionNotice(notice); err != nil {
+						log.Printf("Reminders: failed to release %s notice for user %d: %v", notice.Kind, notice.UserID, err)
+					}
+					released = true
+				}
+			}
+		}
+		if released || len(notices) < reminderBatchSize {
+			return
+		}
+	}
+}
+
+func (bh *Handlers) sendSubscriptionNotice(ctx context.Context, b *bot.Bot, notice types.SubscriptionNotice) error {
+	lang := bh.langFromUserOrCtx(contextkeys.WithLang(ctx, notice.Lang), notice.UserID)
+	title := notice.Plan
+	var keyboard [][]models.InlineKeyboardButton
+	if plan := bh.lookupPlan(notice.Plan); plan != nil {
+		title = plan.Title
+		if _, ok := plan.Prices.Amount("XTR"); ok {
+			keyboard = append(keyboard, []models.InlineKeyboardButton{
+				{Text: messages.PayBtnStars(lang), CallbackData: "menu_pay_stars:" + plan.Name},
+			})
+		}
+		if _, ok := plan.Prices.Amount("RUB"); ok {
+			keyboard = append(keyboard, []models.InlineKeyboardButton{
+				{Text: messages.PayBtnYooKassa(lang), CallbackData: "menu_pay_yk:" + plan.Name},
+			})
+		}
+	}
+
+	text := ""
+	switch notice.Kind {
+	case types.NoticeExpiresIn3Days:
+		text = messages.SubscriptionExpiringReminder(lang, title, notice.ExpiresAt, 3)
+	case types.NoticeExpiresIn1Day:
+		text = messages.SubscriptionExpiringReminder(lang, title, notice.ExpiresAt, 1)
+	default:
+		text = messages.SubscriptionExpiredNotice(lang, title)
+	}
+
+	params := &bot.SendMessageParams{
+		ChatID:    notice.UserID,
+		Text:      text,
+		ParseMode: messages.ParseModeHTML,
+	}
+	if len(keyboard) > 0 {
+		params.ReplyMarkup = &models.InlineKeyboardMarkup{InlineKeyboard: keyboard}
+	}
+	_, err := b.SendMessage(ctx, params)
+	return err
+}
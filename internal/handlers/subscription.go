@@ -2,8 +2,8 @@ package handlers
 
 import (
 	"context"
+	"log"
 	"os"
-	"strconv"
 	"strings"
 	"time"
 
@@ -14,17 +14,44 @@ import (
 	"github.com/go-telegram/bot/models"
 )
 
+const (
+	paymentAttempts = 3
+
+	planPayloadPrefix = "sub:"
+	packPayloadPrefix = "pack:"
+	giftPayloadPrefix = "gift:"
+	legacySubPayload  = "sub_unlimited_month"
+)
+
+type invoiceItem struct {
+	plan *types.Plan
+	pack *types.CreditPack
+	gift bool
+}
+
+func (i *invoiceItem) prices() types.Prices {
+	if i.pack != nil {
+		return i.pack.Prices
+	}
+	return i.plan.Prices
+}
+
 func (bh *Handlers) HandlePreCheckout(ctx context.Context, b *bot.Bot, update *models.Update, userID int64) {
 	if update == nil || update.PreCheckoutQuery == nil {
 		return
 	}
 	lang := bh.langFromUserOrCtx(ctx, userID)
-	payload := strings.TrimSpace(update.PreCheckoutQuery.InvoicePayload)
-	expected := strings.TrimSpace(os.Getenv("SUB_PAYLOAD"))
-	if expected == "" {
-		expected = "sub_unlimited_month"
+	q := update.PreCheckoutQuery
+	ok := false
+	forever := false
+	if item := bh.resolveInvoice(q.InvoicePayload); item != nil {
+		amount, priced := item.prices().Amount(q.Currency)
+		ok = priced && amount == int64(q.TotalAmount)
+		if ok && item.plan != nil && !item.gift && bh.hasForeverPlan(userID) {
+			ok = false
+			forever = true
+		}
 	}
-	ok := payload == expected
 	_, _ = b.AnswerPreCheckoutQuery(ctx, &bot.AnswerPreCheckoutQueryParams{
 		PreCheckoutQueryID: update.PreCheckoutQuery.ID,
 		OK:                 ok,
@@ -32,6 +59,9 @@ func (bh *Handlers) HandlePreCheckout(ctx context.Context, b *bot.Bot, update *m
 			if ok {
 				return ""
 			}
+			if forever {
+				return messages.PaymentForeverPlan(lang)
+			}
 			if lang == i18n.RU {
 				return "Некорректный платеж"
 			}
@@ -51,17 +81,15 @@ func (bh *Handlers) HandleSuccessfulPayment(ctx context.Context, b *bot.Bot, upd
 	}
 	p := update.Message.SuccessfulPayment
 	payload := strings.TrimSpace(p.InvoicePayload)
-	expected := strings.TrimSpace(os.Getenv("SUB_PAYLOAD"))
-	if expected == "" {
-		expected = "sub_unlimited_month"
-	}
-	if payload != expected {
+	item := bh.resolveInvoice(payload)
+	if item == nil {
+		log.Printf("Payment %s from user %d has unknown payload %q", p.TelegramPaymentChargeID, userID, payload)
 		return
 	}
 	if bh.userStore == nil {
 		return
 	}
-	inserted, err := bh.userStore.RecordPayment(types.Payment{
+	payment := types.Payment{
 		UserID: userID,
 		Provider: func() string {
 			if strings.EqualFold(strings.TrimSpace(p.Currency), "XTR") {
@@ -75,8 +103,18 @@ func (bh *Handlers) HandleSuccessfulPayment(ctx context.Context, b *bot.Bot, upd
 		TelegramPaymentCharge: strings.TrimSpace(p.TelegramPaymentChargeID),
 		ProviderPaymentCharge: strings.TrimSpace(p.ProviderPaymentChargeID),
 		CreatedAt:             time.Now().UTC(),
-	})
+	}
+	if item.pack != nil {
+		bh.grantCreditPack(ctx, b, chatID, lang, payment, item.pack)
+		return
+	}
+	if !item.gift {
+		bh.activatePlanPayment(ctx, b, chatID, lang, payment, item.plan)
+		return
+	}
+	inserted, err := bh.userStore.RecordPayment(payment)
 	if err != nil {
+		log.Printf("Error recording payment %s: %v", p.TelegramPaymentChargeID, err)
 		return
 	}
 	if !inserted {
@@ -88,13 +126,38 @@ func (bh *Handlers) HandleSuccessfulPayment(ctx context.Context, b *bot.Bot, upd
 		return
 	}
 
-	sub, err := bh.userStore.ActivateOrExtendUnlimited(userID, 30*24*time.Hour)
+	bh.grantGift(ctx, b, chatID, userID, lang, item.plan, p.TelegramPaymentChargeID)
+}
+
+func (bh *Handlers) activatePlanPayment(ctx context.Context, b *bot.Bot, chatID int64, lang i18n.Lang, payment types.Payment, plan *types.Plan) {
+	var sub *types.Subscription
+	inserted := false
+	err := withPaymentRetries(func() error {
+		var err error
+		sub, inserted, err = bh.userStore.ActivatePlanPayment(payment, plan.Name, plan.Duration())
+		return err
+	})
 	if err != nil {
+		log.Printf("Error activating plan %s for payment %s from user %d: %v", plan.Name, payment.TelegramPaymentCharge, payment.UserID, err)
+		_, _ = b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:    chatID,
+			Text:      messages.PaymentActivationFailed(lang, payment.TelegramPaymentCharge),
+			ParseMode: messages.ParseModeHTML,
+		})
 		return
 	}
-	until := time.Now().UTC()
+	if !inserted {
+		_, _ = b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:    chatID,
+			Text:      messages.PaymentAlreadyProcessed(lang),
+			ParseMode: messages.ParseModeHTML,
+		})
+		return
+	}
+	var until *time.Time
 	if sub != nil && sub.ExpiresAt != nil {
-		until = sub.ExpiresAt.UTC()
+		t := sub.ExpiresAt.UTC()
+		until = &t
 	}
 	_, _ = b.SendMessage(ctx, &bot.SendMessageParams{
 		ChatID:    chatID,
@@ -103,58 +166,208 @@ func (bh *Handlers) HandleSuccessfulPayment(ctx context.Context, b *bot.Bot, upd
 	})
 }
 
-func getEnvInt(name string, def int) int {
-	v := strings.TrimSpace(os.Getenv(name))
-	if v == "" {
-		return def
+func (bh *Handlers) grantCreditPack(ctx context.Context, b *bot.Bot, chatID int64, lang i18n.Lang, payment types.Payment, pack *types.CreditPack) {
+	if bh.billing == nil {
+		return
 	}
-	n, err := strconv.Atoi(v)
+	var balance *types.Balance
+	inserted := false
+	err := withPaymentRetries(func() error {
+		var err error
+		balance, inserted, err = bh.billing.AddPurchasedCredits(payment, pack.Credits)
+		return err
+	})
 	if err != nil {
-		return def
-	}
-	return n
-}
-
-func (bh *Handlers) sendSubscriptionInvoiceStars(ctx context.Context, b *bot.Bot, chatID int64, lang i18n.Lang) bool {
-	priceStars := getEnvInt("SUB_PRICE_STARS", 150)
-	payload := strings.TrimSpace(os.Getenv("SUB_PAYLOAD"))
-	if payload == "" {
-		payload = "sub_unlimited_month"
-	}
-	_, err := b.SendInvoice(ctx, &bot.SendInvoiceParams{
-		ChatID:         chatID,
-		Title:          "Unlimited subscription",
-		Description:    "Unlimited conversions for 1 month",
-		Payload:        payload,
-		Currency:       "XTR",
-		Prices:         []models.LabeledPrice{{Label: "Unlimited (1 month)", Amount: priceStars}},
-		StartParameter: "unlimited_month",
-		ProviderToken:  "",
+		log.Printf("Error adding %d credits for payment %s: %v", pack.Credits, payment.TelegramPaymentCharge, err)
+		_, _ = b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:    chatID,
+			Text:      messages.PaymentActivationFailed(lang, payment.TelegramPaymentCharge),
+			ParseMode: messages.ParseModeHTML,
+		})
+		return
+	}
+	if !inserted {
+		_, _ = b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:    chatID,
+			Text:      messages.PaymentAlreadyProcessed(lang),
+			ParseMode: messages.ParseModeHTML,
+		})
+		return
+	}
+	_, _ = b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    chatID,
+		Text:      messages.PackPurchased(lang, pack.Credits, balance),
+		ParseMode: messages.ParseModeHTML,
 	})
-	_ = lang
-	return err == nil
 }
 
-func (bh *Handlers) sendSubscriptionInvoiceYooKassa(ctx context.Context, b *bot.Bot, chatID int64, lang i18n.Lang) bool {
-	token := strings.TrimSpace(os.Getenv("YOOKASSA_PROVIDER_TOKEN"))
-	if token == "" {
+func withPaymentRetries(fn func() error) error {
+	var err error
+	for attempt := 1; attempt <= paymentAttempts; attempt++ {
+		if err = fn(); err == nil {
+			return nil
+		}
+		if attempt < paymentAttempts {
+			time.Sleep(time.Duration(attempt) * time.Second)
+		}
+	}
+	return err
+}
+
+func (bh *Handlers) hasForeverPlan(userID int64) bool {
+	if bh.userStore == nil {
+		return false
+	}
+	sub, err := bh.userStore.GetSubscription(userID)
+	if err != nil || sub == nil {
 		return false
 	}
-	priceKopeks := getEnvInt("SUB_PRICE_RUB_KOPEKS", 15000)
-	payload := strings.TrimSpace(os.Getenv("SUB_PAYLOAD"))
-	if payload == "" {
-		payload = "sub_unlimited_month"
-	}
-	_, err := b.SendInvoice(ctx, &bot.SendInvoiceParams{
-		ChatID:         chatID,
-		Title:          "Unlimited subscription",
-		Description:    "Unlimited conversions for 1 month",
-		Payload:        payload,
-		ProviderToken:  token,
-		Currency:       "RUB",
-		Prices:         []models.LabeledPrice{{Label: "Unlimited (1 month)", Amount: priceKopeks}},
-		StartParameter: "unlimited_month",
+	return sub.Status == "active" && sub.Plan != types.FreePlan && sub.ExpiresAt == nil
+}
+
+func (bh *Handlers) resolveInvoice(payload string) *invoiceItem {
+	payload = strings.TrimSpace(payload)
+	if name, ok := strings.CutPrefix(payload, packPayloadPrefix); ok {
+		if pack := bh.lookupPack(name); pack != nil {
+			return &invoiceItem{pack: pack}
+		}
+		return nil
+	}
+	if name, ok := strings.CutPrefix(payload, giftPayloadPrefix); ok {
+		if bh.promos == nil {
+			return nil
+		}
+		if plan := bh.invoicePlan(planPayloadPrefix + name); plan != nil && plan.DurationDays > 0 {
+			return &invoiceItem{plan: plan, gift: true}
+		}
+		return nil
+	}
+	if plan := bh.invoicePlan(payload); plan != nil {
+		return &invoiceItem{plan: plan}
+	}
+	return nil
+}
+
+func planFromPayload(payload string) string {
+	payload = strings.TrimSpace(payload)
+	if name, ok := strings.CutPrefix(payload, planPayloadPrefix); ok {
+		return strings.TrimSpace(name)
+	}
+	legacy := strings.TrimSpace(os.Getenv("SUB_PAYLOAD"))
+	if legacy == "" {
+		legacy = legacySubPayload
+	}
+	if payload == legacy {
+		return types.UnlimitedPlan
+	}
+	return ""
+}
+
+func (bh *Handlers) invoicePlan(payload string) *types.Plan {
+	name := planFromPayload(payload)
+	if name == "" || name == types.FreePlan {
+		return nil
+	}
+	return bh.lookupPlan(name)
+}
+
+func (bh *Handlers) lookupPlan(name string) *types.Plan {
+	if bh.billing == nil {
+		return nil
+	}
+	plan, err := bh.billing.GetPlan(strings.TrimSpace(name))
+	if err != nil {
+		log.Printf("Error loading plan %s: %v", name, err)
+		return nil
+	}
+	return plan
+}
+
+func (bh *Handlers) lookupPack(name string) *types.CreditPack {
+	if bh.billing == nil {
+		return nil
+	}
+	pack, err := bh.billing.GetCreditPack(strings.TrimSpace(name))
+	if err != nil {
+		log.Printf("Error loading credit pack %s: %v", name, err)
+		return nil
+	}
+	return pack
+}
+
+func (bh *Handlers) purchasablePlans() []types.Plan {
+	if bh.billing == nil {
+		return nil
+	}
+	plans, err := bh.billing.ListPlans()
+	if err != nil {
+		log.Printf("Error loading plans: %v", err)
+		return nil
+	}
+	return plans
+}
+
+func (bh *Handlers) creditPacks() []types.CreditPack {
+	if bh.billing == nil {
+		return nil
+	}
+	packs, err := bh.billing.ListCreditPacks()
+	if err != nil {
+		log.Printf("Error loading credit packs: %v", err)
+		return nil
+	}
+	return packs
+}
+
+func (bh *Handlers) sendPlanInvoice(ctx context.Context, b *bot.Bot, chatID int64, lang i18n.Lang, plan *types.Plan, currency string) bool {
+	return bh.sendInvoice(ctx, b, chatID, currency, plan.Prices, &bot.SendInvoiceParams{
+		Title:          messages.PlanInvoiceTitle(lang, plan),
+		Description:    messages.PlanInvoiceDescription(lang, plan),
+		Payload:        planPayloadPrefix + plan.Name,
+		Prices:         []models.LabeledPrice{{Label: plan.Title}},
+		StartParameter: "plan_" + plan.Name,
 	})
-	_ = lang
+}
+
+func (bh *Handlers) sendGiftInvoice(ctx context.Context, b *bot.Bot, chatID int64, lang i18n.Lang, plan *types.Plan, currency string) bool {
+	return bh.sendInvoice(ctx, b, chatID, currency, plan.Prices, &bot.SendInvoiceParams{
+		Title:          messages.GiftInvoiceTitle(lang, plan),
+		Description:    messages.GiftInvoiceDescription(lang, plan),
+		Payload:        giftPayloadPrefix + plan.Name,
+		Prices:         []models.LabeledPrice{{Label: plan.Title}},
+		StartParameter: "gift_" + plan.Name,
+	})
+}
+
+func (bh *Handlers) sendPackInvoice(ctx context.Context, b *bot.Bot, chatID int64, lang i18n.Lang, pack *types.CreditPack, currency string) bool {
+	return bh.sendInvoice(ctx, b, chatID, currency, pack.Prices, &bot.SendInvoiceParams{
+		Title:          messages.PackInvoiceTitle(lang, pack),
+		Description:    messages.PackInvoiceDescription(lang, pack),
+		Payload:        packPayloadPrefix + pack.Name,
+		Prices:         []models.LabeledPrice{{Label: pack.Title}},
+		StartParameter: "pack_" + pack.Name,
+	})
+}
+
+func (bh *Handlers) sendInvoice(ctx context.Context, b *bot.Bot, chatID int64, currency string, prices types.Prices, params *bot.SendInvoiceParams) bool {
+	amount, ok := prices.Amount(currency)
+	if !ok || len(params.Prices) == 0 {
+		return false
+	}
+	token := ""
+	if currency != "XTR" {
+		token = strings.TrimSpace(os.Getenv("YOOKASSA_PROVIDER_TOKEN"))
+		if token == "" {
+			return false
+		}
+	}
+	params.ChatID = chatID
+	params.Currency = currency
+	params.ProviderToken = token
+	params.Prices[0].Amount = int(amount)
+	_, err := b.SendInvoice(ctx, params)
+	if err != nil {
+		log.Printf("Error sending %s invoice %s: %v", currency, params.Payload, err)
+	}
 	return err == nil
 }
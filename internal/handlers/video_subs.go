@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"github.com/BatmanBruc/bat-bot-convetor/internal/contextkeys"
+	"github.com/BatmanBruc/bat-bot-convetor/internal/formats"
+	"github.com/BatmanBruc/bat-bot-convetor/internal/i18n"
+	"github.com/BatmanBruc/bat-bot-convetor/internal/messages"
+	"github.com/BatmanBruc/bat-bot-convetor/types"
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+func videoSubsTarget(mode string, originalExt string) string {
+	if mode == "mux" && originalExt != "mp4" && originalExt != "mov" {
+		return "mkv"
+	}
+	return "mp4"
+}
+
+func (bh *Handlers) askVideoSubtitles(ctx context.Context, b *bot.Bot, update *models.Update, userID int64, lang i18n.Lang, taskID string, mode string) {
+	if mode != "burn" && mode != "mux" {
+		_ = bh.answerCallbackAlert(ctx, b, update.CallbackQuery.ID, messages.CallbackInvalidButtonData(lang))
+		return
+	}
+	task, err := bh.store.GetTask(taskID)
+	if err != nil || task == nil {
+		_ = bh.answerCallback(ctx, b, update.CallbackQuery.ID, messages.CallbackTaskNotFound(lang))
+		return
+	}
+	if task.UserID != userID {
+		_ = bh.answerCallback(ctx, b, update.CallbackQuery.ID, messages.CallbackTaskNotInSession(lang))
+		return
+	}
+	if task.State != types.StateChooseExt {
+		_ = bh.answerCallback(ctx, b, update.CallbackQuery.ID, messages.CallbackTaskAlreadyStarted(lang))
+		return
+	}
+	if update.CallbackQuery.Message.Message == nil {
+		_ = bh.answerCallback(ctx, b, update.CallbackQuery.ID, messages.CallbackInvalidAction(lang))
+		return
+	}
+	msg := update.CallbackQuery.Message.Message
+
+	options, _ := bh.userState.GetUserOptions(userID)
+	if options == nil {
+		options = map[string]interface{}{}
+	}
+	delete(options, "merge_state")
+	delete(options, "img2pdf_state")
+	options["vsub_state"] = "waiting"
+	options["vsub_task_id"] = task.ID
+	options["vsub_mode"] = mode
+	options["vsub_msg_id"] = msg.ID
+	_ = bh.userState.SetUserOptions(userID, options)
+
+	_ = bh.answerCallback(ctx, b, update.CallbackQuery.ID, "")
+	_, _ = b.EditMessageText(ctx, &bot.EditMessageTextParams{
+		ChatID:    msg.Chat.ID,
+		MessageID: msg.ID,
+		Text:      messages.VideoSubsWaiting(lang, mode, task.FileName),
+		ParseMode: messages.ParseModeHTML,
+	})
+}
+
+func (bh *Handlers) handleVideoSubsFile(ctx context.Context, b *bot.Bot, userID int64, lang i18n.Lang, files []contextkeys.FileInfo) {
+	chatID := userID
+	if len(files) != 1 || formats.GetCategoryByExtension(filepath.Ext(files[0].FileName)) != "subtitles" {
+		_, _ = b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:    chatID,
+			Text:      messages.VideoSubsInvalidFile(lang),
+			ParseMode: messages.ParseModeHTML,
+		})
+		return
+	}
+	subs := files[0]
+
+	options, _ := bh.userState.GetUserOptions(userID)
+	if options == nil {
+		options = map[string]interface{}{}
+	}
+	taskID, _ := options["vsub_task_id"].(string)
+	mode, _ := options["vsub_mode"].(string)
+	messageID := intOption(options, "vsub_msg_id")
+	delete(options, "vsub_state")
+	delete(options, "vsub_task_id")
+	delete(options, "vsub_mode")
+	delete(options, "vsub_msg_id")
+	_ = bh.userState.SetUserOptions(userID, options)
+
+	task, err := bh.store.GetTask(taskID)
+	if err != nil || task == nil || task.UserID != userID {
+		_, _ = b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:    chatID,
+			Text:      messages.CallbackTaskNotFound(lang),
+			ParseMode: messages.ParseModeHTML,
+		})
+		return
+	}
+	if task.State != types.StateChooseExt {
+		_, _ = b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:    chatID,
+			Text:      messages.CallbackTaskAlreadyStarted(lang),
+			ParseMode: messages.ParseModeHTML,
+		})
+		return
+	}
+
+	plan := bh.userPlan(userID)
+
+	if task.Options == nil {
+		task.Options = map[string]interface{}{}
+	}
+	task.TargetExt = videoSubsTarget(mode, strings.ToLower(task.OriginalExt))
+	task.State = types.StateProcessing
+	task.Inputs = []types.TaskInput{{FileID: subs.FileID, FileName: subs.FileName}}
+	task.Options["unlimited"] = plan.Unlimited
+	task.Options["lang"] = string(lang)
+	delete(task.Options, "vid_height")
+	delete(task.Options, "vid_crf")
+	delete(task.Options, "vid_gif_height")
+	delete(task.Options, "vid_w")
+	delete(task.Options, "vid_h")
+	task.Options["vid_op"] = mode + "_subs"
+	delete(task.Options, "credits_charged")
+	delete(task.Options, "credits_purchased")
+
+	remaining, denial, err := bh.chargeCredits(lang, plan, userID, task)
+	if err != nil || denial != "" {
+		text := denial
+		if err != nil {
+			log.Printf("Error charging credits for task %s: %v", task.ID, err)
+			text = messages.ErrorDefault(lang)
+		}
+		_, _ = b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:    chatID,
+			Text:      text,
+			ParseMode: messages.ParseModeHTML,
+		})
+		return
+	}
+
+	if err := bh.store.UpdateTask(task); err != nil {
+		log.Printf("Error updating task %s: %v", task.ID, err)
+		_, _ = b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:    chatID,
+			Text:      messages.CallbackTaskUpdateFailed(lang),
+			ParseMode: messages.ParseModeHTML,
+		})
+		bh.refundCredits(task)
+		return
+	}
+
+	bh.enqueueAndReport(ctx, b, userID, chatID, messageID, lang, task, plan, remaining)
+}
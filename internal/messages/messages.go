@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/BatmanBruc/bat-bot-convetor/internal/i18n"
+	"github.com/BatmanBruc/bat-bot-convetor/types"
 )
 
 const ParseModeHTML = "HTML"
@@ -83,6 +84,40 @@ func QueueStarted(lang i18n.Lang, fileName string) string {
 	return pick(lang, "⚙️ <b>Конвертация началась</b>\n", "⚙️ <b>Conversion started</b>\n") + FileLine(lang, fileName)
 }
 
+func QueueJobAbandoned(lang i18n.Lang, fileName string) string {
+	return pick(lang, "🚫 <b>Конвертация не завершилась</b>\nЗадача несколько раз прерывалась. Отправьте файл ещё раз.\n", "🚫 <b>Conversion did not finish</b>\nThe task was interrupted several times. Please send the file again.\n") + FileLine(lang, fileName)
+}
+
+func QueueRetrying(lang i18n.Lang, fileName string, delay time.Duration) string {
+	return pick(lang, "🔁 <b>Временная ошибка</b>\nПовтор через ", "🔁 <b>Temporary error</b>\nRetrying in ") + formatETA(lang, delay) + "\n" + FileLine(lang, fileName)
+}
+
+func QueueProgress(lang i18n.Lang, fileName string, percent int, eta time.Duration) string {
+	filled := percent / 10
+	bar := strings.Repeat("▓", filled) + strings.Repeat("░", 10-filled)
+	msg := fmt.Sprintf("⚙️ <b>%s</b> %d%%\n%s\n", Escape(pick(lang, "Конвертация:", "Converting:")), percent, bar)
+	if eta > 0 {
+		msg += pick(lang, "⏱ Осталось примерно ", "⏱ About ") + formatETA(lang, eta) + pick(lang, "\n", " left\n")
+	}
+	return msg + FileLine(lang, fileName)
+}
+
+func formatETA(lang i18n.Lang, d time.Duration) string {
+	d = d.Round(time.Second)
+	if d < time.Minute {
+		return fmt.Sprintf(pick(lang, "%d с", "%ds"), int(d.Seconds()))
+	}
+	return fmt.Sprintf(pick(lang, "%d мин %d с", "%dm %ds"), int(d.Minutes()), int(d.Seconds())%60)
+}
+
+func CancelTaskBtn(lang i18n.Lang) string {
+	return pick(lang, "✖️ Отменить", "✖️ Cancel")
+}
+
+func TaskCancelled(lang i18n.Lang, fileName string) string {
+	return pick(lang, "🛑 <b>Конвертация отменена</b>\n", "🛑 <b>Conversion cancelled</b>\n") + FileLine(lang, fileName)
+}
+
 func TextReceivedChooseFormat(lang i18n.Lang) string {
 	return pick(lang, "📝 <b>Текст получен</b>\nВыберите формат файла:", "📝 <b>Text received</b>\nChoose the output format:")
 }
@@ -168,18 +203,48 @@ func PlanUnlimitedLine(lang i18n.Lang) string {
 	return pick(lang, "Тариф: безлимит", "Plan: unlimited")
 }
 
-func CreditsRemainingLine(lang i18n.Lang, remaining int) string {
+func creditsFraction(remaining int, allowance int) string {
+	if remaining > allowance {
+		return fmt.Sprintf("%d", remaining)
+	}
+	return fmt.Sprintf("%d/%d", remaining, allowance)
+}
+
+func CreditsRemainingLine(lang i18n.Lang, remaining int, allowance int) string {
+	return pick(lang, "Осталось кредитов: ", "Remaining credits: ") + creditsFraction(remaining, allowance)
+}
+
+func PurchasedCreditsLine(lang i18n.Lang, purchased int) string {
 	if lang == i18n.RU {
-		return fmt.Sprintf("Осталось кредитов: %d/20", remaining)
+		return fmt.Sprintf("Купленные кредиты: %d", purchased)
 	}
-	return fmt.Sprintf("Remaining credits: %d/20", remaining)
+	return fmt.Sprintf("Purchased credits: %d", purchased)
 }
 
 func NoCreditsHint(lang i18n.Lang) string {
 	if lang == i18n.RU {
-		return "К сожалению, у вас закончились кредиты. Подождите до следующего обновления (раз в 24 часа) или оформите подписку и пользуйтесь безлимитом."
+		return "К сожалению, у вас закончились кредиты. Подождите до следующего обновления (раз в 24 часа), купите пакет кредитов или оформите подписку в /menu."
+	}
+	return "Unfortunately, you're out of credits. Wait for the next daily reset (every 24 hours), buy a credit pack or get a subscription in /menu."
+}
+
+func BalanceInfo(lang i18n.Lang, plan *types.Plan, balance *types.Balance) string {
+	text := pick(lang, "💳 <b>Баланс</b>", "💳 <b>Balance</b>") + "\n" + PlanLine(lang, plan)
+	if plan.Unlimited {
+		text += "\n" + PlanUnlimitedLine(lang)
+	} else {
+		reset := balance.ResetAt.UTC().Format("2006-01-02 15:04 UTC")
+		text += "\n" + pick(lang, "Дневные кредиты: ", "Daily credits: ") + creditsFraction(balance.Daily, plan.DailyCredits)
+		text += "\n" + pick(lang, "Обновление: ", "Resets at: ") + reset
+	}
+	if balance.Purchased > 0 {
+		text += "\n" + PurchasedCreditsLine(lang, balance.Purchased)
 	}
-	return "Unfortunately, you're out of credits. Wait for the next daily reset (every 24 hours) or get a subscription to use unlimited conversions."
+	return text
+}
+
+func PlanLine(lang i18n.Lang, plan *types.Plan) string {
+	return pick(lang, "Тариф: <b>", "Plan: <b>") + Escape(plan.Title) + "</b>"
 }
 
 func BalanceUnavailable(lang i18n.Lang) string {
@@ -214,21 +279,38 @@ func CallbackTaskUpdateFailed(lang i18n.Lang) string {
 	return pick(lang, "Не удалось обновить задачу", "Failed to update task")
 }
 
+func CallbackTaskAlreadyFinished(lang i18n.Lang) string {
+	return pick(lang, "Задача уже завершена", "The task has already finished")
+}
+
+func CallbackTaskAlreadyStarted(lang i18n.Lang) string {
+	return pick(lang, "Задача уже в работе", "The task is already in progress")
+}
+
 func CallbackBillingError(lang i18n.Lang) string {
 	return pick(lang, "Ошибка списания кредитов", "Failed to charge credits")
 }
 
-func CallbackInsufficientCredits(lang i18n.Lang, remaining int) string {
+func CallbackInsufficientCredits(lang i18n.Lang, remaining int, allowance int) string {
+	text := pick(lang, "Недостаточно кредитов. Осталось ", "Not enough credits. Remaining ") + creditsFraction(remaining, allowance)
+	if remaining <= 0 {
+		return text + ".\n\n" + NoCreditsHint(lang)
+	}
+	return text
+}
+
+func CallbackFileTooLargeForPlan(lang i18n.Lang, plan *types.Plan) string {
 	if lang == i18n.RU {
-		if remaining <= 0 {
-			return "Недостаточно кредитов. Осталось 0/50.\n\n" + NoCreditsHint(lang)
-		}
-		return fmt.Sprintf("Недостаточно кредитов. Осталось %d/20", remaining)
+		return fmt.Sprintf("Файл больше лимита тарифа %s (%d МБ)", plan.Title, plan.MaxFileSize>>20)
 	}
-	if remaining <= 0 {
-		return "Not enough credits. Remaining 0/20.\n\n" + NoCreditsHint(lang)
+	return fmt.Sprintf("The file exceeds the %s plan limit (%d MB)", plan.Title, plan.MaxFileSize>>20)
+}
+
+func CallbackCategoryNotInPlan(lang i18n.Lang, plan *types.Plan) string {
+	if lang == i18n.RU {
+		return fmt.Sprintf("Этот тип файлов не входит в тариф %s", plan.Title)
 	}
-	return fmt.Sprintf("Not enough credits. Remaining %d/20", remaining)
+	return fmt.Sprintf("This file type is not included in the %s plan", plan.Title)
 }
 
 func AdminGrantUsage(lang i18n.Lang) string {
@@ -250,6 +332,52 @@ func AdminDenied(lang i18n.Lang) string {
 	return pick(lang, "Недостаточно прав", "Access denied")
 }
 
+func AdminDeadLettersUsage(lang i18n.Lang) string {
+	return pick(
+		lang,
+		"Использование: <code>/dead_letters</code> или <code>/dead_letters retry &lt;ID&gt;</code>",
+		"Usage: <code>/dead_letters</code> or <code>/dead_letters retry &lt;ID&gt;</code>",
+	)
+}
+
+func AdminDeadLetters(lang i18n.Lang, letters []*types.DeadLetter) string {
+	if len(letters) == 0 {
+		return pick(lang, "✅ Нет задач с исчерпанными попытками", "✅ No tasks with exhausted retries")
+	}
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf(pick(lang, "🪦 <b>Упавшие задачи: %d</b>\n", "🪦 <b>Dead tasks: %d</b>\n"), len(letters)))
+	for _, l := range letters {
+		sb.WriteString(fmt.Sprintf("\n<code>%s</code> %s\n%s · %s\n",
+			Escape(l.Job.TaskID),
+			Escape(l.Job.FileName),
+			Escape(l.FailedAt.UTC().Format("2006-01-02 15:04")),
+			Escape(shortReason(l.Reason)),
+		))
+	}
+	sb.WriteString(pick(lang, "\nПовторить: <code>/dead_letters retry &lt;ID&gt;</code>", "\nRetry: <code>/dead_letters retry &lt;ID&gt;</code>"))
+	return sb.String()
+}
+
+func shortReason(reason string) string {
+	r := []rune(strings.TrimSpace(reason))
+	if len(r) > 200 {
+		return string(r[:200]) + "…"
+	}
+	return string(r)
+}
+
+func AdminRedriveDone(lang i18n.Lang, taskID string) string {
+	return pick(lang, "✅ Задача снова в очереди: ", "✅ Task is back in the queue: ") + fmt.Sprintf("<code>%s</code>", Escape(taskID))
+}
+
+func AdminRedriveNotFound(lang i18n.Lang, taskID string) string {
+	return pick(lang, "Задача не найдена среди упавших: ", "Task not found among dead tasks: ") + fmt.Sprintf("<code>%s</code>", Escape(taskID))
+}
+
+func AdminRedriveNeedsPassword(lang i18n.Lang, taskID string) string {
+	return pick(lang, "Задачу нельзя повторить: пароль PDF не сохраняется, пользователь должен запустить её заново: ", "Task cannot be retried: the PDF password is not kept, the user has to start it again: ") + fmt.Sprintf("<code>%s</code>", Escape(taskID))
+}
+
 func TaskTypeLine(lang i18n.Lang, heavy bool) string {
 	if lang == i18n.RU {
 		if heavy {
@@ -289,6 +417,27 @@ func LangInvalid(lang i18n.Lang) string {
 	return pick(lang, "🚫 Неверный язык. Используйте: <code>/lang ru</code> или <code>/lang en</code>", "🚫 Invalid language. Use: <code>/lang ru</code> or <code>/lang en</code>")
 }
 
+func DeliveryUsage(lang i18n.Lang, asFile bool) string {
+	current := pick(lang, "Сейчас: фото, видео и аудио приходят как медиа", "Now: photos, videos and audio arrive as media")
+	if asFile {
+		current = pick(lang, "Сейчас: все результаты приходят файлом", "Now: all results arrive as files")
+	}
+	return pick(lang,
+		"📦 <b>Доставка результатов</b>\n",
+		"📦 <b>Result delivery</b>\n",
+	) + current + pick(lang,
+		"\n\nИспользование: <code>/delivery file</code> или <code>/delivery auto</code>",
+		"\n\nUsage: <code>/delivery file</code> or <code>/delivery auto</code>",
+	)
+}
+
+func DeliverySet(lang i18n.Lang, asFile bool) string {
+	if asFile {
+		return pick(lang, "✅ Результаты будут приходить файлом", "✅ Results will be sent as files")
+	}
+	return pick(lang, "✅ Результаты будут приходить как фото, видео и аудио", "✅ Results will be sent as photos, videos and audio")
+}
+
 func MenuTitle(lang i18n.Lang) string {
 	return pick(lang, "📋 <b>Меню</b>", "📋 <b>Menu</b>")
 }
@@ -351,6 +500,69 @@ func MergePDFError(lang i18n.Lang) string {
 	return pick(lang, "❌ Ошибка объединения PDF файлов", "❌ Error merging PDF files")
 }
 
+func MenuBtnImagesToPDF(lang i18n.Lang) string {
+	return pick(lang, "🖼 Фото в PDF", "🖼 Images to PDF")
+}
+
+func ImagesToPDFWaiting(lang i18n.Lang) string {
+	return pick(lang,
+		"🖼 <b>Фото в PDF</b>\n\nОтправляйте фото или изображения по одному — каждое станет отдельной страницей в том порядке, в котором вы их пришлёте.\n\n<i>Telegram не гарантирует последовательность, если вы пришлёте пачкой.</i>",
+		"🖼 <b>Images to PDF</b>\n\nSend photos or images one by one — each becomes a page in the order you send them.\n\n<i>Telegram doesn't guarantee order if you send multiple at once.</i>",
+	)
+}
+
+func ImagesToPDFFilesList(lang i18n.Lang, files []string, page string, orientation string, marginMM int) string {
+	var b strings.Builder
+	for i, f := range files {
+		b.WriteString(fmt.Sprintf("%d. %s\n", i+1, Escape(f)))
+	}
+	return pick(lang,
+		fmt.Sprintf("🖼 <b>Страницы PDF:</b>\n%s\nФормат: <b>%s</b>, ориентация: <b>%s</b>, поля: <b>%d мм</b>\n\nДобавьте ещё изображения или нажмите «Создать PDF».", b.String(), ImagesToPDFPageLabel(lang, page), ImagesToPDFOrientationLabel(lang, orientation), marginMM),
+		fmt.Sprintf("🖼 <b>PDF pages:</b>\n%s\nPage: <b>%s</b>, orientation: <b>%s</b>, margins: <b>%d mm</b>\n\nSend more images or press \"Create PDF\".", b.String(), ImagesToPDFPageLabel(lang, page), ImagesToPDFOrientationLabel(lang, orientation), marginMM),
+	)
+}
+
+func ImagesToPDFPageLabel(lang i18n.Lang, page string) string {
+	switch page {
+	case "letter":
+		return "Letter"
+	case "fit":
+		return pick(lang, "По размеру фото", "Fit to image")
+	default:
+		return "A4"
+	}
+}
+
+func ImagesToPDFOrientationLabel(lang i18n.Lang, orientation string) string {
+	if orientation == "landscape" {
+		return pick(lang, "Альбомная", "Landscape")
+	}
+	return pick(lang, "Книжная", "Portrait")
+}
+
+func ImagesToPDFMarginLabel(lang i18n.Lang, marginMM int) string {
+	if marginMM == 0 {
+		return pick(lang, "Без полей", "No margins")
+	}
+	return pick(lang, fmt.Sprintf("Поля %d мм", marginMM), fmt.Sprintf("%d mm margins", marginMM))
+}
+
+func ImagesToPDFBtn(lang i18n.Lang) string {
+	return pick(lang, "📄 Создать PDF", "📄 Create PDF")
+}
+
+func ImagesToPDFStarted(lang i18n.Lang) string {
+	return pick(lang, "🔄 Собираю PDF из изображений...", "🔄 Building PDF from images...")
+}
+
+func ImagesToPDFSuccess(lang i18n.Lang) string {
+	return pick(lang, "✅ PDF из изображений готов!", "✅ PDF from images is ready!")
+}
+
+func ImagesToPDFError(lang i18n.Lang) string {
+	return pick(lang, "❌ Ошибка сборки PDF из изображений", "❌ Error building PDF from images")
+}
+
 func MenuBtnBack(lang i18n.Lang) string {
 	return pick(lang, "⬅️ Назад", "⬅️ Back")
 }
@@ -362,11 +574,11 @@ func MenuBtnSubscribeNow(lang i18n.Lang, active bool) string {
 	return pick(lang, "✅ Оплатить", "✅ Pay")
 }
 
-func AboutCreditsBlock(lang i18n.Lang) string {
-	return pick(lang,
-		"💳 <b>Кредиты</b>\n- Без подписки: 50 кредитов в сутки (обновляются каждый день)\n- Подписка: кредиты не нужны (безлимит)\n\nКоманды: <code>/balance</code>, <code>/menu</code>",
-		"💳 <b>Credits</b>\n- No subscription: 20 credits per day (refreshed daily)\n- Subscription: credits are not needed (unlimited)\n\nCommands: <code>/balance</code>, <code>/menu</code>",
-	)
+func AboutCreditsBlock(lang i18n.Lang, freeCredits int) string {
+	if lang == i18n.RU {
+		return fmt.Sprintf("💳 <b>Кредиты</b>\n- Без подписки: %d кредитов в сутки (обновляются каждый день)\n- Подписка: больше кредитов или безлимит, в зависимости от тарифа\n\nКоманды: <code>/balance</code>, <code>/promo</code>, <code>/menu</code>", freeCredits)
+	}
+	return fmt.Sprintf("💳 <b>Credits</b>\n- No subscription: %d credits per day (refreshed daily)\n- Subscription: more credits or unlimited, depending on the plan\n\nCommands: <code>/balance</code>, <code>/promo</code>, <code>/menu</code>", freeCredits)
 }
 
 func BatchHowManyPrompt(lang i18n.Lang) string {
@@ -388,6 +600,81 @@ func BatchCountInvalid(lang i18n.Lang) string {
 	return pick(lang, "🚫 Введите число файлов (например: <code>3</code>)", "🚫 Enter the number of files (e.g. <code>3</code>)")
 }
 
+func PdfPagesPrompt(lang i18n.Lang, op string, fileName string) string {
+	name := Escape(fileName)
+	switch op {
+	case "split_ranges":
+		return pick(lang,
+			fmt.Sprintf("✂️ <b>%s</b>\n\nОтправьте диапазоны через запятую — каждый станет отдельным файлом.\nНапример: <code>1-3,4-7,8-</code>", name),
+			fmt.Sprintf("✂️ <b>%s</b>\n\nSend ranges separated by commas — each becomes a separate file.\nExample: <code>1-3,4-7,8-</code>", name),
+		)
+	case "delete":
+		return pick(lang,
+			fmt.Sprintf("🗑 <b>%s</b>\n\nКакие страницы удалить? Например: <code>2,5-7</code>", name),
+			fmt.Sprintf("🗑 <b>%s</b>\n\nWhich pages should be deleted? Example: <code>2,5-7</code>", name),
+		)
+	case "reorder":
+		return pick(lang,
+			fmt.Sprintf("🔀 <b>%s</b>\n\nПеречислите страницы в новом порядке. Например: <code>3,1,2,4-</code>", name),
+			fmt.Sprintf("🔀 <b>%s</b>\n\nList the pages in the new order. Example: <code>3,1,2,4-</code>", name),
+		)
+	default:
+		return pick(lang,
+			fmt.Sprintf("📄 <b>%s</b>\n\nКакие страницы извлечь? Например: <code>3-7,10</code>", name),
+			fmt.Sprintf("📄 <b>%s</b>\n\nWhich pages should be extracted? Example: <code>3-7,10</code>", name),
+		)
+	}
+}
+
+func PdfPagesInvalid(lang i18n.Lang) string {
+	return pick(lang,
+		"🚫 Не удалось разобрать номера страниц. Пример: <code>3-7,10</code>",
+		"🚫 Couldn't parse page numbers. Example: <code>3-7,10</code>",
+	)
+}
+
+func PdfPasswordPrompt(lang i18n.Lang, op string, fileName string) string {
+	name := Escape(fileName)
+	if op == "decrypt" {
+		return pick(lang,
+			fmt.Sprintf("🔓 <b>%s</b>\n\nОтправьте текущий пароль от PDF. Сообщение с паролем будет удалено.", name),
+			fmt.Sprintf("🔓 <b>%s</b>\n\nSend the current PDF password. The message with the password will be deleted.", name),
+		)
+	}
+	return pick(lang,
+		fmt.Sprintf("🔒 <b>%s</b>\n\nОтправьте пароль, который нужно установить на PDF. Сообщение с паролем будет удалено.", name),
+		fmt.Sprintf("🔒 <b>%s</b>\n\nSend the password to set on the PDF. The message with the password will be deleted.", name),
+	)
+}
+
+func PdfPasswordInvalid(lang i18n.Lang) string {
+	return pick(lang,
+		"🚫 Пароль должен быть одной строкой не длиннее 128 символов. Отправьте другой пароль.",
+		"🚫 The password must be a single line of at most 128 characters. Send another password.",
+	)
+}
+
+func VideoSubsWaiting(lang i18n.Lang, mode string, fileName string) string {
+	name := Escape(fileName)
+	if mode == "burn" {
+		return pick(lang,
+			fmt.Sprintf("🔥 <b>%s</b>\n\nОтправьте файл субтитров (SRT, ASS, VTT, SBV или TTML) — он будет вшит в изображение видео.", name),
+			fmt.Sprintf("🔥 <b>%s</b>\n\nSend a subtitle file (SRT, ASS, VTT, SBV or TTML) to burn it into the video picture.", name),
+		)
+	}
+	return pick(lang,
+		fmt.Sprintf("📎 <b>%s</b>\n\nОтправьте файл субтитров (SRT, ASS, VTT, SBV или TTML) — он будет добавлен отдельной дорожкой, которую можно включить в плеере.", name),
+		fmt.Sprintf("📎 <b>%s</b>\n\nSend a subtitle file (SRT, ASS, VTT, SBV or TTML) to add it as a separate track you can toggle in the player.", name),
+	)
+}
+
+func VideoSubsInvalidFile(lang i18n.Lang) string {
+	return pick(lang,
+		"🚫 Нужен один файл субтитров: SRT, ASS, SSA, VTT, SBV, TTML или DFXP.",
+		"🚫 Please send a single subtitle file: SRT, ASS, SSA, VTT, SBV, TTML or DFXP.",
+	)
+}
+
 func BatchTimeout(lang i18n.Lang, got int, expected int) string {
 	if lang == i18n.RU {
 		return fmt.Sprintf("⏱ Таймер истёк. Получено файлов: <b>%d</b> из <b>%d</b>.", got, expected)
@@ -395,35 +682,70 @@ func BatchTimeout(lang i18n.Lang, got int, expected int) string {
 	return fmt.Sprintf("⏱ Timeout. Received files: <b>%d</b> of <b>%d</b>.", got, expected)
 }
 
-func SubscriptionInfo(lang i18n.Lang, unlimited bool) string {
-	if unlimited {
-		return pick(lang, "💎 <b>Подписка активна</b>\nТариф: безлимит", "💎 <b>Subscription active</b>\nPlan: unlimited")
+func SubscriptionInfo(lang i18n.Lang, plan *types.Plan) string {
+	if plan.Name != types.FreePlan {
+		return pick(lang, "💎 <b>Подписка активна</b>\n", "💎 <b>Subscription active</b>\n") + PlanLine(lang, plan)
 	}
-	return pick(lang, "💎 <b>Подписка не активна</b>\nЧтобы подключить безлимит — напишите @esteticcus", "💎 <b>Subscription inactive</b>\nTo enable unlimited, message @esteticcus")
+	return pick(lang, "💎 <b>Подписка не активна</b>\nЧтобы подключить тариф — откройте /menu или напишите @esteticcus", "💎 <b>Subscription inactive</b>\nTo choose a plan, open /menu or message @esteticcus")
 }
 
-func SubscriptionOffer(lang i18n.Lang) string {
-	return pick(lang,
-		"💎 <b>Подписка</b>\n\n"+
-			"✅ <b>Безграничный лимит на конвертации</b>\n"+
-			"— кредиты не списываются\n"+
-			"— можно конвертировать сколько угодно\n\n"+
-			"⚡ <b>Приоритетная очередь</b>\n"+
-			"— задачи обрабатываются раньше обычных\n\n"+
-			"Цена: <b>150 ₽/мес</b>\n\n"+
-			"Чтобы подключить — нажмите кнопку ниже.",
-		"💎 <b>Subscription</b>\n\n"+
-			"✅ <b>Unlimited conversions</b>\n"+
-			"— credits are not charged\n"+
-			"— convert as much as you need\n\n"+
-			"⚡ <b>Priority queue</b>\n"+
-			"— your tasks are processed before regular ones\n\n"+
-			"Price: <b>150 RUB / month</b>\n\n"+
-			"To subscribe, press the button below.",
-	)
+func PriceList(prices types.Prices) string {
+	parts := make([]string, 0, 2)
+	if amount, ok := prices.Amount("RUB"); ok {
+		parts = append(parts, fmt.Sprintf("%d ₽", amount/100))
+	}
+	if amount, ok := prices.Amount("XTR"); ok {
+		parts = append(parts, fmt.Sprintf("%d ⭐", amount))
+	}
+	return strings.Join(parts, " / ")
+}
+
+func PlanFeatures(lang i18n.Lang, plan *types.Plan) string {
+	lines := make([]string, 0, 4)
+	if plan.Unlimited {
+		lines = append(lines, pick(lang, "✅ безграничный лимит на конвертации", "✅ unlimited conversions"))
+	} else if lang == i18n.RU {
+		lines = append(lines, fmt.Sprintf("✅ %d кредитов в сутки", plan.DailyCredits))
+	} else {
+		lines = append(lines, fmt.Sprintf("✅ %d credits per day", plan.DailyCredits))
+	}
+	if plan.MaxFileSize > 0 {
+		if lang == i18n.RU {
+			lines = append(lines, fmt.Sprintf("📦 файлы до %d МБ", plan.MaxFileSize>>20))
+		} else {
+			lines = append(lines, fmt.Sprintf("📦 files up to %d MB", plan.MaxFileSize>>20))
+		}
+	}
+	if plan.PriorityWeight > 1 {
+		lines = append(lines, pick(lang, "⚡ приоритетная очередь", "⚡ priority queue"))
+	}
+	if len(plan.AllowedCategories) > 0 {
+		lines = append(lines, pick(lang, "📁 категории: ", "📁 categories: ")+Escape(strings.Join(plan.AllowedCategories, ", ")))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func SubscriptionOffer(lang i18n.Lang, plans []types.Plan) string {
+	text := pick(lang, "💎 <b>Подписка</b>", "💎 <b>Subscription</b>")
+	if len(plans) == 0 {
+		return text + "\n\n" + PaymentNotConfigured(lang)
+	}
+	for i := range plans {
+		plan := &plans[i]
+		text += "\n\n<b>" + Escape(plan.Title) + "</b>"
+		if price := PriceList(plan.Prices); price != "" {
+			if lang == i18n.RU {
+				text += fmt.Sprintf(" — %s за %d дн.", price, plan.DurationDays)
+			} else {
+				text += fmt.Sprintf(" — %s for %d days", price, plan.DurationDays)
+			}
+		}
+		text += "\n" + PlanFeatures(lang, plan)
+	}
+	return text + pick(lang, "\n\nЧтобы подключить — выберите тариф ниже.", "\n\nTo subscribe, choose a plan below.")
 }
 
-func SubscriptionActiveDetails(lang i18n.Lang, expiresAt *time.Time) string {
+func SubscriptionActiveDetails(lang i18n.Lang, plan *types.Plan, expiresAt *time.Time) string {
 	until := ""
 	if expiresAt != nil {
 		until = expiresAt.UTC().Format("2006-01-02")
@@ -436,18 +758,101 @@ func SubscriptionActiveDetails(lang i18n.Lang, expiresAt *time.Time) string {
 	}
 	if lang == i18n.RU {
 		return "💎 <b>Подписка активна</b>\n\n" +
-			"Тариф: <b>Безлимит</b>\n" +
+			PlanLine(lang, plan) + "\n" +
 			"Активна до: <b>" + Escape(until) + "</b>\n\n" +
 			"Что включено:\n" +
-			"✅ безграничный лимит на конвертации (кредиты не списываются)\n" +
-			"⚡ приоритетная очередь"
+			PlanFeatures(lang, plan)
 	}
 	return "💎 <b>Subscription active</b>\n\n" +
-		"Plan: <b>Unlimited</b>\n" +
+		PlanLine(lang, plan) + "\n" +
 		"Active until: <b>" + Escape(until) + "</b>\n\n" +
 		"Included:\n" +
-		"✅ unlimited conversions (credits are not charged)\n" +
-		"⚡ priority queue"
+		PlanFeatures(lang, plan)
+}
+
+func MenuBtnPlan(plan *types.Plan) string {
+	if price := PriceList(plan.Prices); price != "" {
+		return "💎 " + plan.Title + " — " + price
+	}
+	return "💎 " + plan.Title
+}
+
+func MenuBtnCredits(lang i18n.Lang) string {
+	return pick(lang, "🪙 Купить кредиты", "🪙 Buy credits")
+}
+
+func CreditPacksOffer(lang i18n.Lang, packs []types.CreditPack) string {
+	text := pick(lang,
+		"🪙 <b>Пакеты кредитов</b>\nКупленные кредиты не сгорают при ежедневном обновлении и тратятся после дневных.",
+		"🪙 <b>Credit packs</b>\nPurchased credits survive the daily reset and are spent after the daily ones.",
+	)
+	if len(packs) == 0 {
+		return text + "\n\n" + PaymentNotConfigured(lang)
+	}
+	text += "\n"
+	for i := range packs {
+		text += "\n• <b>" + Escape(packs[i].Title) + "</b> — " + PriceList(packs[i].Prices)
+	}
+	return text
+}
+
+func MenuBtnPack(pack *types.CreditPack) string {
+	if price := PriceList(pack.Prices); price != "" {
+		return "🪙 " + pack.Title + " — " + price
+	}
+	return "🪙 " + pack.Title
+}
+
+func PackInvoiceTitle(lang i18n.Lang, pack *types.CreditPack) string {
+	return pick(lang, "Пакет: ", "Pack: ") + pack.Title
+}
+
+func PackInvoiceDescription(lang i18n.Lang, pack *types.CreditPack) string {
+	if lang == i18n.RU {
+		return fmt.Sprintf("%d кредитов на конвертации, не сгорают при ежедневном обновлении", pack.Credits)
+	}
+	return fmt.Sprintf("%d conversion credits that survive the daily reset", pack.Credits)
+}
+
+func PackPurchased(lang i18n.Lang, credits int, balance *types.Balance) string {
+	if lang == i18n.RU {
+		return fmt.Sprintf("✅ Оплата прошла успешно.\nНачислено кредитов: <b>%d</b>\n", credits) + PurchasedCreditsLine(lang, balance.Purchased)
+	}
+	return fmt.Sprintf("✅ Payment successful.\nCredits added: <b>%d</b>\n", credits) + PurchasedCreditsLine(lang, balance.Purchased)
+}
+
+func SubscriptionExpiringReminder(lang i18n.Lang, planTitle string, expiresAt time.Time, days int) string {
+	until := expiresAt.UTC().Format("2006-01-02 15:04 UTC")
+	if lang == i18n.RU {
+		left := "3 дня"
+		if days <= 1 {
+			left = "1 день"
+		}
+		return fmt.Sprintf("⏳ <b>Подписка скоро закончится</b>\n\nТариф <b>%s</b> действует ещё %s — до %s.\nПродлите сейчас, чтобы не потерять лимиты и приоритет.", Escape(planTitle), left, until)
+	}
+	left := "3 days"
+	if days <= 1 {
+		left = "1 day"
+	}
+	return fmt.Sprintf("⏳ <b>Your subscription ends soon</b>\n\nThe <b>%s</b> plan is active for %s more — until %s.\nRenew now to keep your limits and priority.", Escape(planTitle), left, until)
+}
+
+func SubscriptionExpiredNotice(lang i18n.Lang, planTitle string) string {
+	if lang == i18n.RU {
+		return fmt.Sprintf("⌛ <b>Подписка закончилась</b>\n\nТариф <b>%s</b> истёк, теперь действует бесплатный тариф. Продлить подписку можно кнопкой ниже.", Escape(planTitle))
+	}
+	return fmt.Sprintf("⌛ <b>Your subscription has ended</b>\n\nThe <b>%s</b> plan has expired and you are on the free plan now. You can renew with the button below.", Escape(planTitle))
+}
+
+func PlanInvoiceTitle(lang i18n.Lang, plan *types.Plan) string {
+	return pick(lang, "Подписка ", "Subscription ") + plan.Title
+}
+
+func PlanInvoiceDescription(lang i18n.Lang, plan *types.Plan) string {
+	if lang == i18n.RU {
+		return fmt.Sprintf("Тариф %s на %d дн.", plan.Title, plan.DurationDays)
+	}
+	return fmt.Sprintf("%s plan for %d days", plan.Title, plan.DurationDays)
 }
 
 func PayMethodTitle(lang i18n.Lang) string {
@@ -470,7 +875,10 @@ func PaymentNotConfigured(lang i18n.Lang) string {
 	return pick(lang, "Оплата временно недоступна", "Payments are temporarily unavailable")
 }
 
-func PaymentSucceeded(lang i18n.Lang, until time.Time) string {
+func PaymentSucceeded(lang i18n.Lang, until *time.Time) string {
+	if until == nil {
+		return pick(lang, "✅ Оплата прошла успешно.\nПодписка активна: <b>бессрочно</b>", "✅ Payment successful.\nSubscription active: <b>forever</b>")
+	}
 	if lang == i18n.RU {
 		return fmt.Sprintf("✅ Оплата прошла успешно.\nПодписка активна до: <b>%s</b>", until.Format("2006-01-02"))
 	}
@@ -480,3 +888,152 @@ func PaymentSucceeded(lang i18n.Lang, until time.Time) string {
 func PaymentAlreadyProcessed(lang i18n.Lang) string {
 	return pick(lang, "✅ Платёж уже обработан", "✅ Payment already processed")
 }
+
+func PaymentActivationFailed(lang i18n.Lang, chargeID string) string {
+	return pick(
+		lang,
+		"⚠️ Оплата получена, но зачислить её не удалось. Напишите в поддержку и укажите код платежа: <code>",
+		"⚠️ Payment received, but we could not apply it. Please contact support with this payment ID: <code>",
+	) + Escape(chargeID) + "</code>"
+}
+
+func PaymentForeverPlan(lang i18n.Lang) string {
+	return pick(lang, "У вас уже бессрочная подписка", "You already have a lifetime subscription")
+}
+
+func PromoUsage(lang i18n.Lang) string {
+	return pick(lang, "Использование: <code>/promo КОД</code>", "Usage: <code>/promo CODE</code>")
+}
+
+func PromoNotFound(lang i18n.Lang) string {
+	return pick(lang, "🚫 Промокод не найден", "🚫 Promo code not found")
+}
+
+func PromoInactive(lang i18n.Lang) string {
+	return pick(lang, "🚫 Промокод больше не действует", "🚫 This promo code is no longer valid")
+}
+
+func PromoAlreadyUsed(lang i18n.Lang) string {
+	return pick(lang, "🚫 Вы уже активировали этот промокод", "🚫 You have already redeemed this promo code")
+}
+
+func PromoRedeemedPlan(lang i18n.Lang, planTitle string, until *time.Time) string {
+	if until == nil {
+		return pick(lang, "🎉 Промокод активирован.\nТариф <b>", "🎉 Promo code redeemed.\nPlan <b>") + Escape(planTitle) + pick(lang, "</b>: бессрочно", "</b>: forever")
+	}
+	if lang == i18n.RU {
+		return fmt.Sprintf("🎉 Промокод активирован.\nТариф <b>%s</b> действует до: <b>%s</b>", Escape(planTitle), until.UTC().Format("2006-01-02"))
+	}
+	return fmt.Sprintf("🎉 Promo code redeemed.\nPlan <b>%s</b> is active until: <b>%s</b>", Escape(planTitle), until.UTC().Format("2006-01-02"))
+}
+
+func PromoRedeemedCredits(lang i18n.Lang, credits int, balance *types.Balance) string {
+	if lang == i18n.RU {
+		return fmt.Sprintf("🎉 Промокод активирован.\nНачислено кредитов: <b>%d</b>\n", credits) + PurchasedCreditsLine(lang, balance.Purchased)
+	}
+	return fmt.Sprintf("🎉 Promo code redeemed.\nCredits added: <b>%d</b>\n", credits) + PurchasedCreditsLine(lang, balance.Purchased)
+}
+
+func AdminPromoUsage(lang i18n.Lang) string {
+	return pick(
+		lang,
+		"Использование:\n<code>/promo_codes</code> — список\n<code>/promo_codes create КОД days=30 [plan=unlimited] [credits=100] [uses=1] [expires=2026-12-31]</code>\n<code>/promo_codes disable КОД</code>\n\n<code>uses=unlimited</code> — без ограничения активаций.",
+		"Usage:\n<code>/promo_codes</code> — list\n<code>/promo_codes create CODE days=30 [plan=unlimited] [credits=100] [uses=1] [expires=2026-12-31]</code>\n<code>/promo_codes disable CODE</code>\n\n<code>uses=unlimited</code> means no redemption limit.",
+	)
+}
+
+func promoReward(lang i18n.Lang, p types.PromoCode) string {
+	parts := make([]string, 0, 2)
+	if p.Days > 0 {
+		if lang == i18n.RU {
+			parts = append(parts, fmt.Sprintf("%s на %d дн.", Escape(p.Plan), p.Days))
+		} else {
+			parts = append(parts, fmt.Sprintf("%s for %d days", Escape(p.Plan), p.Days))
+		}
+	}
+	if p.Credits > 0 {
+		if lang == i18n.RU {
+			parts = append(parts, fmt.Sprintf("%d кредитов", p.Credits))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d credits", p.Credits))
+		}
+	}
+	return strings.Join(parts, " + ")
+}
+
+func promoUses(p types.PromoCode) string {
+	if p.MaxUses <= 0 {
+		return fmt.Sprintf("%d/∞", p.UsedCount)
+	}
+	return fmt.Sprintf("%d/%d", p.UsedCount, p.MaxUses)
+}
+
+func AdminPromoCreated(lang i18n.Lang, p types.PromoCode) string {
+	text := pick(lang, "✅ Промокод создан: ", "✅ Promo code created: ") + fmt.Sprintf("<code>%s</code>\n", Escape(p.Code)) +
+		promoReward(lang, p) + "\n" + pick(lang, "Активаций: ", "Redemptions: ") + promoUses(p)
+	if p.ExpiresAt != nil {
+		text += "\n" + pick(lang, "Действует до: ", "Valid until: ") + Escape(p.ExpiresAt.UTC().Format("2006-01-02 15:04 UTC"))
+	}
+	return text
+}
+
+func AdminPromoExists(lang i18n.Lang, code string) string {
+	return pick(lang, "Промокод уже существует: ", "Promo code already exists: ") + fmt.Sprintf("<code>%s</code>", Escape(code))
+}
+
+func AdminPromoList(lang i18n.Lang, codes []types.PromoCode, now time.Time) string {
+	if len(codes) == 0 {
+		return pick(lang, "Промокодов пока нет", "No promo codes yet")
+	}
+	var sb strings.Builder
+	sb.WriteString(pick(lang, "🎟 <b>Промокоды</b>\n", "🎟 <b>Promo codes</b>\n"))
+	for _, p := range codes {
+		status := "✅"
+		if !p.Active(now) {
+			status = "⛔"
+		}
+		sb.WriteString(fmt.Sprintf("\n%s <code>%s</code> %s · %s", status, Escape(p.Code), promoReward(lang, p), promoUses(p)))
+		if p.ExpiresAt != nil {
+			sb.WriteString(" · " + pick(lang, "до ", "until ") + Escape(p.ExpiresAt.UTC().Format("2006-01-02")))
+		}
+		if p.GiftPayment != "" {
+			sb.WriteString(" · 🎁")
+		}
+	}
+	sb.WriteString(pick(lang, "\n\nОтключить: <code>/promo_codes disable КОД</code>", "\n\nDisable: <code>/promo_codes disable CODE</code>"))
+	return sb.String()
+}
+
+func AdminPromoDisabled(lang i18n.Lang, code string) string {
+	return pick(lang, "✅ Промокод отключён: ", "✅ Promo code disabled: ") + fmt.Sprintf("<code>%s</code>", Escape(code))
+}
+
+func AdminPromoNotFound(lang i18n.Lang, code string) string {
+	return pick(lang, "Промокод не найден: ", "Promo code not found: ") + fmt.Sprintf("<code>%s</code>", Escape(code))
+}
+
+func MenuBtnGift(lang i18n.Lang) string {
+	return pick(lang, "🎁 Подарить", "🎁 Buy as a gift")
+}
+
+func GiftMethodTitle(lang i18n.Lang) string {
+	return pick(lang, "🎁 <b>Подписка в подарок</b>\nПосле оплаты вы получите промокод, который можно передать другу.\nВыберите способ оплаты:", "🎁 <b>Gift subscription</b>\nAfter payment you get a promo code to pass on to a friend.\nChoose a payment method:")
+}
+
+func GiftInvoiceTitle(lang i18n.Lang, plan *types.Plan) string {
+	return pick(lang, "Подарок: ", "Gift: ") + plan.Title
+}
+
+func GiftInvoiceDescription(lang i18n.Lang, plan *types.Plan) string {
+	if lang == i18n.RU {
+		return fmt.Sprintf("Промокод на тариф %s на %d дн.", plan.Title, plan.DurationDays)
+	}
+	return fmt.Sprintf("Promo code for the %s plan for %d days", plan.Title, plan.DurationDays)
+}
+
+func GiftPurchased(lang i18n.Lang, code string, plan *types.Plan, expiresAt time.Time) string {
+	if lang == i18n.RU {
+		return fmt.Sprintf("🎁 Оплата прошла успешно.\n\nПромокод на тариф <b>%s</b> (%d дн.):\n<code>%s</code>\n\nПередайте его получателю — активировать можно командой <code>/promo %s</code> до %s.", Escape(plan.Title), plan.DurationDays, Escape(code), Escape(code), expiresAt.UTC().Format("2006-01-02"))
+	}
+	return fmt.Sprintf("🎁 Payment successful.\n\nPromo code for the <b>%s</b> plan (%d days):\n<code>%s</code>\n\nPass it on to the recipient — it can be redeemed with <code>/promo %s</code> until %s.", Escape(plan.Title), plan.DurationDays, Escape(code), Escape(code), expiresAt.UTC().Format("2006-01-02"))
+}
@@ -74,6 +74,7 @@ func (m *Middlewares) CheckTaskMiddleWare(next bot.HandlerFunc) bot.HandlerFunc
 				Username:  username,
 				FirstName: firstName,
 				LastName:  lastName,
+				Lang:      string(lang),
 			})
 		}
 
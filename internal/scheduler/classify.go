@@ -0,0 +1,93 @@
+package scheduler
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/BatmanBruc/bat-bot-convetor/internal/formats"
+	"github.com/BatmanBruc/bat-bot-convetor/types"
+)
+
+const (
+	heavyFileSize      = 50 << 20
+	heavyMediaDuration = 20 * 60
+)
+
+var heavyBackends = map[string]bool{
+	"libreoffice": true,
+	"calibre":     true,
+	"tesseract":   true,
+}
+
+var defaultClassSlots = map[types.ResourceClass]int{
+	types.ResourceHeavy: 1,
+}
+
+func (s *Scheduler) ClassifyTask(task *types.Task) types.ResourceClass {
+	if task == nil {
+		return types.ResourceLight
+	}
+
+	if int64Option(task.Options, "file_size") >= heavyFileSize {
+		return types.ResourceHeavy
+	}
+	if op, _ := task.Options["pdf_op"].(string); op == "ocr" {
+		return types.ResourceHeavy
+	}
+
+	from := strings.ToLower(strings.TrimPrefix(task.OriginalExt, "."))
+	to := strings.ToLower(strings.TrimPrefix(task.TargetExt, "."))
+	fromCategory := formats.GetCategoryByExtension(from)
+	toCategory := formats.GetCategoryByExtension(to)
+	longMedia := int64Option(task.Options, "duration") >= heavyMediaDuration
+
+	if fromCategory == "video" {
+		if op, _ := task.Options["vid_op"].(string); op == "mux_subs" {
+			return lightUnless(longMedia)
+		}
+		if toCategory == "video" || to == "gif" {
+			return types.ResourceHeavy
+		}
+		return lightUnless(longMedia)
+	}
+	if fromCategory == "audio" && longMedia {
+		return types.ResourceHeavy
+	}
+
+	if planner, ok := s.converter.(conversionPlanner); ok {
+		steps, err := planner.Plan(from, to)
+		if err == nil {
+			for _, step := range steps {
+				if heavyBackends[step.Backend] {
+					return types.ResourceHeavy
+				}
+			}
+		}
+	}
+	return types.ResourceLight
+}
+
+func lightUnless(heavy bool) types.ResourceClass {
+	if heavy {
+		return types.ResourceHeavy
+	}
+	return types.ResourceLight
+}
+
+func int64Option(options map[string]interface{}, key string) int64 {
+	if options == nil {
+		return 0
+	}
+	switch v := options[key].(type) {
+	case int:
+		return int64(v)
+	case int64:
+		return v
+	case float64:
+		return int64(v)
+	case string:
+		n, _ := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+		return n
+	}
+	return 0
+}
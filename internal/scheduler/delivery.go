@@ -0,0 +1,180 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BatmanBruc/bat-bot-convetor/internal/converter"
+	"github.com/BatmanBruc/bat-bot-convetor/types"
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+type deliveryKind int
+
+const (
+	deliverDocument deliveryKind = iota
+	deliverPhoto
+	deliverVideo
+	deliverAudio
+	deliverVoice
+	deliverAnimation
+	deliverVideoNote
+)
+
+const maxPhotoSize = 10 << 20
+
+func deliveryKindFor(fileName string) deliveryKind {
+	switch strings.ToLower(strings.TrimPrefix(filepath.Ext(fileName), ".")) {
+	case "jpg", "jpeg", "png":
+		return deliverPhoto
+	case "mp4":
+		return deliverVideo
+	case "mp3", "m4a":
+		return deliverAudio
+	case "ogg", "oga", "opus":
+		return deliverVoice
+	case "gif":
+		return deliverAnimation
+	}
+	return deliverDocument
+}
+
+func requestedDelivery(task *types.Task) (deliveryKind, bool) {
+	if task == nil || task.Options == nil {
+		return deliverDocument, false
+	}
+	media, _ := task.Options["tg_media"].(string)
+	switch media {
+	case "voice":
+		return deliverVoice, true
+	case "video_note":
+		return deliverVideoNote, true
+	}
+	return deliverDocument, false
+}
+
+func isVoiceNote(ctx context.Context, filePath string) bool {
+	info := converter.ProbeMedia(ctx, filePath)
+	return info.Codec == "opus" && info.Channels == 1
+}
+
+func deliverAsFile(task *types.Task) bool {
+	if task == nil || task.Options == nil {
+		return false
+	}
+	mode, _ := task.Options["delivery"].(string)
+	return mode == "file"
+}
+
+func (s *Scheduler) sendResult(ctx context.Context, task *types.Task, chatID int64, filePath string, fileName string, caption string) (*models.Message, error) {
+	kind, requested := requestedDelivery(task)
+	if !requested && !deliverAsFile(task) {
+		kind = deliveryKindFor(fileName)
+	}
+	if kind == deliverPhoto {
+		if info, err := os.Stat(filePath); err != nil || info.Size() > maxPhotoSize {
+			kind = deliverDocument
+		}
+	}
+	if kind == deliverVoice && !requested && !isVoiceNote(ctx, filePath) {
+		kind = deliverDocument
+	}
+
+	if kind != deliverDocument {
+		msg, err := s.sendMedia(ctx, kind, chatID, filePath, fileName, caption)
+		if err == nil {
+			return msg, nil
+		}
+		if ctx.Err() != nil || converter.IsTransient(err) {
+			return nil, err
+		}
+		log.Printf("Telegram rejected %s as media, sending as document: %v", fileName, err)
+	}
+	return s.sendDocumentFromPath(ctx, chatID, filePath, fileName, caption)
+}
+
+func (s *Scheduler) sendMedia(ctx context.Context, kind deliveryKind, chatID int64, filePath string, fileName string, caption string) (*models.Message, error) {
+	var info converter.MediaInfo
+	if kind != deliverPhoto {
+		info = converter.ProbeMedia(ctx, filePath)
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	if strings.TrimSpace(fileName) == "" {
+		fileName = filepath.Base(filePath)
+	}
+	upload := &models.InputFileUpload{Filename: fileName, Data: file}
+
+	switch kind {
+	case deliverPhoto:
+		return s.botClient.SendPhoto(ctx, &bot.SendPhotoParams{
+			ChatID:  chatID,
+			Photo:   upload,
+			Caption: caption,
+		})
+	case deliverVideo:
+		return s.botClient.SendVideo(ctx, &bot.SendVideoParams{
+			ChatID:            chatID,
+			Video:             upload,
+			Duration:          info.Duration,
+			Width:             info.Width,
+			Height:            info.Height,
+			Caption:           caption,
+			SupportsStreaming: true,
+		})
+	case deliverAudio:
+		title := info.Title
+		if title == "" {
+			title = strings.TrimSuffix(fileName, filepath.Ext(fileName))
+		}
+		return s.botClient.SendAudio(ctx, &bot.SendAudioParams{
+			ChatID:    chatID,
+			Audio:     upload,
+			Caption:   caption,
+			Duration:  info.Duration,
+			Performer: info.Performer,
+			Title:     title,
+		})
+	case deliverVoice:
+		return s.botClient.SendVoice(ctx, &bot.SendVoiceParams{
+			ChatID:   chatID,
+			Voice:    upload,
+			Caption:  caption,
+			Duration: info.Duration,
+		})
+	case deliverVideoNote:
+		length := info.Width
+		if info.Height > 0 && (length == 0 || info.Height < length) {
+			length = info.Height
+		}
+		return s.botClient.SendVideoNote(ctx, &bot.SendVideoNoteParams{
+			ChatID:    chatID,
+			VideoNote: upload,
+			Duration:  info.Duration,
+			Length:    length,
+		})
+	case deliverAnimation:
+		return s.botClient.SendAnimation(ctx, &bot.SendAnimationParams{
+			ChatID:    chatID,
+			Animation: upload,
+			Duration:  info.Duration,
+			Width:     info.Width,
+			Height:    info.Height,
+			Caption:   caption,
+		})
+	}
+	return s.botClient.SendDocument(ctx, &bot.SendDocumentParams{
+		ChatID:   chatID,
+		Document: upload,
+		Caption:  caption,
+	})
+}
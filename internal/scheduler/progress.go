@@ -0,0 +1,53 @@
+package scheduler
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	progressInterval = 5 * time.Second
+	progressMinETA   = 3 * time.Second
+)
+
+type progressTracker struct {
+	mu       sync.Mutex
+	started  time.Time
+	fraction float64
+	shown    int
+	editedAt time.Time
+}
+
+func newProgressTracker() *progressTracker {
+	return &progressTracker{started: time.Now()}
+}
+
+func (p *progressTracker) set(fraction float64) {
+	p.mu.Lock()
+	if fraction > p.fraction {
+		p.fraction = fraction
+	}
+	p.mu.Unlock()
+}
+
+func (p *progressTracker) next(now time.Time) (int, time.Duration, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	percent := int(p.fraction * 100)
+	if percent >= 100 {
+		percent = 99
+	}
+	if percent <= p.shown || now.Sub(p.editedAt) < progressInterval {
+		return 0, 0, false
+	}
+	p.shown = percent
+	p.editedAt = now
+
+	eta := time.Duration(0)
+	elapsed := now.Sub(p.started)
+	if p.fraction >= 0.02 && elapsed >= progressMinETA {
+		eta = time.Duration(float64(elapsed) * (1 - p.fraction) / p.fraction)
+	}
+	return percent, eta, true
+}
@@ -2,6 +2,7 @@ package scheduler
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -13,64 +14,105 @@ import (
 	"github.com/BatmanBruc/bat-bot-convetor/internal/converter"
 	"github.com/BatmanBruc/bat-bot-convetor/internal/i18n"
 	"github.com/BatmanBruc/bat-bot-convetor/internal/messages"
+	"github.com/BatmanBruc/bat-bot-convetor/internal/utils"
 	"github.com/BatmanBruc/bat-bot-convetor/types"
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
 )
 
+const (
+	jobLease       = 2 * time.Minute
+	leaseHeartbeat = 30 * time.Second
+	sweepInterval  = 15 * time.Second
+	pollInterval   = time.Second
+	maxJobAttempts = 3
+	workerTTL      = 45 * time.Second
+	workerBeat     = 15 * time.Second
+	cancelPoll     = 2 * time.Second
+	taskTimeout    = 10 * time.Minute
+	retryBaseDelay = 15 * time.Second
+	retryMaxDelay  = 5 * time.Minute
+)
+
+type jobOutcome int
+
+const (
+	jobDone jobOutcome = iota
+	jobCancelled
+	jobInterrupted
+	jobRetry
+	jobExhausted
+)
+
 type Scheduler struct {
-	store      types.TaskStore
-	converter  converter.Converter
-	botClient  *bot.Bot
-	workers    int
-	ctx        context.Context
-	cancel     context.CancelFunc
-	wg         sync.WaitGroup
-	mu         sync.Mutex
-	running    bool
-	taskQueueP chan string
-	taskQueueN chan string
-	inFlight   map[string]*inFlightEntry
-	inFlightMu sync.RWMutex
-	heavySem   chan struct{}
-}
-
-type inFlightEntry struct {
-	chatID    int64
-	messageID int
-	position  int
-	fileName  string
-	lang      i18n.Lang
+	store     types.TaskStore
+	queue     types.JobQueue
+	converter converter.Converter
+	botClient *bot.Bot
+	workers   int
+	ctx       context.Context
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+	mu        sync.Mutex
+	running   bool
+	workerID  string
+	perUser   int
+	wake      chan struct{}
+	pools     map[types.ResourceClass]chan struct{}
+	refunds   types.RefundQueue
+}
+
+type conversionPlanner interface {
+	Plan(from, to string) ([]converter.Step, error)
 }
 
 type Config struct {
-	Workers int
+	Workers    int
+	WorkerID   string
+	MaxPerUser int
+	ClassSlots map[types.ResourceClass]int
+	Refunds    types.RefundQueue
 }
 
-func NewScheduler(store types.TaskStore, converter converter.Converter, botClient *bot.Bot, config Config) *Scheduler {
+func NewScheduler(store types.TaskStore, queue types.JobQueue, converter converter.Converter, botClient *bot.Bot, config Config) *Scheduler {
 	if config.Workers <= 0 {
 		config.Workers = 3
 	}
 
-	ctx, cancel := context.WithCancel(context.Background())
+	if config.MaxPerUser <= 0 {
+		config.MaxPerUser = 2
+	}
+	if config.ClassSlots == nil {
+		config.ClassSlots = defaultClassSlots
+	}
+	pools := make(map[types.ResourceClass]chan struct{})
+	for class, slots := range config.ClassSlots {
+		if slots > 0 && slots < config.Workers {
+			pools[class] = make(chan struct{}, slots)
+		}
+	}
 
-	queueSize := config.Workers * 2
-	if queueSize < 10 {
-		queueSize = 10
+	if config.WorkerID == "" {
+		host, _ := os.Hostname()
+		config.WorkerID = fmt.Sprintf("%s-%d", host, os.Getpid())
 	}
 
+	ctx, cancel := context.WithCancel(context.Background())
+
 	return &Scheduler{
-		store:      store,
-		converter:  converter,
-		botClient:  botClient,
-		workers:    config.Workers,
-		ctx:        ctx,
-		cancel:     cancel,
-		running:    false,
-		taskQueueP: make(chan string, queueSize),
-		taskQueueN: make(chan string, queueSize),
-		inFlight:   make(map[string]*inFlightEntry),
-		heavySem:   make(chan struct{}, 1),
+		store:     store,
+		queue:     queue,
+		converter: converter,
+		botClient: botClient,
+		workers:   config.Workers,
+		ctx:       ctx,
+		cancel:    cancel,
+		running:   false,
+		workerID:  config.WorkerID,
+		perUser:   config.MaxPerUser,
+		wake:      make(chan struct{}, config.Workers),
+		pools:     pools,
+		refunds:   config.Refunds,
 	}
 }
 
@@ -83,61 +125,20 @@ func (s *Scheduler) Start() {
 	s.running = true
 	s.mu.Unlock()
 
-	log.Printf("Scheduler started with %d workers", s.workers)
+	log.Printf("Scheduler %s started with %d workers", s.workerID, s.workers)
+
+	s.wg.Add(1)
+	go s.heartbeat()
 
 	for i := 0; i < s.workers; i++ {
 		s.wg.Add(1)
 		go s.worker(i)
 	}
 
-	go s.recoverProcessingTasks()
-}
+	s.wg.Add(1)
+	go s.sweeper()
 
-func (s *Scheduler) recoverProcessingTasks() {
-	tasks, err := s.store.GetProcessingTasks()
-	if err != nil {
-		log.Printf("Scheduler recovery: failed to list processing tasks: %v", err)
-		return
-	}
-
-	if len(tasks) == 0 {
-		return
-	}
-
-	enqueued := 0
-	skipped := 0
-	for _, task := range tasks {
-		if task == nil {
-			continue
-		}
-		if strings.TrimSpace(task.TargetExt) == "" {
-			skipped++
-			continue
-		}
-		s.EnqueueTask(task.ID, 0, 0, task.FileName, langFromTask(task), priorityFromTask(task))
-		enqueued++
-	}
-
-	if enqueued > 0 || skipped > 0 {
-		log.Printf("Scheduler recovery: enqueued=%d skipped=%d (processing tasks=%d)", enqueued, skipped, len(tasks))
-	}
-}
-
-func priorityFromTask(task *types.Task) bool {
-	if task == nil || task.Options == nil {
-		return false
-	}
-	if v, ok := task.Options["priority"]; ok {
-		if b, ok := v.(bool); ok {
-			return b
-		}
-	}
-	if v, ok := task.Options["unlimited"]; ok {
-		if b, ok := v.(bool); ok && b {
-			return true
-		}
-	}
-	return false
+	go s.refreshQueueMessages()
 }
 
 func langFromTask(task *types.Task) i18n.Lang {
@@ -164,61 +165,79 @@ func (s *Scheduler) Stop() {
 	log.Println("Stopping scheduler...")
 	s.cancel()
 	s.wg.Wait()
+	if err := s.queue.UnregisterWorker(s.workerID); err != nil {
+		log.Printf("Queue: failed to unregister worker %s: %v", s.workerID, err)
+	}
 	log.Println("Scheduler stopped")
 }
 
-func (s *Scheduler) EnqueueTask(taskID string, chatID int64, messageID int, fileName string, lang i18n.Lang, priority bool) int {
-	s.inFlightMu.Lock()
-	if _, exists := s.inFlight[taskID]; exists {
-		s.inFlightMu.Unlock()
-		return -1
+func (s *Scheduler) EnqueueTask(taskID string, userID int64, chatID int64, messageID int, fileName string, lang i18n.Lang, weight int) (int, error) {
+	if weight <= 0 {
+		weight = 1
+	}
+	class := types.ResourceLight
+	if task, err := s.store.GetTask(taskID); err == nil {
+		class = s.ClassifyTask(task)
 	}
 
-	running := 0
-	maxPos := 0
-	for _, e := range s.inFlight {
-		if e == nil {
-			continue
-		}
-		if e.position == 0 {
-			running++
-			continue
-		}
-		if e.position > maxPos {
-			maxPos = e.position
-		}
+	pos, err := s.queue.Enqueue(&types.QueuedJob{
+		TaskID:    taskID,
+		UserID:    userID,
+		ChatID:    chatID,
+		MessageID: messageID,
+		FileName:  fileName,
+		Lang:      string(lang),
+		Weight:    weight,
+		Class:     class,
+	})
+	if err != nil {
+		return 0, err
+	}
+	if pos.Duplicate {
+		return -1, nil
 	}
 
+	capacity, err := s.queue.Capacity()
+	if err != nil {
+		log.Printf("Queue: failed to read worker capacity: %v", err)
+	}
 	position := 0
-	if running >= s.workers {
-		position = maxPos + 1
+	if pos.Ahead > 0 || pos.Active >= capacity {
+		position = pos.Ahead + 1
 	}
-
-	s.inFlight[taskID] = &inFlightEntry{
-		chatID:    chatID,
-		messageID: messageID,
-		position:  position,
-		fileName:  fileName,
-		lang:      lang,
+	if _, err := s.queue.MarkShown(taskID, position); err != nil {
+		log.Printf("Queue: failed to save position for task %s: %v", taskID, err)
 	}
-	s.inFlightMu.Unlock()
 
-	go func() {
+	s.notify()
+	go s.refreshQueueMessages()
+	return position, nil
+}
+
+func (s *Scheduler) heartbeat() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(workerBeat)
+	defer ticker.Stop()
+
+	for {
+		if err := s.queue.RegisterWorker(s.workerID, s.workers, workerTTL); err != nil {
+			log.Printf("Queue: failed to register worker %s: %v", s.workerID, err)
+		}
+
 		select {
-		case func() chan string {
-			if priority {
-				return s.taskQueueP
-			}
-			return s.taskQueueN
-		}() <- taskID:
 		case <-s.ctx.Done():
-			s.inFlightMu.Lock()
-			delete(s.inFlight, taskID)
-			s.inFlightMu.Unlock()
+			return
+		case <-ticker.C:
 		}
-	}()
+	}
+}
 
-	return position
+func (s *Scheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
 }
 
 func (s *Scheduler) worker(id int) {
@@ -227,145 +246,523 @@ func (s *Scheduler) worker(id int) {
 	log.Printf("Worker %d started", id)
 
 	for {
-		var taskID string
 		select {
 		case <-s.ctx.Done():
 			log.Printf("Worker %d stopped", id)
 			return
-		case taskID = <-s.taskQueueP:
 		default:
+		}
+
+		job, err := s.queue.Dequeue(jobLease, s.perUser, s.fullClasses())
+		if err != nil {
+			log.Printf("Worker %d: error reading queue: %v", id, err)
+		}
+		var release func()
+		if job != nil {
+			var ok bool
+			if release, ok = s.acquireSlot(job); !ok {
+				if err := s.queue.Release(job.TaskID); err != nil {
+					log.Printf("Worker %d: error returning task %s to the queue: %v", id, job.TaskID, err)
+				}
+				job = nil
+			}
+		}
+		if job == nil {
 			select {
 			case <-s.ctx.Done():
 				log.Printf("Worker %d stopped", id)
 				return
-			case taskID = <-s.taskQueueP:
-			case taskID = <-s.taskQueueN:
+			case <-s.wake:
+			case <-time.After(pollInterval):
 			}
+			continue
 		}
 
-		if strings.TrimSpace(taskID) == "" {
+		outcome, jobErr := s.runJob(id, job)
+		release()
+		switch outcome {
+		case jobInterrupted:
+			continue
+		case jobRetry:
+			s.retryJob(job, jobErr)
 			continue
+		case jobExhausted:
+			if err := s.queue.Bury(job, jobErr.Error()); err != nil {
+				log.Printf("Worker %d: error moving task %s to dead letters: %v", id, job.TaskID, err)
+			}
+		default:
+			if err := s.queue.Ack(job.TaskID); err != nil {
+				log.Printf("Worker %d: error acking task %s: %v", id, job.TaskID, err)
+			}
 		}
 
-		task, err := s.store.GetTask(taskID)
-		if err != nil {
-			log.Printf("Worker %d: error getting task %s: %v", id, taskID, err)
-			s.inFlightMu.Lock()
-			delete(s.inFlight, taskID)
-			s.inFlightMu.Unlock()
-			continue
+		if outcome != jobCancelled {
+			s.deleteStatusMessage(job)
+		}
+		go s.refreshQueueMessages()
+	}
+}
+
+func (s *Scheduler) fullClasses() []types.ResourceClass {
+	var full []types.ResourceClass
+	for class, pool := range s.pools {
+		if len(pool) >= cap(pool) {
+			full = append(full, class)
+		}
+	}
+	return full
+}
+
+func (s *Scheduler) acquireSlot(job *types.QueuedJob) (func(), bool) {
+	if job.Class == "" {
+		if task, err := s.store.GetTask(job.TaskID); err == nil {
+			job.Class = s.ClassifyTask(task)
+		}
+	}
+	pool, ok := s.pools[job.Class]
+	if !ok {
+		return func() {}, true
+	}
+	select {
+	case pool <- struct{}{}:
+		return func() {
+			<-pool
+			s.notify()
+		}, true
+	default:
+		return nil, false
+	}
+}
+
+func (s *Scheduler) runJob(id int, job *types.QueuedJob) (jobOutcome, error) {
+	task, err := s.store.GetTask(job.TaskID)
+	if errors.Is(err, types.ErrTaskNotFound) {
+		log.Printf("Worker %d: task %s no longer exists, dropping it from the queue", id, job.TaskID)
+		return jobDone, err
+	}
+	if err != nil {
+		log.Printf("Worker %d: error getting task %s: %v", id, job.TaskID, err)
+		if job.Attempts < maxJobAttempts {
+			return jobRetry, err
+		}
+		return jobExhausted, err
+	}
+	if task.State == types.StateCancelled {
+		log.Printf("Worker %d: task %s was cancelled, dropping it from the queue", id, task.ID)
+		s.refundTask(task)
+		s.scrubTaskSecrets(task.ID)
+		return jobCancelled, nil
+	}
+	if task.State != types.StateProcessing {
+		log.Printf("Worker %d: task %s is %s, dropping it from the queue", id, task.ID, task.State)
+		s.scrubTaskSecrets(task.ID)
+		return jobDone, nil
+	}
+	if job.Attempts > 1 {
+		log.Printf("Worker %d: retrying task %s (attempt %d)", id, task.ID, job.Attempts)
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, taskTimeout)
+	defer cancel()
+
+	progress := newProgressTracker()
+	ctx = converter.WithProgress(ctx, progress.set)
+
+	stop := s.watchJob(job, cancel, progress)
+	defer stop()
+
+	s.markStarted(job)
+
+	err = s.processTask(ctx, task)
+	if err == nil {
+		s.scrubTaskSecrets(task.ID)
+		return jobDone, nil
+	}
+	if s.ctx.Err() != nil {
+		log.Printf("Worker %d: scheduler stopping, leaving task %s for redelivery", id, task.ID)
+		return jobInterrupted, err
+	}
+	if errors.Is(ctx.Err(), context.Canceled) {
+		if cancelled, err := s.store.GetTask(task.ID); err == nil {
+			s.refundTask(cancelled)
 		}
+		s.scrubTaskSecrets(task.ID)
+		return jobCancelled, err
+	}
+
+	transient := converter.IsTransient(err)
+	if transient && job.Attempts < maxJobAttempts {
+		log.Printf("Worker %d: transient error on task %s (attempt %d): %v", id, task.ID, job.Attempts, err)
+		return jobRetry, err
+	}
+	log.Printf("Worker %d: error processing task %s: %v", id, task.ID, err)
+	s.failTask(task, err)
+	s.scrubTaskSecrets(task.ID)
+	if transient {
+		return jobExhausted, err
+	}
+	return jobDone, err
+}
 
-		isHeavy := s.isHeavyTask(task)
-		if isHeavy {
+func (s *Scheduler) failTask(task *types.Task, err error) {
+	s.refundTask(task)
+	if err := s.store.SetTaskError(task.ID, err.Error()); err != nil {
+		log.Printf("Error setting task error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, _ = s.botClient.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    task.UserID,
+		Text:      messages.ErrorConversionFailed(langFromTask(task), task.FileName, err),
+		ParseMode: messages.ParseModeHTML,
+	})
+}
+
+func (s *Scheduler) refundTask(task *types.Task) {
+	credits := int(int64Option(task.Options, "credits_charged"))
+	if credits <= 0 || s.refunds == nil {
+		return
+	}
+	err := s.refunds.PushRefund(types.CreditRefund{
+		UserID:    task.UserID,
+		TaskID:    task.ID,
+		Credits:   credits,
+		Purchased: int(int64Option(task.Options, "credits_purchased")),
+	})
+	if err != nil {
+		log.Printf("Billing: failed to queue refund for task %s: %v", task.ID, err)
+		return
+	}
+	delete(task.Options, "credits_charged")
+	delete(task.Options, "credits_purchased")
+	if err := s.store.UpdateTask(task); err != nil {
+		log.Printf("Error saving refund for task %s: %v", task.ID, err)
+	}
+}
+
+func retryDelay(attempt int, err error) time.Duration {
+	delay := retryBaseDelay
+	for i := 1; i < attempt && delay < retryMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > retryMaxDelay {
+		delay = retryMaxDelay
+	}
+	if after := converter.RetryAfter(err); after > delay {
+		delay = after
+	}
+	return delay
+}
+
+func (s *Scheduler) retryJob(job *types.QueuedJob, err error) {
+	delay := retryDelay(job.Attempts, err)
+	if err := s.queue.Retry(job.TaskID, delay); err != nil {
+		log.Printf("Queue: failed to schedule retry for task %s: %v", job.TaskID, err)
+		return
+	}
+	log.Printf("Queue: task %s will be retried in %s", job.TaskID, delay)
+
+	if job.ChatID == 0 || job.MessageID == 0 {
+		return
+	}
+	lang := i18n.Parse(job.Lang)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, editErr := s.botClient.EditMessageText(ctx, &bot.EditMessageTextParams{
+		ChatID:      job.ChatID,
+		MessageID:   job.MessageID,
+		Text:        withTaskType(lang, job, messages.QueueRetrying(lang, queueFileName(job), delay)),
+		ParseMode:   messages.ParseModeHTML,
+		ReplyMarkup: utils.CancelKeyboard(lang, job.TaskID),
+	})
+	if editErr != nil {
+		log.Printf("Queue update: failed to edit message chat=%d msg=%d: %v", job.ChatID, job.MessageID, editErr)
+	}
+}
+
+func (s *Scheduler) watchJob(job *types.QueuedJob, cancel context.CancelFunc, progress *progressTracker) func() {
+	taskID := job.TaskID
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(cancelPoll)
+		defer ticker.Stop()
+		extended := time.Now()
+		for {
 			select {
-			case s.heavySem <- struct{}{}:
-			case <-s.ctx.Done():
-				s.inFlightMu.Lock()
-				delete(s.inFlight, taskID)
-				s.inFlightMu.Unlock()
+			case <-done:
 				return
+			case <-ticker.C:
 			}
-		}
 
-		func() {
-			defer func() {
-				if isHeavy {
-					<-s.heavySem
+			if time.Since(extended) >= leaseHeartbeat {
+				if err := s.queue.Extend(taskID, jobLease); err != nil {
+					log.Printf("Queue: failed to extend lease for task %s: %v", taskID, err)
 				}
-			}()
+				extended = time.Now()
+			}
 
-			if err := s.processTask(task); err != nil {
-				log.Printf("Worker %d: error processing task %s: %v", id, taskID, err)
+			task, err := s.store.GetTask(taskID)
+			if err == nil && task.State == types.StateCancelled {
+				log.Printf("Task %s cancelled by user", taskID)
+				cancel()
+				return
 			}
-		}()
-
-		var entry *inFlightEntry
-		s.inFlightMu.RLock()
-		entry = s.inFlight[taskID]
-		s.inFlightMu.RUnlock()
-		if entry != nil && entry.chatID != 0 && entry.messageID != 0 {
-			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-			_, err := s.botClient.DeleteMessage(ctx, &bot.DeleteMessageParams{
-				ChatID:    entry.chatID,
-				MessageID: entry.messageID,
-			})
-			cancel()
-			if err != nil {
-				log.Printf("Failed to delete status message chat=%d msg=%d: %v", entry.chatID, entry.messageID, err)
+
+			if percent, eta, ok := progress.next(time.Now()); ok {
+				s.showProgress(job, percent, eta)
 			}
 		}
+	}()
+	return func() { close(done) }
+}
+
+func (s *Scheduler) CancelTask(taskID string) (bool, error) {
+	removed, err := s.queue.Remove(taskID)
+	if err != nil {
+		return false, err
+	}
+	if removed {
+		go s.refreshQueueMessages()
+	}
+	return removed, nil
+}
+
+func (s *Scheduler) DeadLetters(limit int) ([]*types.DeadLetter, error) {
+	return s.queue.DeadLetters(limit)
+}
+
+func (s *Scheduler) RedriveTask(taskID string) (bool, error) {
+	letter, err := s.queue.TakeDeadLetter(taskID)
+	if err != nil || letter == nil {
+		return false, err
+	}
+
+	task, err := s.store.GetTask(taskID)
+	if err != nil {
+		if buryErr := s.queue.Bury(letter.Job, letter.Reason); buryErr != nil {
+			log.Printf("Queue: failed to restore dead letter %s: %v", taskID, buryErr)
+		}
+		return false, err
+	}
+	if op, _ := task.Options["pdf_op"].(string); op == "encrypt" || op == "decrypt" {
+		if buryErr := s.queue.Bury(letter.Job, letter.Reason); buryErr != nil {
+			log.Printf("Queue: failed to restore dead letter %s: %v", taskID, buryErr)
+		}
+		return false, types.ErrRedriveNeedsPassword
+	}
+	task.State = types.StateProcessing
+	task.Error = ""
+	if err := s.store.UpdateTask(task); err != nil {
+		return false, err
+	}
+
+	job := *letter.Job
+	job.Attempts = 0
+	job.MessageID = 0
+	job.EnqueuedAt = time.Time{}
+	if _, err := s.queue.Enqueue(&job); err != nil {
+		return false, err
+	}
+	log.Printf("Queue: task %s re-enqueued from dead letters", taskID)
+
+	s.notify()
+	go s.refreshQueueMessages()
+	return true, nil
+}
+
+func (s *Scheduler) sweeper() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+
+	for {
+		s.requeueExpired()
+		s.promoteDelayed()
+
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
 
-		s.inFlightMu.Lock()
-		delete(s.inFlight, taskID)
-		s.inFlightMu.Unlock()
+func (s *Scheduler) requeueExpired() {
+	requeued, abandoned, err := s.queue.RequeueExpired(maxJobAttempts)
+	if err != nil {
+		log.Printf("Queue: failed to requeue expired jobs: %v", err)
+		return
+	}
 
-		go s.decrementQueueAndUpdateMessages()
+	if len(requeued) > 0 {
+		log.Printf("Queue: requeued %d expired jobs: %v", len(requeued), requeued)
+		for range requeued {
+			s.notify()
+		}
+		go s.refreshQueueMessages()
+	}
+
+	for _, job := range abandoned {
+		s.abandonJob(job)
 	}
 }
 
-func (s *Scheduler) isHeavyTask(task *types.Task) bool {
-	if task == nil {
-		return false
+func (s *Scheduler) promoteDelayed() {
+	promoted, err := s.queue.PromoteDelayed()
+	if err != nil {
+		log.Printf("Queue: failed to promote delayed jobs: %v", err)
+		return
+	}
+	if len(promoted) == 0 {
+		return
 	}
+	log.Printf("Queue: %d delayed jobs are ready for retry: %v", len(promoted), promoted)
+	for range promoted {
+		s.notify()
+	}
+	go s.refreshQueueMessages()
+}
 
-	if task.Options != nil {
-		if v, ok := task.Options["is_heavy"]; ok {
-			switch t := v.(type) {
-			case bool:
-				return t
-			case string:
-				return strings.EqualFold(strings.TrimSpace(t), "true")
-			}
+func (s *Scheduler) abandonJob(job *types.QueuedJob) {
+	log.Printf("Queue: task %s abandoned after %d attempts", job.TaskID, maxJobAttempts)
+
+	s.deleteStatusMessage(job)
+
+	chatID := job.ChatID
+	task, err := s.store.GetTask(job.TaskID)
+	if err == nil && task != nil {
+		if task.State != types.StateProcessing {
+			return
 		}
+		chatID = task.UserID
+		s.refundTask(task)
+		s.scrubTaskSecrets(task.ID)
+		reason := fmt.Sprintf("abandoned after %d attempts", maxJobAttempts)
+		if err := s.store.SetTaskError(task.ID, reason); err != nil {
+			log.Printf("Error setting task error: %v", err)
+		}
+		if err := s.queue.Bury(job, reason); err != nil {
+			log.Printf("Queue: failed to move task %s to dead letters: %v", job.TaskID, err)
+		}
+	}
+	if chatID == 0 {
+		return
 	}
-	return false
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, _ = s.botClient.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    chatID,
+		Text:      messages.QueueJobAbandoned(i18n.Parse(job.Lang), job.FileName),
+		ParseMode: messages.ParseModeHTML,
+	})
 }
 
-func (s *Scheduler) decrementQueueAndUpdateMessages() {
+func (s *Scheduler) deleteStatusMessage(job *types.QueuedJob) {
+	if job.ChatID == 0 || job.MessageID == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err := s.botClient.DeleteMessage(ctx, &bot.DeleteMessageParams{
+		ChatID:    job.ChatID,
+		MessageID: job.MessageID,
+	})
+	if err != nil {
+		log.Printf("Failed to delete status message chat=%d msg=%d: %v", job.ChatID, job.MessageID, err)
+	}
+}
+
+func queueFileName(job *types.QueuedJob) string {
+	name := strings.TrimSpace(job.FileName)
+	if name == "" {
+		name = "файл"
+	}
+	return name
+}
+
+func withTaskType(lang i18n.Lang, job *types.QueuedJob, text string) string {
+	return text + "\n" + messages.TaskTypeLine(lang, job.Class == types.ResourceHeavy)
+}
+
+func (s *Scheduler) markStarted(job *types.QueuedJob) {
+	if job.ChatID == 0 || job.MessageID == 0 {
+		return
+	}
+	changed, err := s.queue.MarkShown(job.TaskID, 0)
+	if err != nil || !changed {
+		return
+	}
+
+	lang := i18n.Parse(job.Lang)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err = s.botClient.EditMessageText(ctx, &bot.EditMessageTextParams{
+		ChatID:      job.ChatID,
+		MessageID:   job.MessageID,
+		Text:        withTaskType(lang, job, messages.QueueStarted(lang, queueFileName(job))),
+		ParseMode:   messages.ParseModeHTML,
+		ReplyMarkup: utils.CancelKeyboard(lang, job.TaskID),
+	})
+	if err != nil {
+		log.Printf("Queue update: failed to edit message chat=%d msg=%d: %v", job.ChatID, job.MessageID, err)
+	}
+}
+
+func (s *Scheduler) showProgress(job *types.QueuedJob, percent int, eta time.Duration) {
+	if job.ChatID == 0 || job.MessageID == 0 {
+		return
+	}
+
+	lang := i18n.Parse(job.Lang)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err := s.botClient.EditMessageText(ctx, &bot.EditMessageTextParams{
+		ChatID:      job.ChatID,
+		MessageID:   job.MessageID,
+		Text:        withTaskType(lang, job, messages.QueueProgress(lang, queueFileName(job), percent, eta)),
+		ParseMode:   messages.ParseModeHTML,
+		ReplyMarkup: utils.CancelKeyboard(lang, job.TaskID),
+	})
+	if err != nil {
+		log.Printf("Progress update: failed to edit message chat=%d msg=%d: %v", job.ChatID, job.MessageID, err)
+	}
+}
+
+func (s *Scheduler) refreshQueueMessages() {
+	jobs, err := s.queue.Waiting()
+	if err != nil {
+		log.Printf("Queue update: failed to read waiting jobs: %v", err)
+		return
+	}
+
 	type upd struct {
 		chatID    int64
 		messageID int
 		text      string
+		markup    models.ReplyMarkup
 	}
 	updates := make([]upd, 0)
 
-	s.inFlightMu.Lock()
-	for _, entry := range s.inFlight {
-		if entry == nil {
+	for i, job := range jobs {
+		if job.ChatID == 0 || job.MessageID == 0 {
 			continue
 		}
-
-		if entry.position == 0 {
+		position := i + 1
+		changed, err := s.queue.MarkShown(job.TaskID, position)
+		if err != nil || !changed {
 			continue
 		}
-
-		entry.position--
-
-		if entry.chatID == 0 || entry.messageID == 0 {
-			continue
-		}
-
-		name := strings.TrimSpace(entry.fileName)
-		if name == "" {
-			name = "файл"
-		}
-
-		if entry.position == 0 {
-			updates = append(updates, upd{
-				chatID:    entry.chatID,
-				messageID: entry.messageID,
-				text:      messages.QueueStarted(entry.lang, name),
-			})
-		} else {
-			updates = append(updates, upd{
-				chatID:    entry.chatID,
-				messageID: entry.messageID,
-				text:      messages.QueueQueued(entry.lang, name, entry.position),
-			})
-		}
+		lang := i18n.Parse(job.Lang)
+		updates = append(updates, upd{
+			chatID:    job.ChatID,
+			messageID: job.MessageID,
+			text:      withTaskType(lang, job, messages.QueueQueued(lang, queueFileName(job), position)),
+			markup:    utils.CancelKeyboard(lang, job.TaskID),
+		})
 	}
-	s.inFlightMu.Unlock()
 
 	if len(updates) == 0 {
 		return
@@ -376,10 +773,11 @@ func (s *Scheduler) decrementQueueAndUpdateMessages() {
 
 	for _, u := range updates {
 		_, err := s.botClient.EditMessageText(ctx, &bot.EditMessageTextParams{
-			ChatID:    u.chatID,
-			MessageID: u.messageID,
-			Text:      u.text,
-			ParseMode: messages.ParseModeHTML,
+			ChatID:      u.chatID,
+			MessageID:   u.messageID,
+			Text:        u.text,
+			ParseMode:   messages.ParseModeHTML,
+			ReplyMarkup: u.markup,
 		})
 		if err != nil {
 			log.Printf("Queue update: failed to edit message chat=%d msg=%d: %v", u.chatID, u.messageID, err)
@@ -387,38 +785,27 @@ func (s *Scheduler) decrementQueueAndUpdateMessages() {
 	}
 }
 
-func (s *Scheduler) processTask(task *types.Task) error {
+func (s *Scheduler) processTask(ctx context.Context, task *types.Task) error {
 	log.Printf("Processing task %s: %s -> %s", task.ID, task.OriginalExt, task.TargetExt)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
-	defer cancel()
-	lang := langFromTask(task)
+	s.recordConversionChain(task)
 
-	resultPath, outName, err := s.converter.Convert(ctx, s.botClient, task.FileID, task.OriginalExt, task.TargetExt, task.FileName, task.Options)
+	resultPath, outName, err := s.converter.Convert(ctx, s.botClient, task.FileID, task.OriginalExt, task.TargetExt, task.FileName, task.Inputs, task.Options)
 	if err != nil {
-		if err := s.store.SetTaskError(task.ID, err.Error()); err != nil {
-			log.Printf("Error setting task error: %v", err)
+		if errors.Is(ctx.Err(), context.Canceled) {
+			log.Printf("Task %s: conversion stopped after cancellation", task.ID)
 		}
-
-		chatID := task.UserID
-		s.botClient.SendMessage(ctx, &bot.SendMessageParams{
-			ChatID:    chatID,
-			Text:      messages.ErrorConversionFailed(lang, task.FileName, err),
-			ParseMode: messages.ParseModeHTML,
-		})
-
 		return err
 	}
 
 	caption := s.resultCaption(task, outName)
 
 	chatID := task.UserID
-	msg, err := s.sendDocumentFromPath(ctx, chatID, resultPath, outName, caption)
+	msg, err := s.sendResult(ctx, task, chatID, resultPath, outName, caption)
 	if err != nil {
-		log.Printf("Error sending document: %v", err)
+		log.Printf("Error sending result: %v", err)
 		_ = os.Remove(resultPath)
-		_ = s.store.SetTaskError(task.ID, fmt.Sprintf("send document failed: %v", err))
-		return err
+		return fmt.Errorf("send document failed: %w", err)
 	}
 
 	_ = msg
@@ -437,6 +824,40 @@ func (s *Scheduler) processTask(task *types.Task) error {
 	return nil
 }
 
+func (s *Scheduler) recordConversionChain(task *types.Task) {
+	planner, ok := s.converter.(conversionPlanner)
+	if !ok {
+		return
+	}
+	steps, err := planner.Plan(task.OriginalExt, task.TargetExt)
+	if err != nil || len(steps) < 2 {
+		return
+	}
+	chain := converter.ChainString(steps)
+	log.Printf("Task %s: multi-step conversion %s", task.ID, chain)
+	if task.Options == nil {
+		task.Options = make(map[string]interface{})
+	}
+	task.Options["conversion_chain"] = chain
+	if err := s.store.UpdateTask(task); err != nil {
+		log.Printf("Error saving conversion chain for task %s: %v", task.ID, err)
+	}
+}
+
+func (s *Scheduler) scrubTaskSecrets(taskID string) {
+	task, err := s.store.GetTask(taskID)
+	if err != nil || task == nil || task.Options == nil {
+		return
+	}
+	if _, ok := task.Options["pdf_password"]; !ok {
+		return
+	}
+	delete(task.Options, "pdf_password")
+	if err := s.store.UpdateTask(task); err != nil {
+		log.Printf("Error clearing secrets for task %s: %v", task.ID, err)
+	}
+}
+
 func (s *Scheduler) resultCaption(task *types.Task, fileName string) string {
 	caption := strings.TrimSpace(fileName)
 	if caption == "" {
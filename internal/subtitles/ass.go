@@ -0,0 +1,138 @@
+package subtitles
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const assHeader = `[Script Info]
+ScriptType: v4.00+
+PlayResX: 384
+PlayResY: 288
+WrapStyle: 0
+ScaledBorderAndShadow: yes
+
+[V4+ Styles]
+Format: Name, Fontname, Fontsize, PrimaryColour, SecondaryColour, OutlineColour, BackColour, Bold, Italic, Underline, StrikeOut, ScaleX, ScaleY, Spacing, Angle, BorderStyle, Outline, Shadow, Alignment, MarginL, MarginR, MarginV, Encoding
+Style: Default,Arial,16,&H00FFFFFF,&H000000FF,&H00000000,&H00000000,0,0,0,0,100,100,0,0,1,1,0,2,10,10,10,1
+
+[Events]
+Format: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text
+`
+
+const ssaHeader = `[Script Info]
+ScriptType: v4.00
+PlayResX: 384
+PlayResY: 288
+
+[V4 Styles]
+Format: Name, Fontname, Fontsize, PrimaryColour, SecondaryColour, TertiaryColour, BackColour, Bold, Italic, BorderStyle, Outline, Shadow, Alignment, MarginL, MarginR, MarginV, AlphaLevel, Encoding
+Style: Default,Arial,16,16777215,255,0,0,0,0,1,1,0,2,10,10,10,0,1
+
+[Events]
+Format: Marked, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text
+`
+
+func parseASS(text string) ([]Cue, error) {
+	inEvents := false
+	format := []string{"layer", "start", "end", "style", "name", "marginl", "marginr", "marginv", "effect", "text"}
+	cues := make([]Cue, 0)
+
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") {
+			inEvents = strings.EqualFold(line, "[Events]")
+			continue
+		}
+		if !inEvents {
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "Format":
+			format = format[:0]
+			for _, f := range strings.Split(value, ",") {
+				format = append(format, strings.ToLower(strings.TrimSpace(f)))
+			}
+		case "Dialogue":
+			fields := strings.SplitN(strings.TrimSpace(value), ",", len(format))
+			if len(fields) < len(format) {
+				return nil, fmt.Errorf("неверная строка ASS: %q", line)
+			}
+			cue := Cue{}
+			for i, name := range format {
+				var err error
+				switch name {
+				case "start":
+					cue.Start, err = parseTimestamp(fields[i])
+				case "end":
+					cue.End, err = parseTimestamp(fields[i])
+				case "text":
+					cue.Text = assToText(fields[i])
+				}
+				if err != nil {
+					return nil, err
+				}
+			}
+			if strings.TrimSpace(stripTags(cue.Text)) != "" {
+				cues = append(cues, cue)
+			}
+		}
+	}
+	return cues, nil
+}
+
+var assOverrideRe = regexp.MustCompile(`\{[^}]*\}`)
+
+var assStyleTagRe = regexp.MustCompile(`\\([ibu])([01])`)
+
+func assToText(s string) string {
+	s = assOverrideRe.ReplaceAllStringFunc(s, func(block string) string {
+		out := ""
+		for _, m := range assStyleTagRe.FindAllStringSubmatch(block, -1) {
+			if m[2] == "1" {
+				out += "<" + m[1] + ">"
+			} else {
+				out += "</" + m[1] + ">"
+			}
+		}
+		return out
+	})
+	return strings.NewReplacer(`\N`, "\n", `\n`, "\n", `\h`, " ").Replace(s)
+}
+
+func textToASS(s string) string {
+	s = strings.NewReplacer("{", "(", "}", ")").Replace(s)
+	s = basicTagRe.ReplaceAllStringFunc(keepBasicTags(s), func(tag string) string {
+		if strings.HasPrefix(tag, "</") {
+			return `{\` + tag[2:3] + `0}`
+		}
+		return `{\` + tag[1:2] + `1}`
+	})
+	return strings.ReplaceAll(s, "\n", `\N`)
+}
+
+func formatASSTimestamp(d time.Duration) string {
+	h, m, s, ms := splitDuration(d)
+	return fmt.Sprintf("%d:%02d:%02d.%02d", h, m, s, ms/10)
+}
+
+func writeASS(cues []Cue, ssa bool) []byte {
+	var sb strings.Builder
+	prefix := "0"
+	if ssa {
+		sb.WriteString(ssaHeader)
+		prefix = "Marked=0"
+	} else {
+		sb.WriteString(assHeader)
+	}
+	for _, c := range cues {
+		fmt.Fprintf(&sb, "Dialogue: %s,%s,%s,Default,,0,0,0,,%s\n", prefix, formatASSTimestamp(c.Start), formatASSTimestamp(c.End), textToASS(c.Text))
+	}
+	return []byte(sb.String())
+}
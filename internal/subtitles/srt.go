@@ -0,0 +1,82 @@
+package subtitles
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+func parseCueTiming(line string) (Cue, bool) {
+	start, rest, ok := strings.Cut(line, "-->")
+	if !ok {
+		return Cue{}, false
+	}
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return Cue{}, false
+	}
+	s, err := parseTimestamp(start)
+	if err != nil {
+		return Cue{}, false
+	}
+	e, err := parseTimestamp(fields[0])
+	if err != nil {
+		return Cue{}, false
+	}
+	return Cue{Start: s, End: e}, true
+}
+
+func parseSRT(text string) ([]Cue, error) {
+	cues := make([]Cue, 0)
+	for _, block := range splitBlocks(text) {
+		i := 0
+		if _, err := strconv.Atoi(strings.TrimSpace(block[0])); err == nil && len(block) > 1 {
+			i = 1
+		}
+		cue, ok := parseCueTiming(block[i])
+		if !ok {
+			return nil, fmt.Errorf("неверный блок SRT: %q", strings.Join(block, " / "))
+		}
+		cue.Text = keepBasicTags(strings.Join(block[i+1:], "\n"))
+		cues = append(cues, cue)
+	}
+	return cues, nil
+}
+
+func writeSRT(cues []Cue) []byte {
+	var sb strings.Builder
+	for i, c := range cues {
+		fmt.Fprintf(&sb, "%d\n%s --> %s\n%s\n\n", i+1, formatTimestamp(c.Start, ","), formatTimestamp(c.End, ","), c.Text)
+	}
+	return []byte(sb.String())
+}
+
+func parseSBV(text string) ([]Cue, error) {
+	cues := make([]Cue, 0)
+	for _, block := range splitBlocks(text) {
+		start, end, ok := strings.Cut(block[0], ",")
+		if !ok {
+			return nil, fmt.Errorf("неверный блок SBV: %q", block[0])
+		}
+		s, err := parseTimestamp(start)
+		if err != nil {
+			return nil, err
+		}
+		e, err := parseTimestamp(end)
+		if err != nil {
+			return nil, err
+		}
+		cues = append(cues, Cue{Start: s, End: e, Text: strings.Join(block[1:], "\n")})
+	}
+	return cues, nil
+}
+
+func writeSBV(cues []Cue) []byte {
+	var sb strings.Builder
+	for _, c := range cues {
+		sh, sm, ss, sms := splitDuration(c.Start)
+		eh, em, es, ems := splitDuration(c.End)
+		fmt.Fprintf(&sb, "%d:%02d:%02d.%03d,%d:%02d:%02d.%03d\n%s\n\n", sh, sm, ss, sms, eh, em, es, ems, stripTags(c.Text))
+	}
+	return []byte(sb.String())
+}
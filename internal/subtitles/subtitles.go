@@ -0,0 +1,178 @@
+package subtitles
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+type Cue struct {
+	Start time.Duration
+	End   time.Duration
+	Text  string
+}
+
+func Formats() []string {
+	return []string{"srt", "vtt", "ass", "ssa", "sbv", "ttml", "dfxp"}
+}
+
+func IsFormat(ext string) bool {
+	ext = normalizeExt(ext)
+	for _, f := range Formats() {
+		if f == ext {
+			return true
+		}
+	}
+	return false
+}
+
+func Convert(data []byte, from, to string) ([]byte, error) {
+	cues, err := Parse(data, from)
+	if err != nil {
+		return nil, err
+	}
+	return Write(cues, to)
+}
+
+func Parse(data []byte, format string) ([]Cue, error) {
+	text := decodeText(data)
+	var (
+		cues []Cue
+		err  error
+	)
+	switch normalizeExt(format) {
+	case "srt":
+		cues, err = parseSRT(text)
+	case "vtt":
+		cues, err = parseVTT(text)
+	case "ass", "ssa":
+		cues, err = parseASS(text)
+	case "sbv":
+		cues, err = parseSBV(text)
+	case "ttml", "dfxp":
+		cues, err = parseTTML(text)
+	default:
+		return nil, fmt.Errorf("неподдерживаемый формат субтитров: %s", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(cues) == 0 {
+		return nil, fmt.Errorf("в файле не найдено ни одного субтитра")
+	}
+	sort.SliceStable(cues, func(i, j int) bool { return cues[i].Start < cues[j].Start })
+	return cues, nil
+}
+
+func Write(cues []Cue, format string) ([]byte, error) {
+	switch normalizeExt(format) {
+	case "srt":
+		return writeSRT(cues), nil
+	case "vtt":
+		return writeVTT(cues), nil
+	case "ass":
+		return writeASS(cues, false), nil
+	case "ssa":
+		return writeASS(cues, true), nil
+	case "sbv":
+		return writeSBV(cues), nil
+	case "ttml", "dfxp":
+		return writeTTML(cues), nil
+	default:
+		return nil, fmt.Errorf("неподдерживаемый формат субтитров: %s", format)
+	}
+}
+
+func normalizeExt(ext string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(ext), "."))
+}
+
+func decodeText(data []byte) string {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if !utf8.Valid(data) {
+		if decoded, err := charmap.Windows1251.NewDecoder().Bytes(data); err == nil {
+			data = decoded
+		}
+	}
+	s := strings.ReplaceAll(string(data), "\r\n", "\n")
+	return strings.ReplaceAll(s, "\r", "\n")
+}
+
+func splitBlocks(text string) [][]string {
+	blocks := make([][]string, 0)
+	current := make([]string, 0)
+	for _, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) == "" {
+			if len(current) > 0 {
+				blocks = append(blocks, current)
+				current = make([]string, 0)
+			}
+			continue
+		}
+		current = append(current, strings.TrimRight(line, " \t"))
+	}
+	if len(current) > 0 {
+		blocks = append(blocks, current)
+	}
+	return blocks
+}
+
+var timestampRe = regexp.MustCompile(`^(?:(\d+):)?(\d{1,2}):(\d{1,2})(?:[.,](\d{1,3}))?$`)
+
+func parseTimestamp(s string) (time.Duration, error) {
+	m := timestampRe.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return 0, fmt.Errorf("неверная метка времени: %q", s)
+	}
+	h, _ := strconv.Atoi(m[1])
+	min, _ := strconv.Atoi(m[2])
+	sec, _ := strconv.Atoi(m[3])
+	ms := 0
+	if m[4] != "" {
+		frac := (m[4] + "00")[:3]
+		ms, _ = strconv.Atoi(frac)
+	}
+	return time.Duration(h)*time.Hour + time.Duration(min)*time.Minute + time.Duration(sec)*time.Second + time.Duration(ms)*time.Millisecond, nil
+}
+
+func splitDuration(d time.Duration) (h, m, s, ms int) {
+	if d < 0 {
+		d = 0
+	}
+	total := int(d / time.Millisecond)
+	return total / 3600000, total / 60000 % 60, total / 1000 % 60, total % 1000
+}
+
+func formatTimestamp(d time.Duration, sep string) string {
+	h, m, s, ms := splitDuration(d)
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d", h, m, s, sep, ms)
+}
+
+var tagRe = regexp.MustCompile(`</?[^>]*>`)
+
+func stripTags(s string) string {
+	return tagRe.ReplaceAllString(s, "")
+}
+
+var basicTagRe = regexp.MustCompile(`</?([a-zA-Z]+)[^>]*>`)
+
+func keepBasicTags(s string) string {
+	return basicTagRe.ReplaceAllStringFunc(s, func(tag string) string {
+		m := basicTagRe.FindStringSubmatch(tag)
+		name := strings.ToLower(m[1])
+		if name != "i" && name != "b" && name != "u" {
+			return ""
+		}
+		if strings.HasPrefix(tag, "</") {
+			return "</" + name + ">"
+		}
+		return "<" + name + ">"
+	})
+}
@@ -0,0 +1,208 @@
+package subtitles
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+func ms(n int) time.Duration {
+	return time.Duration(n) * time.Millisecond
+}
+
+var sampleCues = []Cue{
+	{Start: ms(1000), End: ms(3500), Text: "Hello, <i>world</i>!"},
+	{Start: ms(4000), End: ms(6250), Text: "Two lines\nof <b>text</b> & more"},
+	{Start: ms(3723450), End: ms(3725000), Text: "After an hour"},
+}
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		format    string
+		plainText bool
+	}{
+		{format: "srt"},
+		{format: "vtt"},
+		{format: "ass"},
+		{format: "ssa"},
+		{format: "sbv", plainText: true},
+		{format: "ttml"},
+		{format: "dfxp"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			data, err := Write(sampleCues, tt.format)
+			if err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+			got, err := Parse(data, tt.format)
+			if err != nil {
+				t.Fatalf("Parse: %v\n%s", err, data)
+			}
+			want := make([]Cue, len(sampleCues))
+			copy(want, sampleCues)
+			if tt.plainText {
+				for i := range want {
+					want[i].Text = stripTags(want[i].Text)
+				}
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("round trip mismatch\n got: %#v\nwant: %#v\n%s", got, want, data)
+			}
+		})
+	}
+}
+
+func TestConvertBetweenFormats(t *testing.T) {
+	srt := "1\r\n00:00:01,000 --> 00:00:02,500\r\n<i>Hi</i> there\r\n\r\n2\r\n00:00:03,000 --> 00:00:04,000\r\nBye\r\n"
+	out, err := Convert([]byte(srt), "srt", "vtt")
+	if err != nil {
+		t.Fatalf("Convert: %v", err)
+	}
+	want := "WEBVTT\n\n00:00:01.000 --> 00:00:02.500\n<i>Hi</i> there\n\n00:00:03.000 --> 00:00:04.000\nBye\n\n"
+	if string(out) != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+}
+
+func TestParseSortsCues(t *testing.T) {
+	srt := "1\n00:00:05,000 --> 00:00:06,000\nSecond\n\n2\n00:00:01,000 --> 00:00:02,000\nFirst\n"
+	cues, err := Parse([]byte(srt), "srt")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if len(cues) != 2 || cues[0].Text != "First" || cues[1].Text != "Second" {
+		t.Errorf("cues not sorted by start: %#v", cues)
+	}
+}
+
+func TestParseVTTSkipsNotesAndIdentifiers(t *testing.T) {
+	vtt := "WEBVTT - title\n\nNOTE a comment\n\nSTYLE\n::cue { color: red }\n\nintro\n00:01.000 --> 00:02.000 align:start\nHi &amp; bye\n"
+	cues, err := Parse([]byte(vtt), "vtt")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	want := []Cue{{Start: ms(1000), End: ms(2000), Text: "Hi & bye"}}
+	if !reflect.DeepEqual(cues, want) {
+		t.Errorf("got %#v, want %#v", cues, want)
+	}
+}
+
+func TestParseASSCustomFormat(t *testing.T) {
+	ass := "[Script Info]\nTitle: x\n\n[Events]\nFormat: Start, End, Text\nDialogue: 0:00:01.50,0:00:02.00,{\\i1}Hi{\\i0}, you\\Nthere\nComment: 0:00:03.00,0:00:04.00,ignored\n"
+	cues, err := Parse([]byte(ass), "ass")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	want := []Cue{{Start: ms(1500), End: ms(2000), Text: "<i>Hi</i>, you\nthere"}}
+	if !reflect.DeepEqual(cues, want) {
+		t.Errorf("got %#v, want %#v", cues, want)
+	}
+}
+
+func TestParseTTMLTimeExpressions(t *testing.T) {
+	ttml := `<tt xmlns="http://www.w3.org/ns/ttml" frameRate="25" tickRate="10000000"><body><div>
+<p begin="00:00:01:05" end="00:00:02:00">frames</p>
+<p begin="30000000t" dur="1.5s">ticks</p>
+<p begin="1m" end="61500ms">offsets</p>
+</div></body></tt>`
+	cues, err := Parse([]byte(ttml), "ttml")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	want := []Cue{
+		{Start: ms(1200), End: ms(2000), Text: "frames"},
+		{Start: ms(3000), End: ms(4500), Text: "ticks"},
+		{Start: ms(60000), End: ms(61500), Text: "offsets"},
+	}
+	if !reflect.DeepEqual(cues, want) {
+		t.Errorf("got %#v, want %#v", cues, want)
+	}
+}
+
+func TestParseDecodesWindows1251(t *testing.T) {
+	src := "1\n00:00:01,000 --> 00:00:02,000\nПривет\n"
+	data, err := charmap.Windows1251.NewEncoder().Bytes([]byte(src))
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	cues, err := Parse(data, "srt")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if len(cues) != 1 || cues[0].Text != "Привет" {
+		t.Errorf("got %#v", cues)
+	}
+}
+
+func TestParseRejectsMalformedInput(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		data   string
+	}{
+		{"srt bad timing", "srt", "1\nnot a timing\nHello\n"},
+		{"srt bad timestamp", "srt", "1\n00:00:aa,000 --> 00:00:02,000\nHello\n"},
+		{"srt empty", "srt", "\n\n"},
+		{"vtt missing header", "vtt", "00:00:01.000 --> 00:00:02.000\nHello\n"},
+		{"vtt bad cue", "vtt", "WEBVTT\n\n00:00:01.000 -> 00:00:02.000\nHello\n"},
+		{"ass short dialogue", "ass", "[Events]\nFormat: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text\nDialogue: 0,0:00:01.00\n"},
+		{"ass bad time", "ass", "[Events]\nFormat: Start, End, Text\nDialogue: soon,0:00:02.00,Hi\n"},
+		{"ass no events", "ass", "[Script Info]\nTitle: x\n"},
+		{"sbv missing comma", "sbv", "0:00:01.000 0:00:02.000\nHello\n"},
+		{"sbv bad time", "sbv", "0:00:01.000,later\nHello\n"},
+		{"ttml missing begin", "ttml", `<tt><body><div><p end="1s">Hi</p></div></body></tt>`},
+		{"ttml bad time", "ttml", `<tt><body><div><p begin="soon" end="1s">Hi</p></div></body></tt>`},
+		{"ttml broken xml", "ttml", `<tt><body><div><p begin="0s" end="1s">Hi</div>`},
+		{"unknown format", "sub", "whatever"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if cues, err := Parse([]byte(tt.data), tt.format); err == nil {
+				t.Errorf("expected an error, got %#v", cues)
+			}
+		})
+	}
+}
+
+func TestWriteRejectsUnknownFormat(t *testing.T) {
+	if _, err := Write(sampleCues, "sub"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
+
+func TestParseTimestamp(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+		ok   bool
+	}{
+		{"00:00:01,000", ms(1000), true},
+		{"01:02:03.456", ms(3723456), true},
+		{"02:03.4", ms(123400), true},
+		{"0:00:01.5", ms(1500), true},
+		{"12", 0, false},
+		{"00:00:01;000", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		got, err := parseTimestamp(tt.in)
+		if (err == nil) != tt.ok {
+			t.Errorf("parseTimestamp(%q) error = %v, want ok=%v", tt.in, err, tt.ok)
+			continue
+		}
+		if tt.ok && got != tt.want {
+			t.Errorf("parseTimestamp(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestWriteVTTEscapesArrows(t *testing.T) {
+	data := writeVTT([]Cue{{Start: 0, End: ms(1000), Text: "a --> b"}})
+	if strings.Count(string(data), "-->") != 1 {
+		t.Errorf("cue text arrow was not escaped: %q", data)
+	}
+}
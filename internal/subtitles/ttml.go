@@ -0,0 +1,196 @@
+package subtitles
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func parseTTML(text string) ([]Cue, error) {
+	dec := xml.NewDecoder(strings.NewReader(text))
+	dec.Strict = false
+
+	frameRate := 30.0
+	tickRate := 1.0
+	cues := make([]Cue, 0)
+
+	var (
+		current *Cue
+		body    strings.Builder
+		spans   []string
+	)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("неверный файл TTML: %v", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "tt":
+				if v := xmlAttr(t, "frameRate"); v != "" {
+					if f, err := strconv.ParseFloat(v, 64); err == nil && f > 0 {
+						frameRate = f
+					}
+				}
+				if v := xmlAttr(t, "tickRate"); v != "" {
+					if f, err := strconv.ParseFloat(v, 64); err == nil && f > 0 {
+						tickRate = f
+					}
+				}
+			case "p":
+				start, err := parseTTMLTime(xmlAttr(t, "begin"), frameRate, tickRate)
+				if err != nil {
+					return nil, err
+				}
+				end, err := parseTTMLTime(xmlAttr(t, "end"), frameRate, tickRate)
+				if xmlAttr(t, "end") == "" {
+					dur, derr := parseTTMLTime(xmlAttr(t, "dur"), frameRate, tickRate)
+					end, err = start+dur, derr
+				}
+				if err != nil {
+					return nil, err
+				}
+				current = &Cue{Start: start, End: end}
+				body.Reset()
+				spans = spans[:0]
+			case "br":
+				if current != nil {
+					body.WriteString("\n")
+				}
+			case "span":
+				tag := ""
+				switch {
+				case xmlAttr(t, "fontStyle") == "italic":
+					tag = "i"
+				case xmlAttr(t, "fontWeight") == "bold":
+					tag = "b"
+				case xmlAttr(t, "textDecoration") == "underline":
+					tag = "u"
+				}
+				if current != nil && tag != "" {
+					body.WriteString("<" + tag + ">")
+				}
+				spans = append(spans, tag)
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "p":
+				if current != nil {
+					lines := strings.Split(body.String(), "\n")
+					for i := range lines {
+						lines[i] = strings.TrimSpace(lines[i])
+					}
+					current.Text = strings.TrimSpace(strings.Join(lines, "\n"))
+					if current.Text != "" {
+						cues = append(cues, *current)
+					}
+				}
+				current = nil
+			case "span":
+				if n := len(spans); n > 0 {
+					if current != nil && spans[n-1] != "" {
+						body.WriteString("</" + spans[n-1] + ">")
+					}
+					spans = spans[:n-1]
+				}
+			}
+		case xml.CharData:
+			if current != nil {
+				body.WriteString(ttmlSpaceRe.ReplaceAllString(string(t), " "))
+			}
+		}
+	}
+	return cues, nil
+}
+
+func xmlAttr(el xml.StartElement, name string) string {
+	for _, a := range el.Attr {
+		if a.Name.Local == name {
+			return strings.TrimSpace(a.Value)
+		}
+	}
+	return ""
+}
+
+var (
+	ttmlSpaceRe       = regexp.MustCompile(`\s+`)
+	ttmlClockFramesRe = regexp.MustCompile(`^(\d+):(\d{2}):(\d{2}):(\d+(?:\.\d+)?)$`)
+	ttmlOffsetRe      = regexp.MustCompile(`^(\d+(?:\.\d+)?)(h|m|s|ms|f|t)$`)
+)
+
+func parseTTMLTime(s string, frameRate, tickRate float64) (time.Duration, error) {
+	if s == "" {
+		return 0, fmt.Errorf("в TTML не указано время субтитра")
+	}
+	if m := ttmlClockFramesRe.FindStringSubmatch(s); m != nil {
+		h, _ := strconv.Atoi(m[1])
+		min, _ := strconv.Atoi(m[2])
+		sec, _ := strconv.Atoi(m[3])
+		frames, _ := strconv.ParseFloat(m[4], 64)
+		base := time.Duration(h)*time.Hour + time.Duration(min)*time.Minute + time.Duration(sec)*time.Second
+		return base + time.Duration(frames/frameRate*float64(time.Second)), nil
+	}
+	if m := ttmlOffsetRe.FindStringSubmatch(s); m != nil {
+		v, _ := strconv.ParseFloat(m[1], 64)
+		unit := map[string]float64{
+			"h":  float64(time.Hour),
+			"m":  float64(time.Minute),
+			"s":  float64(time.Second),
+			"ms": float64(time.Millisecond),
+			"f":  float64(time.Second) / frameRate,
+			"t":  float64(time.Second) / tickRate,
+		}[m[2]]
+		return time.Duration(v * unit), nil
+	}
+	return parseTimestamp(s)
+}
+
+func writeTTML(cues []Cue) []byte {
+	var sb strings.Builder
+	sb.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	sb.WriteString(`<tt xmlns="http://www.w3.org/ns/ttml" xmlns:tts="http://www.w3.org/ns/ttml#styling">` + "\n")
+	sb.WriteString("  <body>\n    <div>\n")
+	for _, c := range cues {
+		fmt.Fprintf(&sb, `      <p begin="%s" end="%s">%s</p>`+"\n", formatTimestamp(c.Start, "."), formatTimestamp(c.End, "."), textToTTML(c.Text))
+	}
+	sb.WriteString("    </div>\n  </body>\n</tt>\n")
+	return []byte(sb.String())
+}
+
+func textToTTML(s string) string {
+	spans := map[string]string{
+		"i": `<span tts:fontStyle="italic">`,
+		"b": `<span tts:fontWeight="bold">`,
+		"u": `<span tts:textDecoration="underline">`,
+	}
+	s = keepBasicTags(s)
+	var sb strings.Builder
+	last := 0
+	for _, loc := range basicTagRe.FindAllStringIndex(s, -1) {
+		sb.WriteString(escapeTTML(s[last:loc[0]]))
+		tag := s[loc[0]:loc[1]]
+		if strings.HasPrefix(tag, "</") {
+			sb.WriteString("</span>")
+		} else {
+			sb.WriteString(spans[tag[1:2]])
+		}
+		last = loc[1]
+	}
+	sb.WriteString(escapeTTML(s[last:]))
+	return sb.String()
+}
+
+func escapeTTML(s string) string {
+	var sb strings.Builder
+	_ = xml.EscapeText(&sb, []byte(s))
+	return strings.ReplaceAll(sb.String(), "&#xA;", "<br/>")
+}
@@ -0,0 +1,46 @@
+package subtitles
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+func parseVTT(text string) ([]Cue, error) {
+	blocks := splitBlocks(text)
+	if len(blocks) == 0 || !strings.HasPrefix(strings.TrimSpace(blocks[0][0]), "WEBVTT") {
+		return nil, fmt.Errorf("файл не является WebVTT")
+	}
+
+	cues := make([]Cue, 0)
+	for _, block := range blocks[1:] {
+		head := strings.TrimSpace(block[0])
+		if strings.HasPrefix(head, "NOTE") || head == "STYLE" || head == "REGION" {
+			continue
+		}
+		i := 0
+		if !strings.Contains(block[0], "-->") {
+			i = 1
+		}
+		if i >= len(block) {
+			continue
+		}
+		cue, ok := parseCueTiming(block[i])
+		if !ok {
+			return nil, fmt.Errorf("неверный блок WebVTT: %q", strings.Join(block, " / "))
+		}
+		cue.Text = html.UnescapeString(keepBasicTags(strings.Join(block[i+1:], "\n")))
+		cues = append(cues, cue)
+	}
+	return cues, nil
+}
+
+func writeVTT(cues []Cue) []byte {
+	var sb strings.Builder
+	sb.WriteString("WEBVTT\n\n")
+	for _, c := range cues {
+		text := strings.NewReplacer("&", "&amp;", "-->", "--&gt;").Replace(c.Text)
+		fmt.Fprintf(&sb, "%s --> %s\n%s\n\n", formatTimestamp(c.Start, "."), formatTimestamp(c.End, "."), text)
+	}
+	return []byte(sb.String())
+}
@@ -0,0 +1,17 @@
+package utils
+
+import (
+	"github.com/BatmanBruc/bat-bot-convetor/internal/i18n"
+	"github.com/BatmanBruc/bat-bot-convetor/internal/messages"
+	"github.com/go-telegram/bot/models"
+)
+
+func CancelKeyboard(lang i18n.Lang, taskID string) *models.InlineKeyboardMarkup {
+	return &models.InlineKeyboardMarkup{
+		InlineKeyboard: [][]models.InlineKeyboardButton{
+			{
+				{Text: messages.CancelTaskBtn(lang), CallbackData: "cancel_for_" + taskID},
+			},
+		},
+	}
+}
@@ -8,14 +8,19 @@ import (
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
+	"github.com/BatmanBruc/bat-bot-convetor/internal/billing"
+	"github.com/BatmanBruc/bat-bot-convetor/internal/capability"
 	"github.com/BatmanBruc/bat-bot-convetor/internal/config"
 	"github.com/BatmanBruc/bat-bot-convetor/internal/converter"
 	"github.com/BatmanBruc/bat-bot-convetor/internal/handlers"
 	"github.com/BatmanBruc/bat-bot-convetor/internal/middleware"
 	"github.com/BatmanBruc/bat-bot-convetor/internal/scheduler"
 	"github.com/BatmanBruc/bat-bot-convetor/store"
+	"github.com/BatmanBruc/bat-bot-convetor/types"
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
 )
@@ -23,9 +28,57 @@ import (
 func main() {
 	_ = config.LoadEnvFile("config.env")
 
-	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer cancel()
 
+	runMode := strings.ToLower(strings.TrimSpace(os.Getenv("RUN_MODE")))
+	if runMode == "" {
+		runMode = "all"
+	}
+	if runMode != "all" && runMode != "bot" && runMode != "worker" {
+		log.Fatalf("Invalid RUN_MODE %q: expected all, bot or worker", runMode)
+	}
+	runBot := runMode != "worker"
+	runWorkers := runMode != "bot"
+
+	workers := 3
+	if v := os.Getenv("WORKERS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			log.Printf("Invalid WORKERS value, using default: 3")
+		} else {
+			workers = n
+		}
+	}
+
+	maxPerUser := 2
+	if v := os.Getenv("MAX_TASKS_PER_USER"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			log.Printf("Invalid MAX_TASKS_PER_USER value, using default: 2")
+		} else {
+			maxPerUser = n
+		}
+	}
+
+	classSlots := map[types.ResourceClass]int{
+		types.ResourceLight: 0,
+		types.ResourceHeavy: 1,
+	}
+	for class, env := range map[types.ResourceClass]string{
+		types.ResourceLight: "LIGHT_TASK_SLOTS",
+		types.ResourceHeavy: "HEAVY_TASK_SLOTS",
+	} {
+		if v := os.Getenv(env); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				log.Printf("Invalid %s value, using default: %d", env, classSlots[class])
+			} else {
+				classSlots[class] = n
+			}
+		}
+	}
+
 	redisHost := os.Getenv("REDIS_HOST")
 	if redisHost == "" {
 		redisHost = "localhost"
@@ -58,16 +111,8 @@ func main() {
 
 	taskStore := store.NewRedisTaskStore(rdb, 24)
 	userStateStore := store.NewRedisUserStore(rdb, 24)
-
-	pgStore, err := store.NewPostgresStore(ctx, os.Getenv("POSTGRES_DSN"))
-	if err != nil {
-		log.Fatalf("Failed to connect to Postgres: %v", err)
-	}
-	defer pgStore.Close()
-
-	middlewares := middleware.NewMessageAnalyzer(pgStore)
-
-	var h *handlers.Handlers
+	jobQueue := store.NewRedisJobQueue(rdb)
+	refundQueue := store.NewRedisRefundQueue(rdb)
 
 	botToken := os.Getenv("BOT_TOKEN")
 	if botToken == "" {
@@ -89,20 +134,46 @@ func main() {
 	}
 
 	conv := converter.NewDefaultConverter()
+	caps := conv.Probe(ctx)
+	capability.SetCurrent(caps)
+	log.Printf("Capability probe: %d conversion pairs available, backends: %v", caps.PairCount(), caps.Features())
 
 	taskScheduler := scheduler.NewScheduler(
 		taskStore,
+		jobQueue,
 		conv,
 		b,
 		scheduler.Config{
-			Workers: 3,
+			Workers:    workers,
+			WorkerID:   os.Getenv("WORKER_ID"),
+			MaxPerUser: maxPerUser,
+			ClassSlots: classSlots,
+			Refunds:    refundQueue,
 		},
 	)
 
-	h = handlers.NewHandlers(taskStore, userStateStore, taskScheduler, pgStore, pgStore)
+	if runWorkers {
+		taskScheduler.Start()
+		defer taskScheduler.Stop()
+	}
+
+	if !runBot {
+		log.Println("Worker started. Press Ctrl+C to stop.")
+		<-ctx.Done()
+		return
+	}
+
+	pgStore, err := store.NewPostgresStore(ctx, os.Getenv("POSTGRES_DSN"))
+	if err != nil {
+		log.Fatalf("Failed to connect to Postgres: %v", err)
+	}
+	defer pgStore.Close()
+
+	go billing.RunRefunds(ctx, refundQueue, pgStore)
 
-	taskScheduler.Start()
-	defer taskScheduler.Stop()
+	middlewares := middleware.NewMessageAnalyzer(pgStore)
+	h := handlers.NewHandlers(taskStore, userStateStore, taskScheduler, pgStore, pgStore, pgStore)
+	go h.RunSubscriptionReminders(ctx, b)
 
 	handlerChain := middlewares.CheckTaskMiddleWare(
 		middlewares.AnalyzeMessageMiddleware(
@@ -0,0 +1,39 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS plans (
+  name TEXT PRIMARY KEY,
+  title TEXT NOT NULL DEFAULT '',
+  duration_days INTEGER NOT NULL DEFAULT 30,
+  daily_credits INTEGER NULL,
+  max_file_size BIGINT NOT NULL DEFAULT 0,
+  priority_weight INTEGER NOT NULL DEFAULT 1,
+  allowed_categories TEXT[] NOT NULL DEFAULT '{}',
+  active BOOLEAN NOT NULL DEFAULT TRUE,
+  sort_order INTEGER NOT NULL DEFAULT 0,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS plan_prices (
+  plan TEXT NOT NULL REFERENCES plans(name) ON UPDATE CASCADE ON DELETE CASCADE,
+  currency TEXT NOT NULL,
+  amount BIGINT NOT NULL,
+  PRIMARY KEY (plan, currency)
+);
+
+INSERT INTO plans (name, title, duration_days, daily_credits, priority_weight, sort_order)
+VALUES
+  ('free', 'Free', 0, 20, 1, 0),
+  ('unlimited', 'Unlimited', 30, NULL, 3, 10)
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO plan_prices (plan, currency, amount)
+VALUES
+  ('unlimited', 'XTR', 100),
+  ('unlimited', 'RUB', 15000)
+ON CONFLICT (plan, currency) DO NOTHING;
+
+-- +goose Down
+DROP TABLE IF EXISTS plan_prices;
+DROP TABLE IF EXISTS plans;
+
+
@@ -0,0 +1,55 @@
+-- +goose Up
+ALTER TABLE user_credits ADD COLUMN IF NOT EXISTS purchased INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS credit_ledger (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+  delta INTEGER NOT NULL,
+  reason TEXT NOT NULL,
+  ref TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS credit_ledger_user_id_idx ON credit_ledger (user_id, created_at);
+
+CREATE TABLE IF NOT EXISTS credit_packs (
+  name TEXT PRIMARY KEY,
+  title TEXT NOT NULL DEFAULT '',
+  credits INTEGER NOT NULL,
+  active BOOLEAN NOT NULL DEFAULT TRUE,
+  sort_order INTEGER NOT NULL DEFAULT 0,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS credit_pack_prices (
+  pack TEXT NOT NULL REFERENCES credit_packs(name) ON UPDATE CASCADE ON DELETE CASCADE,
+  currency TEXT NOT NULL,
+  amount BIGINT NOT NULL,
+  PRIMARY KEY (pack, currency)
+);
+
+INSERT INTO credit_packs (name, title, credits, sort_order)
+VALUES
+  ('credits_100', '100 credits', 100, 10),
+  ('credits_500', '500 credits', 500, 20),
+  ('credits_2000', '2000 credits', 2000, 30)
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO credit_pack_prices (pack, currency, amount)
+VALUES
+  ('credits_100', 'XTR', 50),
+  ('credits_100', 'RUB', 5000),
+  ('credits_500', 'XTR', 200),
+  ('credits_500', 'RUB', 20000),
+  ('credits_2000', 'XTR', 650),
+  ('credits_2000', 'RUB', 65000)
+ON CONFLICT (pack, currency) DO NOTHING;
+
+-- +goose Down
+DROP TABLE IF EXISTS credit_pack_prices;
+DROP TABLE IF EXISTS credit_packs;
+DROP TABLE IF EXISTS credit_ledger;
+ALTER TABLE user_credits DROP COLUMN IF EXISTS purchased;
+
+
@@ -0,0 +1,16 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN IF NOT EXISTS lang TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS subscription_notifications (
+  user_id BIGINT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+  kind TEXT NOT NULL,
+  expires_at TIMESTAMPTZ NOT NULL,
+  sent_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (user_id, kind, expires_at)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS subscription_notifications;
+ALTER TABLE users DROP COLUMN IF EXISTS lang;
+
+
@@ -0,0 +1,28 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS promo_codes (
+  code TEXT PRIMARY KEY,
+  plan TEXT NOT NULL DEFAULT '',
+  days INTEGER NOT NULL DEFAULT 0,
+  credits INTEGER NOT NULL DEFAULT 0,
+  max_uses INTEGER NOT NULL DEFAULT 1,
+  used_count INTEGER NOT NULL DEFAULT 0,
+  expires_at TIMESTAMPTZ NULL,
+  disabled BOOLEAN NOT NULL DEFAULT FALSE,
+  created_by BIGINT NOT NULL DEFAULT 0,
+  gift_payment TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  CHECK ((days > 0 AND plan <> '') OR credits > 0)
+);
+
+CREATE TABLE IF NOT EXISTS promo_redemptions (
+  code TEXT NOT NULL REFERENCES promo_codes(code) ON DELETE CASCADE,
+  user_id BIGINT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+  redeemed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (code, user_id)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS promo_redemptions;
+DROP TABLE IF EXISTS promo_codes;
+
+
@@ -0,0 +1,7 @@
+-- +goose Up
+CREATE INDEX IF NOT EXISTS credit_ledger_ref_idx ON credit_ledger (user_id, reason, ref);
+
+-- +goose Down
+DROP INDEX IF EXISTS credit_ledger_ref_idx;
+
+
@@ -22,17 +22,17 @@ case "$pm" in
     # PDF merge tools:
     # - pdftk-java provides "pdftk" on Ubuntu 22.04 (pdftk package is transitional)
     # - qpdf is a reliable fallback
-    sudo apt-get install -y --no-install-recommends ca-certificates ffmpeg imagemagick libreoffice calibre poppler-utils pdftk-java qpdf redis-server postgresql
+    sudo apt-get install -y --no-install-recommends ca-certificates ffmpeg imagemagick libreoffice calibre poppler-utils pdftk-java qpdf ghostscript tesseract-ocr tesseract-ocr-eng tesseract-ocr-rus fontforge woff2 img2pdf redis-server postgresql
     ;;
   dnf)
-    sudo dnf install -y ca-certificates ffmpeg ImageMagick libreoffice calibre poppler-utils qpdf java-17-openjdk-headless redis postgresql-server
+    sudo dnf install -y ca-certificates ffmpeg ImageMagick libreoffice calibre poppler-utils qpdf ghostscript tesseract tesseract-langpack-rus fontforge woff2 java-17-openjdk-headless redis postgresql-server
     ;;
   pacman)
-    sudo pacman -Sy --noconfirm --needed ca-certificates ffmpeg imagemagick libreoffice-fresh calibre poppler qpdf pdftk redis postgresql
+    sudo pacman -Sy --noconfirm --needed ca-certificates ffmpeg imagemagick libreoffice-fresh calibre poppler qpdf ghostscript tesseract tesseract-data-eng tesseract-data-rus pdftk fontforge woff2 redis postgresql
     ;;
   brew)
     brew update
-    brew install ffmpeg imagemagick libreoffice calibre poppler qpdf pdftk-java redis postgresql@16
+    brew install ffmpeg imagemagick libreoffice calibre poppler qpdf ghostscript tesseract tesseract-lang pdftk-java fontforge woff2 redis postgresql@16
     ;;
 esac
 
@@ -3,12 +3,15 @@ package store
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/go-redis/redis/v8"
 )
 
+var ErrKeyNotFound = errors.New("key not found")
+
 type RedisClient struct {
 	client *redis.Client
 	ctx    context.Context
@@ -65,7 +68,7 @@ func (r *RedisClient) Get(key string, dest interface{}) error {
 	data, err := r.client.Get(r.ctx, key).Bytes()
 	if err != nil {
 		if err == redis.Nil {
-			return fmt.Errorf("key not found: %s", key)
+			return fmt.Errorf("%w: %s", ErrKeyNotFound, key)
 		}
 		return err
 	}
@@ -0,0 +1,75 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/BatmanBruc/bat-bot-convetor/types"
+)
+
+func (s *PostgresStore) ClaimSubscriptionNotices(limit int) ([]types.SubscriptionNotice, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	rows, err := s.pool.Query(ctx, `
+WITH due AS (
+  SELECT d.user_id, d.plan, d.kind, d.expires_at, d.lang
+  FROM (
+    SELECT s.user_id, s.plan, s.expires_at, u.lang,
+      CASE
+        WHEN s.expires_at <= NOW() THEN 'expired'
+        WHEN s.expires_at <= NOW() + INTERVAL '1 day' THEN 'expires_1d'
+        ELSE 'expires_3d'
+      END AS kind
+    FROM subscriptions s
+    JOIN users u ON u.user_id = s.user_id
+    WHERE s.status = 'active'
+      AND s.plan <> $2
+      AND s.expires_at IS NOT NULL
+      AND s.expires_at <= NOW() + INTERVAL '3 days'
+      AND s.expires_at > NOW() - INTERVAL '7 days'
+  ) d
+  WHERE NOT EXISTS (
+    SELECT 1
+    FROM subscription_notifications n
+    WHERE n.user_id = d.user_id AND n.kind = d.kind AND n.expires_at = d.expires_at
+  )
+  ORDER BY d.expires_at
+  LIMIT $1
+), claimed AS (
+  INSERT INTO subscription_notifications (user_id, kind, expires_at)
+  SELECT user_id, kind, expires_at FROM due
+  ON CONFLICT DO NOTHING
+  RETURNING user_id, kind, expires_at
+)
+SELECT c.user_id, d.plan, c.kind, c.expires_at, d.lang
+FROM claimed c
+JOIN due d ON d.user_id = c.user_id AND d.kind = c.kind AND d.expires_at = c.expires_at
+`, limit, types.FreePlan)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notices []types.SubscriptionNotice
+	for rows.Next() {
+		var n types.SubscriptionNotice
+		if err := rows.Scan(&n.UserID, &n.Plan, &n.Kind, &n.ExpiresAt, &n.Lang); err != nil {
+			return nil, err
+		}
+		notices = append(notices, n)
+	}
+	return notices, rows.Err()
+}
+
+func (s *PostgresStore) ReleaseSubscriptionNotice(notice types.SubscriptionNotice) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := s.pool.Exec(ctx, `
+DELETE FROM subscription_notifications
+WHERE user_id = $1 AND kind = $2 AND expires_at = $3
+`, notice.UserID, notice.Kind, notice.ExpiresAt)
+	return err
+}
@@ -0,0 +1,224 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/BatmanBruc/bat-bot-convetor/types"
+	"github.com/jackc/pgx/v5"
+)
+
+const planColumns = `p.name, p.title, p.duration_days, p.daily_credits, p.max_file_size, p.priority_weight, p.allowed_categories`
+
+func scanPlan(row pgx.Row) (*types.Plan, error) {
+	var p types.Plan
+	var daily *int
+	err := row.Scan(&p.Name, &p.Title, &p.DurationDays, &daily, &p.MaxFileSize, &p.PriorityWeight, &p.AllowedCategories)
+	if err != nil {
+		return nil, err
+	}
+	if daily == nil {
+		p.Unlimited = true
+	} else {
+		p.DailyCredits = *daily
+	}
+	if p.PriorityWeight <= 0 {
+		p.PriorityWeight = 1
+	}
+	return &p, nil
+}
+
+func (s *PostgresStore) loadPrices(ctx context.Context, table string, key string, names []string) (map[string]types.Prices, error) {
+	out := make(map[string]types.Prices, len(names))
+	for _, name := range names {
+		out[name] = types.Prices{}
+	}
+	if len(names) == 0 {
+		return out, nil
+	}
+	rows, err := s.pool.Query(ctx, `
+SELECT `+key+`, currency, amount
+FROM `+table+`
+WHERE `+key+` = ANY($1)
+`, names)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name, currency string
+		var amount int64
+		if err := rows.Scan(&name, &currency, &amount); err != nil {
+			return nil, err
+		}
+		if prices, ok := out[name]; ok {
+			prices[strings.ToUpper(strings.TrimSpace(currency))] = amount
+		}
+	}
+	return out, rows.Err()
+}
+
+func (s *PostgresStore) loadPlanPrices(ctx context.Context, plans ...*types.Plan) error {
+	names := make([]string, 0, len(plans))
+	for _, p := range plans {
+		names = append(names, p.Name)
+	}
+	prices, err := s.loadPrices(ctx, "plan_prices", "plan", names)
+	if err != nil {
+		return err
+	}
+	for _, p := range plans {
+		p.Prices = prices[p.Name]
+	}
+	return nil
+}
+
+func (s *PostgresStore) GetPlan(name string) (*types.Plan, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	plan, err := scanPlan(s.pool.QueryRow(ctx, `
+SELECT `+planColumns+`
+FROM plans p
+WHERE p.name = $1
+`, strings.TrimSpace(name)))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.loadPlanPrices(ctx, plan); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+func (s *PostgresStore) ListPlans() ([]types.Plan, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := s.pool.Query(ctx, `
+SELECT `+planColumns+`
+FROM plans p
+WHERE p.active AND p.name <> $1
+ORDER BY p.sort_order, p.name
+`, types.FreePlan)
+	if err != nil {
+		return nil, err
+	}
+	var plans []*types.Plan
+	for rows.Next() {
+		plan, err := scanPlan(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		plans = append(plans, plan)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := s.loadPlanPrices(ctx, plans...); err != nil {
+		return nil, err
+	}
+
+	out := make([]types.Plan, 0, len(plans))
+	for _, p := range plans {
+		if len(p.Prices) > 0 {
+			out = append(out, *p)
+		}
+	}
+	return out, nil
+}
+
+func (s *PostgresStore) UserPlan(userID int64) (*types.Plan, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	plan, err := scanPlan(s.pool.QueryRow(ctx, `
+SELECT `+planColumns+`
+FROM plans p
+WHERE p.name = COALESCE((
+  SELECT s.plan
+  FROM subscriptions s
+  JOIN plans sp ON sp.name = s.plan
+  WHERE s.user_id = $1
+    AND s.status = 'active'
+    AND (s.expires_at IS NULL OR s.expires_at > NOW())
+), $2)
+`, userID, types.FreePlan))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("plan %q is not configured", types.FreePlan)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+func (s *PostgresStore) ListCreditPacks() ([]types.CreditPack, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := s.pool.Query(ctx, `
+SELECT name, title, credits
+FROM credit_packs
+WHERE active AND credits > 0
+ORDER BY sort_order, name
+`)
+	if err != nil {
+		return nil, err
+	}
+	var packs []types.CreditPack
+	var names []string
+	for rows.Next() {
+		var p types.CreditPack
+		if err := rows.Scan(&p.Name, &p.Title, &p.Credits); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		packs = append(packs, p)
+		names = append(names, p.Name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	prices, err := s.loadPrices(ctx, "credit_pack_prices", "pack", names)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]types.CreditPack, 0, len(packs))
+	for _, p := range packs {
+		p.Prices = prices[p.Name]
+		if len(p.Prices) > 0 {
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+func (s *PostgresStore) GetCreditPack(name string) (*types.CreditPack, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var p types.CreditPack
+	err := s.pool.QueryRow(ctx, `
+SELECT name, title, credits
+FROM credit_packs
+WHERE name = $1 AND credits > 0
+`, strings.TrimSpace(name)).Scan(&p.Name, &p.Title, &p.Credits)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	prices, err := s.loadPrices(ctx, "credit_pack_prices", "pack", []string{p.Name})
+	if err != nil {
+		return nil, err
+	}
+	p.Prices = prices[p.Name]
+	return &p, nil
+}
@@ -0,0 +1,144 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/BatmanBruc/bat-bot-convetor/types"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const promoColumns = `code, plan, days, credits, max_uses, used_count, expires_at, disabled, created_by, gift_payment, created_at`
+
+func normalizePromoCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func scanPromoCode(row pgx.Row) (*types.PromoCode, error) {
+	var p types.PromoCode
+	err := row.Scan(&p.Code, &p.Plan, &p.Days, &p.Credits, &p.MaxUses, &p.UsedCount, &p.ExpiresAt, &p.Disabled, &p.CreatedBy, &p.GiftPayment, &p.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (s *PostgresStore) CreatePromoCode(code types.PromoCode) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := s.pool.Exec(ctx, `
+INSERT INTO promo_codes (code, plan, days, credits, max_uses, expires_at, created_by, gift_payment)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+`, normalizePromoCode(code.Code), strings.TrimSpace(code.Plan), code.Days, code.Credits, code.MaxUses, code.ExpiresAt, code.CreatedBy, code.GiftPayment)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return types.ErrPromoExists
+	}
+	return err
+}
+
+func (s *PostgresStore) ListPromoCodes(limit int) ([]types.PromoCode, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := s.pool.Query(ctx, `
+SELECT `+promoColumns+`
+FROM promo_codes
+ORDER BY created_at DESC
+LIMIT $1
+`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var codes []types.PromoCode
+	for rows.Next() {
+		p, err := scanPromoCode(rows)
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, *p)
+	}
+	return codes, rows.Err()
+}
+
+func (s *PostgresStore) DisablePromoCode(code string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	tag, err := s.pool.Exec(ctx, `
+UPDATE promo_codes SET disabled = TRUE
+WHERE code = $1
+`, normalizePromoCode(code))
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (s *PostgresStore) RedeemPromoCode(userID int64, code string) (*types.PromoRedemption, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	promo, err := scanPromoCode(tx.QueryRow(ctx, `
+SELECT `+promoColumns+`
+FROM promo_codes
+WHERE code = $1
+FOR UPDATE
+`, normalizePromoCode(code)))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, types.ErrPromoNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !promo.Active(time.Now().UTC()) {
+		return nil, types.ErrPromoInactive
+	}
+
+	tag, err := tx.Exec(ctx, `
+INSERT INTO promo_redemptions (code, user_id)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING
+`, promo.Code, userID)
+	if err != nil {
+		return nil, err
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, types.ErrPromoAlreadyUsed
+	}
+	if _, err := tx.Exec(ctx, `UPDATE promo_codes SET used_count = used_count + 1 WHERE code = $1`, promo.Code); err != nil {
+		return nil, err
+	}
+	promo.UsedCount++
+
+	out := &types.PromoRedemption{Code: *promo}
+	if promo.Days > 0 && promo.Plan != "" {
+		out.Subscription, err = activatePlanTx(ctx, tx, userID, promo.Plan, time.Duration(promo.Days)*24*time.Hour)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if promo.Credits > 0 {
+		out.Balance, err = addCreditsTx(ctx, tx, userID, promo.Credits, "promo", promo.Code)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
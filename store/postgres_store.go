@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/BatmanBruc/bat-bot-convetor/types"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/jackc/pgx/v5/stdlib"
 	"github.com/pressly/goose/v3"
@@ -18,7 +19,7 @@ type PostgresStore struct {
 	pool *pgxpool.Pool
 }
 
-var ErrInsufficientCredits = errors.New("insufficient credits")
+var ErrInsufficientCredits = types.ErrInsufficientCredits
 
 func NewPostgresStore(ctx context.Context, dsn string) (*PostgresStore, error) {
 	dsn = strings.TrimSpace(dsn)
@@ -96,15 +97,16 @@ func (s *PostgresStore) UpsertUser(user types.User) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	_, err := s.pool.Exec(ctx, `
-INSERT INTO users (user_id, chat_id, username, first_name, last_name)
-VALUES ($1, $2, $3, $4, $5)
+INSERT INTO users (user_id, chat_id, username, first_name, last_name, lang)
+VALUES ($1, $2, $3, $4, $5, $6)
 ON CONFLICT (user_id) DO UPDATE SET
   chat_id = EXCLUDED.chat_id,
   username = EXCLUDED.username,
   first_name = EXCLUDED.first_name,
   last_name = EXCLUDED.last_name,
+  lang = COALESCE(NULLIF(EXCLUDED.lang, ''), users.lang),
   updated_at = NOW();
-`, user.UserID, user.ChatID, strings.TrimSpace(user.Username), strings.TrimSpace(user.FirstName), strings.TrimSpace(user.LastName))
+`, user.UserID, user.ChatID, strings.TrimSpace(user.Username), strings.TrimSpace(user.FirstName), strings.TrimSpace(user.LastName), strings.TrimSpace(user.Lang))
 	return err
 }
 
@@ -113,16 +115,26 @@ func (s *PostgresStore) GetUser(userID int64) (*types.User, error) {
 	defer cancel()
 	var u types.User
 	err := s.pool.QueryRow(ctx, `
-SELECT user_id, chat_id, username, first_name, last_name, created_at, updated_at
+SELECT user_id, chat_id, username, first_name, last_name, lang, delivery, created_at, updated_at
 FROM users
 WHERE user_id = $1
-`, userID).Scan(&u.UserID, &u.ChatID, &u.Username, &u.FirstName, &u.LastName, &u.CreatedAt, &u.UpdatedAt)
+`, userID).Scan(&u.UserID, &u.ChatID, &u.Username, &u.FirstName, &u.LastName, &u.Lang, &u.Delivery, &u.CreatedAt, &u.UpdatedAt)
 	if err != nil {
 		return nil, err
 	}
 	return &u, nil
 }
 
+func (s *PostgresStore) SetDeliveryMode(userID int64, mode string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := s.pool.Exec(ctx, `
+UPDATE users SET delivery = $2, updated_at = NOW()
+WHERE user_id = $1
+`, userID, strings.TrimSpace(mode))
+	return err
+}
+
 func (s *PostgresStore) UpsertSubscription(sub types.Subscription) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -154,29 +166,32 @@ WHERE user_id = $1
 }
 
 func (s *PostgresStore) IsUnlimited(userID int64) (bool, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	var ok bool
-	err := s.pool.QueryRow(ctx, `
-SELECT EXISTS(
-  SELECT 1
-  FROM subscriptions
-  WHERE user_id = $1
-    AND status = 'active'
-    AND plan = 'unlimited'
-    AND (expires_at IS NULL OR expires_at > NOW())
-)
-`, userID).Scan(&ok)
+	plan, err := s.UserPlan(userID)
 	if err != nil {
 		return false, err
 	}
-	return ok, nil
+	return plan.Unlimited, nil
 }
 
 func (s *PostgresStore) RecordPayment(p types.Payment) (inserted bool, err error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-	tag, err := s.pool.Exec(ctx, `
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	inserted, err = recordPaymentTx(ctx, tx, p)
+	if err != nil {
+		return false, err
+	}
+	return inserted, tx.Commit(ctx)
+}
+
+func recordPaymentTx(ctx context.Context, tx pgx.Tx, p types.Payment) (bool, error) {
+	tag, err := tx.Exec(ctx, `
 INSERT INTO payments (user_id, provider, currency, total_amount, invoice_payload, telegram_payment_charge_id, provider_payment_charge_id)
 VALUES ($1, $2, $3, $4, $5, $6, $7)
 ON CONFLICT (telegram_payment_charge_id) DO NOTHING
@@ -187,7 +202,31 @@ ON CONFLICT (telegram_payment_charge_id) DO NOTHING
 	return tag.RowsAffected() > 0, nil
 }
 
-func (s *PostgresStore) ActivateOrExtendUnlimited(userID int64, duration time.Duration) (*types.Subscription, error) {
+func (s *PostgresStore) ActivatePlanPayment(p types.Payment, plan string, duration time.Duration) (sub *types.Subscription, inserted bool, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	inserted, err = recordPaymentTx(ctx, tx, p)
+	if err != nil || !inserted {
+		return nil, false, err
+	}
+	sub, err = activatePlanTx(ctx, tx, p.UserID, plan, duration)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, false, err
+	}
+	return sub, true, nil
+}
+
+func (s *PostgresStore) ActivateOrExtendPlan(userID int64, plan string, duration time.Duration) (*types.Subscription, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
@@ -197,44 +236,74 @@ func (s *PostgresStore) ActivateOrExtendUnlimited(userID int64, duration time.Du
 	}
 	defer func() { _ = tx.Rollback(ctx) }()
 
+	sub, err := activatePlanTx(ctx, tx, userID, plan, duration)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+func activatePlanTx(ctx context.Context, tx pgx.Tx, userID int64, plan string, duration time.Duration) (*types.Subscription, error) {
+	plan = strings.TrimSpace(plan)
 	now := time.Now().UTC()
+	var currentPlan, currentStatus string
 	var currentExpires *time.Time
-	err = tx.QueryRow(ctx, `
-SELECT expires_at
+	err := tx.QueryRow(ctx, `
+SELECT plan, status, expires_at
 FROM subscriptions
 WHERE user_id = $1
 FOR UPDATE
-`, userID).Scan(&currentExpires)
-	if err != nil {
-		currentExpires = nil
+`, userID).Scan(&currentPlan, &currentStatus, &currentExpires)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+
+	active := currentStatus == "active" && currentPlan != types.FreePlan &&
+		(currentExpires == nil || currentExpires.After(now))
+	if active && currentExpires == nil {
+		return &types.Subscription{
+			UserID:    userID,
+			Plan:      currentPlan,
+			Status:    currentStatus,
+			UpdatedAt: now,
+		}, nil
 	}
 
+	newPlan := plan
 	base := now
-	if currentExpires != nil && currentExpires.After(base) {
+	if active {
 		base = *currentExpires
+		if currentPlan != plan {
+			keep, err := planOutranksTx(ctx, tx, currentPlan, plan)
+			if err != nil {
+				return nil, err
+			}
+			if keep {
+				newPlan = currentPlan
+			}
+		}
 	}
 	newExpires := base.Add(duration)
 
 	_, err = tx.Exec(ctx, `
 INSERT INTO subscriptions (user_id, plan, status, expires_at)
-VALUES ($1, 'unlimited', 'active', $2)
+VALUES ($1, $2, 'active', $3)
 ON CONFLICT (user_id) DO UPDATE SET
-  plan = 'unlimited',
+  plan = EXCLUDED.plan,
   status = 'active',
   expires_at = EXCLUDED.expires_at,
   updated_at = NOW()
-`, userID, newExpires)
+`, userID, newPlan, newExpires)
 	if err != nil {
 		return nil, err
 	}
 
-	if err := tx.Commit(ctx); err != nil {
-		return nil, err
-	}
-
 	sub := &types.Subscription{
 		UserID:    userID,
-		Plan:      "unlimited",
+		Plan:      newPlan,
 		Status:    "active",
 		ExpiresAt: &newExpires,
 		UpdatedAt: now,
@@ -242,9 +311,46 @@ ON CONFLICT (user_id) DO UPDATE SET
 	return sub, nil
 }
 
+func planOutranksTx(ctx context.Context, tx pgx.Tx, current string, next string) (bool, error) {
+	query := `
+SELECT ` + planColumns + `
+FROM plans p
+WHERE p.name = $1
+`
+	a, err := scanPlan(tx.QueryRow(ctx, query, current))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	b, err := scanPlan(tx.QueryRow(ctx, query, next))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if a.Unlimited != b.Unlimited {
+		return a.Unlimited, nil
+	}
+	if a.DailyCredits != b.DailyCredits {
+		return a.DailyCredits > b.DailyCredits, nil
+	}
+	return a.PriorityWeight > b.PriorityWeight, nil
+}
+
 func (s *PostgresStore) GetOrResetBalance(userID int64) (int, error) {
-	remaining, _, err := s.Consume(userID, 0)
-	return remaining, err
+	balance, _, err := s.consume(userID, 0, "")
+	if err != nil {
+		return 0, err
+	}
+	return balance.Total(), nil
+}
+
+func (s *PostgresStore) GetBalance(userID int64) (*types.Balance, error) {
+	balance, _, err := s.consume(userID, 0, "")
+	return balance, err
 }
 
 func nextResetUTC(now time.Time) time.Time {
@@ -253,24 +359,44 @@ func nextResetUTC(now time.Time) time.Time {
 	return time.Date(y, m, d+1, 0, 0, 0, 0, time.UTC)
 }
 
-func (s *PostgresStore) Consume(userID int64, credits int) (remaining int, unlimited bool, err error) {
+func recordLedger(ctx context.Context, tx pgx.Tx, userID int64, delta int, reason string, ref string) error {
+	if delta == 0 {
+		return nil
+	}
+	_, err := tx.Exec(ctx, `
+INSERT INTO credit_ledger (user_id, delta, reason, ref)
+VALUES ($1, $2, $3, $4)
+`, userID, delta, reason, strings.TrimSpace(ref))
+	return err
+}
+
+func (s *PostgresStore) Consume(userID int64, credits int, ref string) (remaining int, purchased int, unlimited bool, err error) {
+	balance, purchased, err := s.consume(userID, credits, ref)
+	if balance == nil {
+		return 0, 0, false, err
+	}
+	return balance.Total(), purchased, balance.Unlimited, err
+}
+
+func (s *PostgresStore) consume(userID int64, credits int, ref string) (*types.Balance, int, error) {
 	if credits < 0 {
 		credits = 0
 	}
-	unlimited, err = s.IsUnlimited(userID)
+	plan, err := s.UserPlan(userID)
 	if err != nil {
-		return 0, false, err
+		return nil, 0, err
 	}
-	if unlimited {
-		return 0, true, nil
+	if plan.Unlimited {
+		return &types.Balance{Unlimited: true}, 0, nil
 	}
+	allowance := plan.DailyCredits
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
 	tx, err := s.pool.Begin(ctx)
 	if err != nil {
-		return 0, false, err
+		return nil, 0, err
 	}
 	defer func() {
 		_ = tx.Rollback(ctx)
@@ -278,57 +404,196 @@ func (s *PostgresStore) Consume(userID int64, credits int) (remaining int, unlim
 
 	now := time.Now().UTC()
 	resetAt := nextResetUTC(now)
-	_, err = tx.Exec(ctx, `
+	tag, err := tx.Exec(ctx, `
 INSERT INTO user_credits (user_id, balance, reset_at)
 VALUES ($1, $2, $3)
 ON CONFLICT (user_id) DO NOTHING
-`, userID, 20, resetAt)
+`, userID, allowance, resetAt)
 	if err != nil {
-		return 0, false, err
+		return nil, 0, err
+	}
+	if tag.RowsAffected() > 0 {
+		if err := recordLedger(ctx, tx, userID, allowance, "daily_reset", ""); err != nil {
+			return nil, 0, err
+		}
 	}
 
-	var balance int
-	var currentReset time.Time
+	b := &types.Balance{}
 	err = tx.QueryRow(ctx, `
-SELECT balance, reset_at
+SELECT balance, purchased, reset_at
 FROM user_credits
 WHERE user_id = $1
 FOR UPDATE
-`, userID).Scan(&balance, &currentReset)
+`, userID).Scan(&b.Daily, &b.Purchased, &b.ResetAt)
 	if err != nil {
-		return 0, false, err
+		return nil, 0, err
 	}
 
-	if !currentReset.After(now) {
-		balance = 20
-		currentReset = resetAt
+	if !b.ResetAt.After(now) {
+		granted := allowance - b.Daily
+		b.Daily = allowance
+		b.ResetAt = resetAt
 		_, err = tx.Exec(ctx, `
 UPDATE user_credits
 SET balance = $2, reset_at = $3, updated_at = NOW()
 WHERE user_id = $1
-`, userID, balance, currentReset)
+`, userID, b.Daily, b.ResetAt)
 		if err != nil {
-			return 0, false, err
+			return nil, 0, err
+		}
+		if err := recordLedger(ctx, tx, userID, granted, "daily_reset", ""); err != nil {
+			return nil, 0, err
 		}
 	}
 
+	fromPurchased := 0
 	if credits > 0 {
-		if balance < credits {
-			return balance, false, ErrInsufficientCredits
+		if b.Total() < credits {
+			if err := tx.Commit(ctx); err != nil {
+				return nil, 0, err
+			}
+			return b, 0, ErrInsufficientCredits
 		}
-		balance = balance - credits
+		fromDaily := min(credits, b.Daily)
+		fromPurchased = credits - fromDaily
+		b.Daily -= fromDaily
+		b.Purchased -= fromPurchased
 		_, err = tx.Exec(ctx, `
 UPDATE user_credits
-SET balance = $2, updated_at = NOW()
+SET balance = $2, purchased = $3, updated_at = NOW()
 WHERE user_id = $1
-`, userID, balance)
+`, userID, b.Daily, b.Purchased)
 		if err != nil {
-			return 0, false, err
+			return nil, 0, err
+		}
+		if err := recordLedger(ctx, tx, userID, -credits, "conversion", ref); err != nil {
+			return nil, 0, err
 		}
 	}
 
 	if err := tx.Commit(ctx); err != nil {
-		return 0, false, err
+		return nil, 0, err
+	}
+	return b, fromPurchased, nil
+}
+
+func (s *PostgresStore) Refund(userID int64, credits int, purchased int, ref string) error {
+	if credits <= 0 {
+		return nil
+	}
+	purchased = min(max(purchased, 0), credits)
+	plan, err := s.UserPlan(userID)
+	if err != nil {
+		return err
+	}
+	allowance := plan.DailyCredits
+	if plan.Unlimited {
+		allowance = 0
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var daily, balancePurchased int
+	err = tx.QueryRow(ctx, `
+SELECT balance, purchased
+FROM user_credits
+WHERE user_id = $1
+FOR UPDATE
+`, userID).Scan(&daily, &balancePurchased)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return types.ErrNoCreditAccount
+	}
+	if err != nil {
+		return err
+	}
+
+	ref = strings.TrimSpace(ref)
+	if ref != "" {
+		var refunded bool
+		err = tx.QueryRow(ctx, `
+SELECT EXISTS (
+  SELECT 1 FROM credit_ledger
+  WHERE user_id = $1 AND reason = 'refund' AND ref = $2
+)
+`, userID, ref).Scan(&refunded)
+		if err != nil {
+			return err
+		}
+		if refunded {
+			return nil
+		}
+	}
+
+	toDaily := min(credits-purchased, max(allowance-daily, 0))
+	daily += toDaily
+	balancePurchased += purchased
+	_, err = tx.Exec(ctx, `
+UPDATE user_credits
+SET balance = $2, purchased = $3, updated_at = NOW()
+WHERE user_id = $1
+`, userID, daily, balancePurchased)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, `
+INSERT INTO credit_ledger (user_id, delta, reason, ref)
+VALUES ($1, $2, 'refund', $3)
+`, userID, toDaily+purchased, ref)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (s *PostgresStore) AddPurchasedCredits(p types.Payment, credits int) (balance *types.Balance, inserted bool, err error) {
+	if credits <= 0 {
+		return nil, false, fmt.Errorf("invalid credit amount: %d", credits)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	inserted, err = recordPaymentTx(ctx, tx, p)
+	if err != nil || !inserted {
+		return nil, false, err
+	}
+	balance, err = addCreditsTx(ctx, tx, p.UserID, credits, "purchase", p.TelegramPaymentCharge)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, false, err
+	}
+	return balance, true, nil
+}
+
+func addCreditsTx(ctx context.Context, tx pgx.Tx, userID int64, credits int, reason string, ref string) (*types.Balance, error) {
+	b := &types.Balance{}
+	err := tx.QueryRow(ctx, `
+INSERT INTO user_credits (user_id, balance, purchased, reset_at)
+VALUES ($1, 0, $2, NOW())
+ON CONFLICT (user_id) DO UPDATE SET
+  purchased = user_credits.purchased + EXCLUDED.purchased,
+  updated_at = NOW()
+RETURNING balance, purchased, reset_at
+`, userID, credits).Scan(&b.Daily, &b.Purchased, &b.ResetAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := recordLedger(ctx, tx, userID, credits, reason, ref); err != nil {
+		return nil, err
 	}
-	return balance, false, nil
+	return b, nil
 }
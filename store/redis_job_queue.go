@@ -0,0 +1,621 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/BatmanBruc/bat-bot-convetor/types"
+	"github.com/go-redis/redis/v8"
+)
+
+type RedisJobQueue struct {
+	client *RedisClient
+}
+
+const maxDeadLetters = 500
+
+func NewRedisJobQueue(redisClient *RedisClient) *RedisJobQueue {
+	return &RedisJobQueue{client: redisClient}
+}
+
+var enqueueScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 1 then
+	return 0
+end
+redis.call('HSET', KEYS[1], 'data', ARGV[2], 'user', ARGV[3], 'weight', ARGV[4], 'class', ARGV[5], 'attempts', 0)
+redis.call('LPUSH', KEYS[2], ARGV[1])
+if not redis.call('ZSCORE', KEYS[3], ARGV[3]) then
+	redis.call('ZADD', KEYS[3], redis.call('GET', KEYS[4]) or 0, ARGV[3])
+end
+return 1
+`)
+
+var dequeueScript = redis.NewScript(`
+local blocked = {}
+for i = 5, #ARGV do
+	blocked[ARGV[i]] = true
+end
+local users = redis.call('ZRANGE', KEYS[1], 0, -1, 'WITHSCORES')
+for i = 1, #users, 2 do
+	local user = users[i]
+	local score = tonumber(users[i + 1])
+	local running = tonumber(redis.call('HGET', KEYS[2], user) or '0')
+	local list = ARGV[1] .. user
+	local head = redis.call('LINDEX', list, -1)
+	local class = head and redis.call('HGET', ARGV[2] .. head, 'class')
+	if running < tonumber(ARGV[4]) and not (class and blocked[class]) then
+		local id = redis.call('RPOP', list)
+		if not id then
+			redis.call('ZREM', KEYS[1], user)
+		else
+			redis.call('SET', KEYS[4], tostring(score))
+			local key = ARGV[2] .. id
+			local weight = tonumber(redis.call('HGET', key, 'weight') or '1') or 1
+			if weight < 1 then
+				weight = 1
+			end
+			if redis.call('LLEN', list) == 0 then
+				redis.call('ZREM', KEYS[1], user)
+			else
+				redis.call('ZADD', KEYS[1], score + 1 / weight, user)
+			end
+			if redis.call('EXISTS', key) == 0 then
+				return {id}
+			end
+			redis.call('HINCRBY', KEYS[2], user, 1)
+			redis.call('ZADD', KEYS[3], ARGV[3], id)
+			local attempts = redis.call('HINCRBY', key, 'attempts', 1)
+			return {id, redis.call('HGET', key, 'data'), attempts}
+		end
+	end
+end
+return false
+`)
+
+var releaseScript = redis.NewScript(`
+if redis.call('ZREM', KEYS[1], ARGV[1]) == 0 then
+	return 0
+end
+local user = redis.call('HGET', KEYS[3], 'user')
+if not user then
+	return 0
+end
+if redis.call('HINCRBY', KEYS[2], user, -1) <= 0 then
+	redis.call('HDEL', KEYS[2], user)
+end
+if tonumber(redis.call('HINCRBY', KEYS[3], 'attempts', -1)) < 0 then
+	redis.call('HSET', KEYS[3], 'attempts', 0)
+end
+redis.call('RPUSH', ARGV[2] .. user, ARGV[1])
+if not redis.call('ZSCORE', KEYS[4], user) then
+	redis.call('ZADD', KEYS[4], redis.call('GET', KEYS[5]) or 0, user)
+end
+return 1
+`)
+
+var ackScript = redis.NewScript(`
+if redis.call('ZREM', KEYS[1], ARGV[1]) == 1 then
+	local user = redis.call('HGET', KEYS[3], 'user')
+	if user and redis.call('HINCRBY', KEYS[2], user, -1) <= 0 then
+		redis.call('HDEL', KEYS[2], user)
+	end
+end
+redis.call('DEL', KEYS[3])
+return 1
+`)
+
+var requeueScript = redis.NewScript(`
+local expired = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1])
+local requeued = {}
+local abandoned = {}
+for _, id in ipairs(expired) do
+	redis.call('ZREM', KEYS[1], id)
+	local key = ARGV[2] .. id
+	local user = redis.call('HGET', key, 'user')
+	if user then
+		if redis.call('HINCRBY', KEYS[2], user, -1) <= 0 then
+			redis.call('HDEL', KEYS[2], user)
+		end
+		local attempts = tonumber(redis.call('HGET', key, 'attempts') or '0')
+		if attempts >= tonumber(ARGV[4]) then
+			table.insert(abandoned, redis.call('HGET', key, 'data'))
+			redis.call('DEL', key)
+		else
+			redis.call('RPUSH', ARGV[3] .. user, id)
+			if not redis.call('ZSCORE', KEYS[3], user) then
+				redis.call('ZADD', KEYS[3], redis.call('GET', KEYS[4]) or 0, user)
+			end
+			table.insert(requeued, id)
+		end
+	end
+end
+return {requeued, abandoned}
+`)
+
+var removeScript = redis.NewScript(`
+if redis.call('ZSCORE', KEYS[1], ARGV[1]) then
+	return 0
+end
+local user = redis.call('HGET', KEYS[2], 'user')
+if not user then
+	return 0
+end
+local list = ARGV[2] .. user
+local removed = redis.call('LREM', list, 0, ARGV[1]) + redis.call('ZREM', KEYS[4], ARGV[1])
+if redis.call('LLEN', list) == 0 then
+	redis.call('ZREM', KEYS[3], user)
+end
+redis.call('DEL', KEYS[2])
+if removed > 0 then
+	return 1
+end
+return 0
+`)
+
+var retryScript = redis.NewScript(`
+if redis.call('ZREM', KEYS[1], ARGV[1]) == 0 then
+	return 0
+end
+local user = redis.call('HGET', KEYS[3], 'user')
+if not user then
+	return 0
+end
+if redis.call('HINCRBY', KEYS[2], user, -1) <= 0 then
+	redis.call('HDEL', KEYS[2], user)
+end
+redis.call('HDEL', KEYS[3], 'shown')
+redis.call('ZADD', KEYS[4], ARGV[2], ARGV[1])
+return 1
+`)
+
+var promoteScript = redis.NewScript(`
+local due = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1])
+local promoted = {}
+for _, id in ipairs(due) do
+	redis.call('ZREM', KEYS[1], id)
+	local user = redis.call('HGET', ARGV[2] .. id, 'user')
+	if user then
+		redis.call('RPUSH', ARGV[3] .. user, id)
+		if not redis.call('ZSCORE', KEYS[2], user) then
+			redis.call('ZADD', KEYS[2], redis.call('GET', KEYS[3]) or 0, user)
+		end
+		table.insert(promoted, id)
+	end
+end
+return promoted
+`)
+
+var buryScript = redis.NewScript(`
+if redis.call('ZREM', KEYS[1], ARGV[1]) == 1 then
+	local user = redis.call('HGET', KEYS[3], 'user')
+	if user and redis.call('HINCRBY', KEYS[2], user, -1) <= 0 then
+		redis.call('HDEL', KEYS[2], user)
+	end
+end
+redis.call('DEL', KEYS[3])
+redis.call('ZADD', KEYS[4], ARGV[3], ARGV[1])
+redis.call('HSET', KEYS[5], ARGV[1], ARGV[2])
+local excess = redis.call('ZCARD', KEYS[4]) - tonumber(ARGV[4])
+if excess > 0 then
+	for _, id in ipairs(redis.call('ZRANGE', KEYS[4], 0, excess - 1)) do
+		redis.call('HDEL', KEYS[5], id)
+	end
+	redis.call('ZREMRANGEBYRANK', KEYS[4], 0, excess - 1)
+end
+return 1
+`)
+
+var takeDeadScript = redis.NewScript(`
+local entry = redis.call('HGET', KEYS[2], ARGV[1])
+if not entry then
+	return false
+end
+redis.call('ZREM', KEYS[1], ARGV[1])
+redis.call('HDEL', KEYS[2], ARGV[1])
+return entry
+`)
+
+var markShownScript = redis.NewScript(`
+if redis.call('HEXISTS', KEYS[1], 'data') == 0 then
+	return 0
+end
+if redis.call('HGET', KEYS[1], 'shown') == ARGV[1] then
+	return 0
+end
+redis.call('HSET', KEYS[1], 'shown', ARGV[1])
+return 1
+`)
+
+var waitingScript = redis.NewScript(`
+local users = redis.call('ZRANGE', KEYS[1], 0, -1, 'WITHSCORES')
+local out = {}
+for i = 1, #users, 2 do
+	local ids = redis.call('LRANGE', ARGV[1] .. users[i], 0, -1)
+	local jobs = {}
+	for j = #ids, 1, -1 do
+		local vals = redis.call('HMGET', ARGV[2] .. ids[j], 'data', 'attempts')
+		if vals[1] then
+			table.insert(jobs, ids[j])
+			table.insert(jobs, vals[1])
+			table.insert(jobs, vals[2] or '0')
+		end
+	end
+	table.insert(out, {users[i + 1], jobs})
+end
+return out
+`)
+
+var capacityScript = redis.NewScript(`
+local expired = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1])
+for _, id in ipairs(expired) do
+	redis.call('HDEL', KEYS[2], id)
+end
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', ARGV[1])
+local total = 0
+for _, id in ipairs(redis.call('ZRANGE', KEYS[1], 0, -1)) do
+	total = total + tonumber(redis.call('HGET', KEYS[2], id) or '0')
+end
+return total
+`)
+
+func (q *RedisJobQueue) usersKey() string {
+	return q.client.generateKey("queue", "users")
+}
+
+func (q *RedisJobQueue) userListPrefix() string {
+	return q.client.generateKey("queue", "user") + ":"
+}
+
+func (q *RedisJobQueue) userListKey(userID string) string {
+	return q.client.generateKey("queue", "user", userID)
+}
+
+func (q *RedisJobQueue) runningKey() string {
+	return q.client.generateKey("queue", "running")
+}
+
+func (q *RedisJobQueue) vtimeKey() string {
+	return q.client.generateKey("queue", "vtime")
+}
+
+func (q *RedisJobQueue) leasesKey() string {
+	return q.client.generateKey("queue", "leases")
+}
+
+func (q *RedisJobQueue) jobKeyPrefix() string {
+	return q.client.generateKey("queue", "job") + ":"
+}
+
+func (q *RedisJobQueue) jobKey(taskID string) string {
+	return q.client.generateKey("queue", "job", taskID)
+}
+
+func (q *RedisJobQueue) delayedKey() string {
+	return q.client.generateKey("queue", "delayed")
+}
+
+func (q *RedisJobQueue) deadKey() string {
+	return q.client.generateKey("queue", "dead")
+}
+
+func (q *RedisJobQueue) deadJobsKey() string {
+	return q.client.generateKey("queue", "dead_jobs")
+}
+
+func (q *RedisJobQueue) workersKey() string {
+	return q.client.generateKey("queue", "workers")
+}
+
+func (q *RedisJobQueue) workerSlotsKey() string {
+	return q.client.generateKey("queue", "worker_slots")
+}
+
+func (q *RedisJobQueue) Enqueue(job *types.QueuedJob) (types.QueuePosition, error) {
+	if job.EnqueuedAt.IsZero() {
+		job.EnqueuedAt = time.Now()
+	}
+	if job.Weight < 1 {
+		job.Weight = 1
+	}
+	data, err := json.Marshal(job)
+	if err != nil {
+		return types.QueuePosition{}, err
+	}
+	user := strconv.FormatInt(job.UserID, 10)
+
+	added, err := enqueueScript.Run(q.client.ctx, q.client.client,
+		[]string{q.jobKey(job.TaskID), q.userListKey(user), q.usersKey(), q.vtimeKey()},
+		job.TaskID, data, user, job.Weight, string(job.Class),
+	).Int()
+	if err != nil {
+		return types.QueuePosition{}, err
+	}
+	if added == 0 {
+		return types.QueuePosition{Duplicate: true}, nil
+	}
+
+	waiting, err := q.Waiting()
+	if err != nil {
+		return types.QueuePosition{}, err
+	}
+	ahead := len(waiting) - 1
+	for i, w := range waiting {
+		if w.TaskID == job.TaskID {
+			ahead = i
+			break
+		}
+	}
+	active, err := q.client.client.ZCard(q.client.ctx, q.leasesKey()).Result()
+	if err != nil {
+		return types.QueuePosition{}, err
+	}
+	return types.QueuePosition{Ahead: ahead, Active: int(active)}, nil
+}
+
+func (q *RedisJobQueue) Dequeue(lease time.Duration, maxPerUser int, blocked []types.ResourceClass) (*types.QueuedJob, error) {
+	deadline := time.Now().Add(lease).UnixMilli()
+	args := []interface{}{q.userListPrefix(), q.jobKeyPrefix(), deadline, maxPerUser}
+	for _, class := range blocked {
+		args = append(args, string(class))
+	}
+	res, err := dequeueScript.Run(q.client.ctx, q.client.client,
+		[]string{q.usersKey(), q.runningKey(), q.leasesKey(), q.vtimeKey()},
+		args...,
+	).Slice()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	taskID, _ := res[0].(string)
+	if len(res) < 3 {
+		return &types.QueuedJob{TaskID: taskID}, nil
+	}
+
+	var job types.QueuedJob
+	data, _ := res[1].(string)
+	if err := json.Unmarshal([]byte(data), &job); err != nil {
+		return nil, fmt.Errorf("broken queue entry %s: %v", taskID, err)
+	}
+	attempts, _ := res[2].(int64)
+	job.TaskID = taskID
+	job.Attempts = int(attempts)
+	return &job, nil
+}
+
+func (q *RedisJobQueue) Extend(taskID string, lease time.Duration) error {
+	return q.client.client.ZAddXX(q.client.ctx, q.leasesKey(), &redis.Z{
+		Score:  float64(time.Now().Add(lease).UnixMilli()),
+		Member: taskID,
+	}).Err()
+}
+
+func (q *RedisJobQueue) Release(taskID string) error {
+	return releaseScript.Run(q.client.ctx, q.client.client,
+		[]string{q.leasesKey(), q.runningKey(), q.jobKey(taskID), q.usersKey(), q.vtimeKey()},
+		taskID, q.userListPrefix(),
+	).Err()
+}
+
+func (q *RedisJobQueue) Ack(taskID string) error {
+	return ackScript.Run(q.client.ctx, q.client.client,
+		[]string{q.leasesKey(), q.runningKey(), q.jobKey(taskID)},
+		taskID,
+	).Err()
+}
+
+func (q *RedisJobQueue) Remove(taskID string) (bool, error) {
+	removed, err := removeScript.Run(q.client.ctx, q.client.client,
+		[]string{q.leasesKey(), q.jobKey(taskID), q.usersKey(), q.delayedKey()},
+		taskID, q.userListPrefix(),
+	).Int()
+	if err != nil {
+		return false, err
+	}
+	return removed == 1, nil
+}
+
+func (q *RedisJobQueue) Retry(taskID string, delay time.Duration) error {
+	return retryScript.Run(q.client.ctx, q.client.client,
+		[]string{q.leasesKey(), q.runningKey(), q.jobKey(taskID), q.delayedKey()},
+		taskID, time.Now().Add(delay).UnixMilli(),
+	).Err()
+}
+
+func (q *RedisJobQueue) PromoteDelayed() ([]string, error) {
+	return promoteScript.Run(q.client.ctx, q.client.client,
+		[]string{q.delayedKey(), q.usersKey(), q.vtimeKey()},
+		time.Now().UnixMilli(), q.jobKeyPrefix(), q.userListPrefix(),
+	).StringSlice()
+}
+
+func (q *RedisJobQueue) Bury(job *types.QueuedJob, reason string) error {
+	now := time.Now()
+	entry, err := json.Marshal(&types.DeadLetter{Job: job, Reason: reason, FailedAt: now})
+	if err != nil {
+		return err
+	}
+	return buryScript.Run(q.client.ctx, q.client.client,
+		[]string{q.leasesKey(), q.runningKey(), q.jobKey(job.TaskID), q.deadKey(), q.deadJobsKey()},
+		job.TaskID, entry, now.UnixMilli(), maxDeadLetters,
+	).Err()
+}
+
+func (q *RedisJobQueue) DeadLetters(limit int) ([]*types.DeadLetter, error) {
+	if limit <= 0 {
+		limit = maxDeadLetters
+	}
+	ids, err := q.client.client.ZRevRange(q.client.ctx, q.deadKey(), 0, int64(limit-1)).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	vals, err := q.client.client.HMGet(q.client.ctx, q.deadJobsKey(), ids...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	letters := make([]*types.DeadLetter, 0, len(vals))
+	for _, v := range vals {
+		data, ok := v.(string)
+		if !ok {
+			continue
+		}
+		var letter types.DeadLetter
+		if err := json.Unmarshal([]byte(data), &letter); err != nil || letter.Job == nil {
+			continue
+		}
+		letters = append(letters, &letter)
+	}
+	return letters, nil
+}
+
+func (q *RedisJobQueue) TakeDeadLetter(taskID string) (*types.DeadLetter, error) {
+	data, err := takeDeadScript.Run(q.client.ctx, q.client.client,
+		[]string{q.deadKey(), q.deadJobsKey()},
+		taskID,
+	).Text()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var letter types.DeadLetter
+	if err := json.Unmarshal([]byte(data), &letter); err != nil || letter.Job == nil {
+		return nil, fmt.Errorf("broken dead letter %s: %v", taskID, err)
+	}
+	return &letter, nil
+}
+
+func (q *RedisJobQueue) RequeueExpired(maxAttempts int) ([]string, []*types.QueuedJob, error) {
+	res, err := requeueScript.Run(q.client.ctx, q.client.client,
+		[]string{q.leasesKey(), q.runningKey(), q.usersKey(), q.vtimeKey()},
+		time.Now().UnixMilli(), q.jobKeyPrefix(), q.userListPrefix(), maxAttempts,
+	).Slice()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	requeued := make([]string, 0)
+	if ids, ok := res[0].([]interface{}); ok {
+		for _, v := range ids {
+			if id, ok := v.(string); ok {
+				requeued = append(requeued, id)
+			}
+		}
+	}
+	abandoned := make([]*types.QueuedJob, 0)
+	if items, ok := res[1].([]interface{}); ok {
+		for _, v := range items {
+			data, _ := v.(string)
+			var job types.QueuedJob
+			if err := json.Unmarshal([]byte(data), &job); err != nil {
+				continue
+			}
+			abandoned = append(abandoned, &job)
+		}
+	}
+	return requeued, abandoned, nil
+}
+
+func (q *RedisJobQueue) Waiting() ([]*types.QueuedJob, error) {
+	res, err := waitingScript.Run(q.client.ctx, q.client.client,
+		[]string{q.usersKey()},
+		q.userListPrefix(), q.jobKeyPrefix(),
+	).Slice()
+	if err != nil {
+		return nil, err
+	}
+
+	type userQueue struct {
+		score float64
+		jobs  []*types.QueuedJob
+	}
+	queues := make([]*userQueue, 0, len(res))
+	total := 0
+	for _, entry := range res {
+		fields, ok := entry.([]interface{})
+		if !ok || len(fields) < 2 {
+			continue
+		}
+		score, _ := fields[0].(string)
+		uq := &userQueue{}
+		uq.score, _ = strconv.ParseFloat(score, 64)
+		items, _ := fields[1].([]interface{})
+		for i := 0; i+2 < len(items); i += 3 {
+			taskID, _ := items[i].(string)
+			data, _ := items[i+1].(string)
+			attempts, _ := items[i+2].(string)
+			var job types.QueuedJob
+			if err := json.Unmarshal([]byte(data), &job); err != nil {
+				continue
+			}
+			job.TaskID = taskID
+			job.Attempts, _ = strconv.Atoi(attempts)
+			uq.jobs = append(uq.jobs, &job)
+		}
+		if len(uq.jobs) > 0 {
+			queues = append(queues, uq)
+			total += len(uq.jobs)
+		}
+	}
+
+	ordered := make([]*types.QueuedJob, 0, total)
+	for len(ordered) < total {
+		var next *userQueue
+		for _, uq := range queues {
+			if len(uq.jobs) > 0 && (next == nil || uq.score < next.score) {
+				next = uq
+			}
+		}
+		job := next.jobs[0]
+		next.jobs = next.jobs[1:]
+		weight := job.Weight
+		if weight < 1 {
+			weight = 1
+		}
+		next.score += 1 / float64(weight)
+		ordered = append(ordered, job)
+	}
+	return ordered, nil
+}
+
+func (q *RedisJobQueue) MarkShown(taskID string, position int) (bool, error) {
+	changed, err := markShownScript.Run(q.client.ctx, q.client.client, []string{q.jobKey(taskID)}, position).Int()
+	if err != nil {
+		return false, err
+	}
+	return changed == 1, nil
+}
+
+func (q *RedisJobQueue) RegisterWorker(workerID string, slots int, ttl time.Duration) error {
+	pipe := q.client.client.TxPipeline()
+	pipe.ZAdd(q.client.ctx, q.workersKey(), &redis.Z{
+		Score:  float64(time.Now().Add(ttl).UnixMilli()),
+		Member: workerID,
+	})
+	pipe.HSet(q.client.ctx, q.workerSlotsKey(), workerID, slots)
+	_, err := pipe.Exec(q.client.ctx)
+	return err
+}
+
+func (q *RedisJobQueue) UnregisterWorker(workerID string) error {
+	pipe := q.client.client.TxPipeline()
+	pipe.ZRem(q.client.ctx, q.workersKey(), workerID)
+	pipe.HDel(q.client.ctx, q.workerSlotsKey(), workerID)
+	_, err := pipe.Exec(q.client.ctx)
+	return err
+}
+
+func (q *RedisJobQueue) Capacity() (int, error) {
+	return capacityScript.Run(q.client.ctx, q.client.client,
+		[]string{q.workersKey(), q.workerSlotsKey()},
+		time.Now().UnixMilli(),
+	).Int()
+}
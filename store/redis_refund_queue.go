@@ -0,0 +1,44 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/BatmanBruc/bat-bot-convetor/types"
+	"github.com/go-redis/redis/v8"
+)
+
+type RedisRefundQueue struct {
+	client *RedisClient
+}
+
+func NewRedisRefundQueue(redisClient *RedisClient) *RedisRefundQueue {
+	return &RedisRefundQueue{client: redisClient}
+}
+
+func (q *RedisRefundQueue) key() string {
+	return q.client.generateKey("billing", "refunds")
+}
+
+func (q *RedisRefundQueue) PushRefund(refund types.CreditRefund) error {
+	data, err := json.Marshal(refund)
+	if err != nil {
+		return err
+	}
+	return q.client.client.LPush(q.client.ctx, q.key(), data).Err()
+}
+
+func (q *RedisRefundQueue) PopRefund() (*types.CreditRefund, error) {
+	data, err := q.client.client.RPop(q.client.ctx, q.key()).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var refund types.CreditRefund
+	if err := json.Unmarshal([]byte(data), &refund); err != nil {
+		return nil, fmt.Errorf("broken refund entry: %v", err)
+	}
+	return &refund, nil
+}
@@ -1,6 +1,7 @@
 package store
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
@@ -57,6 +58,9 @@ func (s *RedisTaskStore) GetTask(taskID string) (*types.Task, error) {
 
 	var task types.Task
 	if err := s.client.Get(taskKey, &task); err != nil {
+		if errors.Is(err, ErrKeyNotFound) {
+			return nil, types.ErrTaskNotFound
+		}
 		return nil, err
 	}
 
@@ -122,28 +126,6 @@ func (s *RedisTaskStore) UpdateTaskState(taskID string, state types.ChatState) e
 	return s.UpdateTask(task)
 }
 
-func (s *RedisTaskStore) GetProcessingTasks() ([]*types.Task, error) {
-	pattern := s.client.generateKey("task", "*")
-	keys, err := s.client.Keys(pattern)
-	if err != nil {
-		return nil, err
-	}
-
-	var processingTasks []*types.Task
-	for _, key := range keys {
-		var task types.Task
-		if err := s.client.Get(key, &task); err != nil {
-			continue
-		}
-
-		if task.State == types.StateProcessing {
-			processingTasks = append(processingTasks, &task)
-		}
-	}
-
-	return processingTasks, nil
-}
-
 func (s *RedisTaskStore) DeleteTask(taskID string) error {
 	task, err := s.GetTask(taskID)
 	if err != nil {
@@ -1,7 +1,96 @@
 package types
 
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInsufficientCredits = errors.New("insufficient credits")
+	ErrNoCreditAccount     = errors.New("user has no credit account")
+)
+
+const (
+	FreePlan      = "free"
+	UnlimitedPlan = "unlimited"
+)
+
+type Prices map[string]int64
+
+func (p Prices) Amount(currency string) (int64, bool) {
+	amount, ok := p[strings.ToUpper(currency)]
+	return amount, ok && amount > 0
+}
+
+type Plan struct {
+	Name              string
+	Title             string
+	Prices            Prices
+	DurationDays      int
+	DailyCredits      int
+	Unlimited         bool
+	MaxFileSize       int64
+	PriorityWeight    int
+	AllowedCategories []string
+}
+
+func (p *Plan) Duration() time.Duration {
+	return time.Duration(p.DurationDays) * 24 * time.Hour
+}
+
+func (p *Plan) AllowsCategory(category string) bool {
+	if len(p.AllowedCategories) == 0 {
+		return true
+	}
+	for _, c := range p.AllowedCategories {
+		if strings.EqualFold(c, category) {
+			return true
+		}
+	}
+	return false
+}
+
+type CreditPack struct {
+	Name    string
+	Title   string
+	Credits int
+	Prices  Prices
+}
+
+type Balance struct {
+	Daily     int
+	Purchased int
+	ResetAt   time.Time
+	Unlimited bool
+}
+
+func (b *Balance) Total() int {
+	return b.Daily + b.Purchased
+}
+
 type BillingStore interface {
 	IsUnlimited(userID int64) (bool, error)
+	UserPlan(userID int64) (*Plan, error)
+	GetPlan(name string) (*Plan, error)
+	ListPlans() ([]Plan, error)
 	GetOrResetBalance(userID int64) (int, error)
-	Consume(userID int64, credits int) (remaining int, unlimited bool, err error)
+	GetBalance(userID int64) (*Balance, error)
+	Consume(userID int64, credits int, ref string) (remaining int, purchased int, unlimited bool, err error)
+	Refund(userID int64, credits int, purchased int, ref string) error
+	AddPurchasedCredits(p Payment, credits int) (balance *Balance, inserted bool, err error)
+	ListCreditPacks() ([]CreditPack, error)
+	GetCreditPack(name string) (*CreditPack, error)
+}
+
+type CreditRefund struct {
+	UserID    int64  `json:"user_id"`
+	TaskID    string `json:"task_id"`
+	Credits   int    `json:"credits"`
+	Purchased int    `json:"purchased,omitempty"`
+}
+
+type RefundQueue interface {
+	PushRefund(refund CreditRefund) error
+	PopRefund() (*CreditRefund, error)
 }
@@ -7,6 +7,7 @@ const (
 	StateProcessing ChatState = "processing"
 	StateReady      ChatState = "ready"
 	StateError      ChatState = "error"
+	StateCancelled  ChatState = "cancelled"
 )
 
 const (
@@ -0,0 +1,50 @@
+package types
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrPromoNotFound    = errors.New("promo code not found")
+	ErrPromoInactive    = errors.New("promo code is disabled, expired or used up")
+	ErrPromoAlreadyUsed = errors.New("promo code already redeemed by this user")
+	ErrPromoExists      = errors.New("promo code already exists")
+)
+
+type PromoCode struct {
+	Code        string
+	Plan        string
+	Days        int
+	Credits     int
+	MaxUses     int
+	UsedCount   int
+	ExpiresAt   *time.Time
+	Disabled    bool
+	CreatedBy   int64
+	GiftPayment string
+	CreatedAt   time.Time
+}
+
+func (p *PromoCode) Active(now time.Time) bool {
+	if p.Disabled {
+		return false
+	}
+	if p.ExpiresAt != nil && !p.ExpiresAt.After(now) {
+		return false
+	}
+	return p.MaxUses <= 0 || p.UsedCount < p.MaxUses
+}
+
+type PromoRedemption struct {
+	Code         PromoCode
+	Subscription *Subscription
+	Balance      *Balance
+}
+
+type PromoStore interface {
+	CreatePromoCode(code PromoCode) error
+	ListPromoCodes(limit int) ([]PromoCode, error)
+	DisablePromoCode(code string) (bool, error)
+	RedeemPromoCode(userID int64, code string) (*PromoRedemption, error)
+}
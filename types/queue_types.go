@@ -0,0 +1,61 @@
+package types
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrRedriveNeedsPassword = errors.New("task needs a PDF password that is no longer stored")
+
+type ResourceClass string
+
+const (
+	ResourceLight ResourceClass = "light"
+	ResourceHeavy ResourceClass = "heavy"
+)
+
+type QueuedJob struct {
+	TaskID     string        `json:"task_id"`
+	UserID     int64         `json:"user_id"`
+	ChatID     int64         `json:"chat_id"`
+	MessageID  int           `json:"message_id"`
+	FileName   string        `json:"file_name"`
+	Lang       string        `json:"lang"`
+	Weight     int           `json:"weight"`
+	Class      ResourceClass `json:"class,omitempty"`
+	Attempts   int           `json:"attempts"`
+	EnqueuedAt time.Time     `json:"enqueued_at"`
+}
+
+type QueuePosition struct {
+	Duplicate bool
+	Ahead     int
+	Active    int
+}
+
+type DeadLetter struct {
+	Job      *QueuedJob `json:"job"`
+	Reason   string     `json:"reason"`
+	FailedAt time.Time  `json:"failed_at"`
+}
+
+type JobQueue interface {
+	Enqueue(job *QueuedJob) (QueuePosition, error)
+	Dequeue(lease time.Duration, maxPerUser int, blocked []ResourceClass) (*QueuedJob, error)
+	Extend(taskID string, lease time.Duration) error
+	Release(taskID string) error
+	Ack(taskID string) error
+	Remove(taskID string) (bool, error)
+	Retry(taskID string, delay time.Duration) error
+	PromoteDelayed() ([]string, error)
+	Bury(job *QueuedJob, reason string) error
+	DeadLetters(limit int) ([]*DeadLetter, error)
+	TakeDeadLetter(taskID string) (*DeadLetter, error)
+	RequeueExpired(maxAttempts int) (requeued []string, abandoned []*QueuedJob, err error)
+	Waiting() ([]*QueuedJob, error)
+	MarkShown(taskID string, position int) (bool, error)
+
+	RegisterWorker(workerID string, slots int, ttl time.Duration) error
+	UnregisterWorker(workerID string) error
+	Capacity() (int, error)
+}
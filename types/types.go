@@ -1,6 +1,11 @@
 package types
 
-import "time"
+import (
+	"errors"
+	"time"
+)
+
+var ErrTaskNotFound = errors.New("task not found")
 
 type PendingSelection struct {
 	MessageID int    `json:"message_id"`
@@ -20,6 +25,11 @@ type Session struct {
 	ExpiresAt time.Time              `json:"expires_at"`
 }
 
+type TaskInput struct {
+	FileID   string `json:"file_id"`
+	FileName string `json:"file_name,omitempty"`
+}
+
 type Task struct {
 	ID          string                 `json:"id"`
 	UserID      int64                  `json:"user_id"`
@@ -28,6 +38,7 @@ type Task struct {
 	FileName    string                 `json:"file_name,omitempty"`
 	OriginalExt string                 `json:"original_ext,omitempty"`
 	TargetExt   string                 `json:"target_ext,omitempty"`
+	Inputs      []TaskInput            `json:"inputs,omitempty"`
 	Options     map[string]interface{} `json:"options,omitempty"`
 	Error       string                 `json:"error,omitempty"`
 	CreatedAt   time.Time              `json:"created_at"`
@@ -52,7 +63,6 @@ type TaskStore interface {
 	DeleteTask(taskID string) error
 
 	SetProcessingFile(userID int64, fileID, fileName string, fileSize int64) (*Task, error)
-	GetProcessingTasks() ([]*Task, error)
 
 	SetTaskReady(taskID string) error
 	SetTaskError(taskID string, errorMsg string) error
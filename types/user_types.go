@@ -8,6 +8,8 @@ type User struct {
 	Username  string
 	FirstName string
 	LastName  string
+	Lang      string
+	Delivery  string
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
@@ -21,6 +23,20 @@ type Subscription struct {
 	UpdatedAt time.Time
 }
 
+const (
+	NoticeExpiresIn3Days = "expires_3d"
+	NoticeExpiresIn1Day  = "expires_1d"
+	NoticeExpired        = "expired"
+)
+
+type SubscriptionNotice struct {
+	UserID    int64
+	Plan      string
+	Kind      string
+	ExpiresAt time.Time
+	Lang      string
+}
+
 type Payment struct {
 	UserID                int64
 	Provider              string
@@ -35,6 +51,7 @@ type Payment struct {
 type UserStore interface {
 	UpsertUser(user User) error
 	GetUser(userID int64) (*User, error)
+	SetDeliveryMode(userID int64, mode string) error
 
 	UpsertSubscription(sub Subscription) error
 	GetSubscription(userID int64) (*Subscription, error)
@@ -42,5 +59,9 @@ type UserStore interface {
 	IsUnlimited(userID int64) (bool, error)
 
 	RecordPayment(p Payment) (inserted bool, err error)
-	ActivateOrExtendUnlimited(userID int64, duration time.Duration) (*Subscription, error)
+	ActivateOrExtendPlan(userID int64, plan string, duration time.Duration) (*Subscription, error)
+	ActivatePlanPayment(p Payment, plan string, duration time.Duration) (sub *Subscription, inserted bool, err error)
+
+	ClaimSubscriptionNotices(limit int) ([]SubscriptionNotice, error)
+	ReleaseSubscriptionNotice(notice SubscriptionNotice) error
 }